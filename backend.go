@@ -0,0 +1,191 @@
+package main
+
+import (
+	gocontext "context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+)
+
+type nodeBackend interface {
+	getInfo() (*lnrpc.GetInfoResponse, error)
+	walletBalance() (*lnrpc.WalletBalanceResponse, error)
+	listChannels() (*lnrpc.ListChannelsResponse, error)
+	pendingChannels() (*lnrpc.PendingChannelsResponse, error)
+	listPeers() (*lnrpc.ListPeersResponse, error)
+	listInvoices(req *lnrpc.ListInvoiceRequest) (*lnrpc.ListInvoiceResponse, error)
+	listPayments(req *lnrpc.ListPaymentsRequest) (*lnrpc.ListPaymentsResponse, error)
+	listChainTxns() (*lnrpc.TransactionDetails, error)
+	forwardingHistory(req *lnrpc.ForwardingHistoryRequest) (*lnrpc.ForwardingHistoryResponse, error)
+	getNodeInfo(pubkey string) (*lnrpc.NodeInfo, error)
+	getChanInfo(chanID uint64) (*lnrpc.ChannelEdge, error)
+	feeReport() (*lnrpc.FeeReportResponse, error)
+	updateChannelPolicy(req *lnrpc.PolicyUpdateRequest) error
+	openChannel(req *lnrpc.OpenChannelRequest, connect string, block bool) (string, error)
+	closeChannel(channelPoint string, force bool, targetConf int32, satPerByte int64) (string, error)
+	connectPeer(pubkey string, host string) error
+	disconnectPeer(pubkey string) error
+	addInvoice(invoice *lnrpc.Invoice, receipt string) (*lnrpc.AddInvoiceResponse, error)
+	decodePayReq(payReq string) (*lnrpc.PayReq, error)
+	payInvoice(req *lnrpc.SendRequest) (*lnrpc.SendResponse, error)
+	queryRoutes(req *lnrpc.QueryRoutesRequest) (*lnrpc.QueryRoutesResponse, error)
+	newAddress(addressType string) (string, error)
+	estimateFee(req *lnrpc.EstimateFeeRequest) (*lnrpc.EstimateFeeResponse, error)
+	sendCoins(req *lnrpc.SendCoinsRequest) (string, error)
+	sendMany(req *lnrpc.SendManyRequest) (string, error)
+	listUnspent() (*lnrpc.ListUnspentResponse, error)
+	listLeases() (*walletrpc.ListLeasesResponse, error)
+	leaseOutput(req *walletrpc.LeaseOutputRequest) (uint64, error)
+	releaseOutput(req *walletrpc.ReleaseOutputRequest) error
+}
+
+// streamingBackend is implemented by the backends able to follow lnd's
+// subscription streams. Each subscribe call returns the receive function of
+// its stream, which blocks until the next update and errors once the stream
+// is closed or ctx is done.
+type streamingBackend interface {
+	subscribeInvoices(ctx gocontext.Context) (func() (*lnrpc.Invoice, error), error)
+	subscribeChannelEvents(ctx gocontext.Context) (func() (*lnrpc.ChannelEventUpdate, error), error)
+	subscribeTransactions(ctx gocontext.Context) (func() (*lnrpc.Transaction, error), error)
+	subscribePeerEvents(ctx gocontext.Context) (func() (*lnrpc.PeerEvent, error), error)
+}
+
+const (
+	lncliBackendName = "lncli"
+	grpcBackendName  = "grpc"
+)
+
+func newNodeBackend() (nodeBackend, error) {
+	switch getBackend() {
+	case lncliBackendName:
+		return newlncliBackend(), nil
+	case grpcBackendName:
+		return newlndGrpcBackend()
+	}
+	return nil, fmt.Errorf("unknown backend '%s'", getBackend())
+}
+
+// txidString returns the usual display form of a transaction hash, which is
+// the byte-reversed hex encoding of the raw hash.
+func txidString(hash []byte) string {
+	r := make([]byte, len(hash))
+	for i, b := range hash {
+		r[len(hash)-1-i] = b
+	}
+	return hex.EncodeToString(r)
+}
+
+// shortChannelID formats a channel id as block height x transaction index x
+// output index.
+func shortChannelID(id uint64) string {
+	return fmt.Sprintf("%dx%dx%d", id>>40, (id>>16)&0xffffff, id&0xffff)
+}
+
+// parseShortChannelID parses a channel id formatted by shortChannelID.
+func parseShortChannelID(s string) (uint64, error) {
+	parts := strings.Split(s, "x")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid channel id '%s'", s)
+	}
+	var id uint64
+	for i, bits := range []uint{24, 24, 16} {
+		n, err := strconv.ParseUint(parts[i], 10, int(bits))
+		if err != nil {
+			return 0, fmt.Errorf("invalid channel id '%s'", s)
+		}
+		id = id<<bits | n
+	}
+	return id, nil
+}
+
+func channelPointTxid(cp *lnrpc.ChannelPoint) string {
+	if s := cp.GetFundingTxidStr(); len(s) > 0 {
+		return s
+	}
+	return txidString(cp.GetFundingTxidBytes())
+}
+
+// channelPointString formats a channel point as txid:index, as listed in
+// the channels.
+func channelPointString(cp *lnrpc.ChannelPoint) string {
+	return fmt.Sprintf("%s:%d", channelPointTxid(cp), cp.GetOutputIndex())
+}
+
+// outPointString formats an outpoint as txid:index.
+func outPointString(op *lnrpc.OutPoint) string {
+	txid := op.TxidStr
+	if len(txid) == 0 {
+		txid = txidString(op.TxidBytes)
+	}
+	return fmt.Sprintf("%s:%d", txid, op.OutputIndex)
+}
+
+// policyUpdateError lists the channels lnd failed to update, nil when all
+// were updated.
+func policyUpdateError(resp *lnrpc.PolicyUpdateResponse) error {
+	if resp == nil || len(resp.FailedUpdates) == 0 {
+		return nil
+	}
+	var failed []string
+	for _, f := range resp.FailedUpdates {
+		failed = append(failed, fmt.Sprintf("%s (%s)", outPointString(f.Outpoint), f.UpdateError))
+	}
+	return fmt.Errorf("policy update failed for %s", strings.Join(failed, ", "))
+}
+
+// paymentResponse maps the outcome of a payment to the response of
+// SendPaymentSync, the failure reason being the payment error.
+func paymentResponse(p *lnrpc.Payment) (*lnrpc.SendResponse, error) {
+	if p.Status != lnrpc.Payment_SUCCEEDED {
+		reason := p.FailureReason.String()
+		if p.FailureReason == lnrpc.PaymentFailureReason_FAILURE_REASON_NONE {
+			reason = "payment " + strings.ToLower(p.Status.String())
+		}
+		return &lnrpc.SendResponse{PaymentError: reason}, nil
+	}
+
+	preimage, err := hex.DecodeString(p.PaymentPreimage)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hex.DecodeString(p.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	sr := &lnrpc.SendResponse{PaymentPreimage: preimage, PaymentHash: hash}
+	for _, h := range p.Htlcs {
+		if h.Status == lnrpc.HTLCAttempt_SUCCEEDED {
+			sr.PaymentRoute = h.Route
+			break
+		}
+	}
+	return sr, nil
+}
+
+func parseChannelPoint(channelPoint string) (*lnrpc.ChannelPoint, error) {
+	txid, index, err := splitChannelPoint(channelPoint)
+	if err != nil {
+		return nil, err
+	}
+	return &lnrpc.ChannelPoint{
+		FundingTxid: &lnrpc.ChannelPoint_FundingTxidStr{FundingTxidStr: txid},
+		OutputIndex: index,
+	}, nil
+}
+
+func splitChannelPoint(channelPoint string) (string, uint32, error) {
+	cp := strings.Split(channelPoint, ":")
+	if len(cp) != 2 {
+		return "", 0, fmt.Errorf("invalid channel point '%s'", channelPoint)
+	}
+	index, err := strconv.ParseUint(cp[1], 10, 32)
+	if err != nil {
+		return "", 0, err
+	}
+	return cp[0], uint32(index), nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/jroimartin/gocui"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+type batchListView struct {
+	viewBase
+	form *formEdit
+}
+
+type loadBatchContainer struct {
+	Path       string `displayname:"CSV file" length:"64" validate:"required"`
+	ConfTarget int    `displayname:"Conf target (opt)" length:"6" validate:"min=0,max=1008"`
+	SatPerByte int    `displayname:"Sat per vbyte (opt)" length:"6" validate:"min=0"`
+	Label      string `displayname:"Label (opt)" length:"64" validate:"max=500"`
+}
+
+func (c *loadBatchContainer) validate() map[string]string {
+	errs := make(map[string]string)
+	if c.ConfTarget > 0 && c.SatPerByte > 0 {
+		errs["SatPerByte"] = "conf target or sat per vbyte"
+	}
+	return errs
+}
+
+type batchConfirmContainer struct {
+	File     string `displayname:"File" length:"66" readonly:"1"`
+	Payments string `displayname:"Payments" length:"66" readonly:"1"`
+	Amount   string `displayname:"Amount" length:"66" readonly:"1"`
+	Fee      string `displayname:"Fee estimate" length:"66" readonly:"1"`
+	Label    string `displayname:"Label" length:"66" readonly:"1"`
+}
+
+func newBatchConfirmContainer(batch *lncliBatchContainer, req *lnrpc.SendManyRequest, e *onChainEstimate) *batchConfirmContainer {
+	cc := new(batchConfirmContainer)
+	cc.File = filepath.Base(batch.path)
+	cc.Payments = fmt.Sprintf("%d addresses, in a single transaction", len(req.AddrToAmount))
+	cc.Amount = formatAmountUnit(e.amount, satUnit)
+	cc.Fee = formatOnChainFee(e, req.TargetConf, req.SatPerByte)
+	cc.Label = req.Label
+	return cc
+}
+
+func newbatchListView(physicalView string, fmtnormal string, fmtheader string, fmtselected string) *batchListView {
+	cv := new(batchListView)
+
+	cv.grid = makeNewDataGrid()
+	cv.mappedToPhysicalView = physicalView
+
+	cv.init(fmtnormal, fmtheader, fmtselected)
+
+	return cv
+}
+
+func (cv *batchListView) init(fmtnormal string, fmtheader string, fmtselected string) {
+
+	cv.grid.fmtForeground = fmtnormal
+	cv.grid.fmtHeader = fmtheader
+	cv.grid.fmtSelected = fmtselected
+
+	cv.shortcuts = nil
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Load CSV", "L", 'l', gocui.ModAlt, cv.load, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Send batch", "B", 'b', gocui.ModAlt, cv.send, true, ""})
+
+	cv.grid.header = "[Batch payments]"
+
+	cv.grid.addColumn("Row", "Row", intRow)
+	cv.grid.addColumn("Address", "Address", stringRow)
+	cv.grid.addAmountColumn("Amount", "Amount", satUnit)
+	cv.grid.addColumn("Error", "Error", stringRow)
+
+	cv.grid.addDisplayColumn("Row", "Row", 6)
+	cv.grid.addDisplayColumn("Address", "Address", 64)
+	cv.grid.addDisplayColumn("Amount", "Amount", 13)
+	cv.grid.addDisplayColumn("Error", "Error", 0)
+	cv.grid.setSortColumn("Row", false)
+}
+
+// load opens the form loading a batch payment file, prefilled with the last
+// batch.
+func (cv *batchListView) load() {
+	batch := status.state().batch
+
+	cc := new(loadBatchContainer)
+	cc.Path = batch.path
+	cc.ConfTarget = int(batch.targetConf)
+	cc.SatPerByte = int(batch.satPerByte)
+	cc.Label = batch.label
+
+	cv.form = newFormEdit("loadBatchVal", "Load batch payments", cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.getValue()
+		cv.form.close(context.gocui)
+		cv.form = nil
+		if valid {
+			loadBatch(cc.Path, int32(cc.ConfTarget), int64(cc.SatPerByte), cc.Label)
+		}
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
+// loadBatch reads the batch payment file in the background, displayed in the
+// batch view once loaded.
+func loadBatch(path string, targetConf int32, satPerByte int64, label string) {
+	status.store.background(func() {
+		if err := status.loadBatch(&context, path, targetConf, satPerByte, label); err != nil {
+			logError(err.Error())
+			runInGui(func() { displayMessage("Error: "+err.Error(), nil) })
+			return
+		}
+		refreshView()
+	})
+}
+
+// send sends the loaded batch once confirmed with its fee estimate, refused
+// while it has invalid payments.
+func (cv *batchListView) send() {
+	batch := status.state().batch
+	if err := batch.checkSendable(); err != nil {
+		displayMessage("Error: "+err.Error(), nil)
+		return
+	}
+	status.sendBatch(&context)
+}
+
+func (cv *batchListView) refreshView(g *gocui.Gui) {
+	v, err := g.View(cv.mappedToPhysicalView)
+	if err != nil {
+		log.Panicln(err.Error())
+		return
+	}
+	v.Clear()
+
+	x, y := v.Size()
+
+	batch := status.state().batch
+	cv.grid.items = batch.payments
+	cv.grid.info = batch.getInfo()
+	cv.grid.setRenderSize(x, y)
+
+	for _, row := range cv.grid.getGridRows() {
+		fmt.Fprintln(v, row)
+	}
+
+	if cv.form != nil {
+		cv.form.layout(g)
+	}
+}
+
+func (cv *batchListView) getShortCuts() []*keyHandle {
+	return cv.shortcuts
+}
+
+func (cv *batchListView) getGrid() *dataGrid {
+	return cv.grid
+}
+
+func (cv *batchListView) getPhysicalView() string {
+	return cv.mappedToPhysicalView
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testBatchAddress1 = "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"
+	testBatchAddress2 = "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"
+)
+
+func writeTestBatch(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "payouts.csv")
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseBatchCSV(t *testing.T) {
+	setupTestContext(t)
+
+	data := "address,amount\n" +
+		"# weekly payouts\n" +
+		testBatchAddress1 + ", 100000\n" +
+		"\n" +
+		testBatchAddress2 + ",2.5k\n" +
+		"bc1notanaddress,1000\n" +
+		testBatchAddress1 + ",5000\n" +
+		testBatchAddress2 + "\n" +
+		"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfo,0.5\n" +
+		"\"" + testBatchAddress2 + "\",0\n"
+
+	payments, err := parseBatchCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []lncliBatchPayment{
+		{3, testBatchAddress1, 100000, ""},
+		{5, testBatchAddress2, 2500, ""},
+		{6, "bc1notanaddress", 1000, "invalid address"},
+		{7, testBatchAddress1, 5000, "duplicate address"},
+		{8, testBatchAddress2, 0, "address,amount expected"},
+		{9, "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfo", 0, "invalid address"},
+		{10, testBatchAddress2, 0, "amount must be positive"},
+	}
+	if len(payments) != len(want) {
+		t.Fatalf("%d payments, want %d", len(payments), len(want))
+	}
+	for i, p := range payments {
+		if *p != want[i] {
+			t.Errorf("payment %d = %+v, want %+v", i, *p, want[i])
+		}
+	}
+
+	if _, err := parseBatchCSV(strings.NewReader("address,amount\n# none\n")); err == nil {
+		t.Error("no error for a file without payment")
+	}
+	if _, err := parseBatchCSV(strings.NewReader(testBatchAddress1 + ",\"1000\n")); err == nil {
+		t.Error("no error for a malformed file")
+	}
+}
+
+func TestParseBatchCSVFirstRow(t *testing.T) {
+	setupTestContext(t)
+
+	tests := []struct {
+		row   string
+		error string
+	}{
+		{"Address, Amount", ""},
+		{"tb1qbadaddress,abc", "invalid address"},
+		{"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfo,amount", "invalid address"},
+		{"address,10k0", "invalid address"},
+	}
+	for _, test := range tests {
+		payments, err := parseBatchCSV(strings.NewReader(test.row + "\n" + testBatchAddress1 + ",1000\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(test.error) == 0 {
+			if len(payments) != 1 || payments[0].Row != 2 {
+				t.Errorf("%q not skipped as a header", test.row)
+			}
+			continue
+		}
+		if len(payments) != 2 || payments[0].Error != test.error {
+			t.Errorf("%q not listed with %q", test.row, test.error)
+		}
+	}
+}
+
+func TestLoadBatch(t *testing.T) {
+	fb := setupTestContext(t)
+
+	activateView(batchListViewt)
+	assertLine(t, renderMainView(t), "[Batch payments]", "No batch, Alt+L")
+
+	path := writeTestBatch(t, testBatchAddress1+",100000\n"+testBatchAddress2+",0.002btc\n")
+	if err := status.loadBatch(&context, path, 1, 0, "payouts"); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := status.state().batch
+	if batch.total != 300000 || batch.invalid != 0 || batch.estimate == nil {
+		t.Fatalf("unexpected batch %+v", batch)
+	}
+	if batch.estimate.fee != 3440 || batch.estimate.feeRate != 20 {
+		t.Errorf("fee estimate %+v", batch.estimate)
+	}
+	if err := batch.checkSendable(); err != nil {
+		t.Error(err)
+	}
+
+	lines := renderMainView(t)
+	assertLine(t, lines, "[Batch payments]", "payouts.csv: 2 payment(s) for 300,000 sat, fee estimate 3,440 sat")
+	assertLine(t, lines, testBatchAddress2, "200,000")
+
+	cc := newBatchConfirmContainer(&batch, batch.sendManyRequest(), batch.estimate)
+	if cc.Amount != "300,000 sat" || cc.Fee != "3,440 sat, 20 sat/vbyte, 1 blocks target" || cc.Payments != "2 addresses, in a single transaction" {
+		t.Errorf("unexpected confirmation %+v", cc)
+	}
+
+	fb.balance.ConfirmedBalance = 200000
+	if err := status.loadBatch(&context, path, 0, 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	assertLine(t, renderMainView(t), "fee estimate failed: insufficient funds")
+
+	if err := status.loadBatch(&context, path+".missing", 0, 0, ""); err == nil {
+		t.Error("no error for a missing file")
+	}
+	if status.state().batch.path != path {
+		t.Error("batch replaced by a failed load")
+	}
+}
+
+func TestLoadInvalidBatch(t *testing.T) {
+	fb := setupTestContext(t)
+
+	path := writeTestBatch(t, testBatchAddress1+",100000\nbc1notanaddress,1000\n")
+	if err := status.loadBatch(&context, path, 0, 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if fb.callCount("estimatefee") != 0 {
+		t.Error("fee estimated for a batch with invalid payments")
+	}
+
+	batch := status.state().batch
+	if err := batch.checkSendable(); err == nil || err.Error() != "1 invalid payment(s) in batch" {
+		t.Errorf("sendable error %v", err)
+	}
+
+	activateView(batchListViewt)
+	lines := renderMainView(t)
+	assertLine(t, lines, "2 payment(s) for 101,000 sat, 1 invalid")
+	assertLine(t, lines, "bc1notanaddress", "1,000", "invalid address")
+}
+
+func TestSendBatch(t *testing.T) {
+	fb := setupTestContext(t)
+
+	activateView(walletTransactionsViewt)
+	updateData()
+
+	path := writeTestBatch(t, testBatchAddress1+",100000\n"+testBatchAddress2+",200000\n")
+	if err := status.loadBatch(&context, path, 0, 10, "payouts"); err != nil {
+		t.Fatal(err)
+	}
+	batch := status.state().batch
+	if batch.estimate.fee != 1720 || batch.estimate.feeRate != 10 {
+		t.Errorf("fee estimate at 10 sat/vbyte %+v", batch.estimate)
+	}
+
+	req := batch.sendManyRequest()
+	msg := status.sendBatchNow(&context, req)
+	if msg != "Batch sent\nTxid: "+strings.Repeat("0d", 32) {
+		t.Errorf("unexpected message %q", msg)
+	}
+	if r := fb.lastSendMany; r != req || len(r.AddrToAmount) != 2 || r.SatPerByte != 10 || r.Label != "payouts" {
+		t.Errorf("sendmany request %+v", fb.lastSendMany)
+	}
+	if fb.callCount("walletbalance") != 2 {
+		t.Error("balance not updated after sending")
+	}
+
+	assertLine(t, renderMainView(t), "-300,860", "payouts", "0d0d0d0d")
+
+	if err := status.state().batch.checkSendable(); err == nil || err.Error() != "batch already sent" {
+		t.Errorf("sendable error %v", err)
+	}
+
+	calls := fb.callCount("sendmany")
+	if msg := status.sendBatchNow(&context, req); msg != "Batch not sent\nbatch already sent" || fb.callCount("sendmany") != calls {
+		t.Errorf("batch sent again, %q", msg)
+	}
+
+	if err := status.loadBatch(&context, path, 0, 10, "payouts"); err != nil {
+		t.Fatal(err)
+	}
+	fb.err = errors.New("insufficient funds")
+	if msg := status.sendBatchNow(&context, req); msg != "Batch send failed\ninsufficient funds" {
+		t.Errorf("unexpected message %q", msg)
+	}
+	fb.err = nil
+	if err := status.state().batch.checkSendable(); err != nil {
+		t.Errorf("failed batch not sendable again, %v", err)
+	}
+
+	// a batch being sent is neither sent again nor replaced
+	status.store.update(func(st *lncliState) { st.batch.sending = true })
+	if err := status.state().batch.checkSendable(); err == nil || err.Error() != "batch being sent" {
+		t.Errorf("sendable error %v", err)
+	}
+	if msg := status.sendBatchNow(&context, req); msg != "Batch not sent\nbatch being sent" {
+		t.Errorf("unexpected message %q", msg)
+	}
+	if err := status.loadBatch(&context, path, 0, 10, "other"); err == nil || status.state().batch.label != "payouts" {
+		t.Errorf("batch replaced while sent, %v", err)
+	}
+}
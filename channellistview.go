@@ -3,9 +3,14 @@ package main
 import (
 	"fmt"
 	"log"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jroimartin/gocui"
+	"github.com/lightningnetwork/lnd/lnrpc"
 )
 
 type channelListView struct {
@@ -22,17 +27,72 @@ type closeChannelContainer struct {
 }
 
 type openChannelContainer struct {
-	NodeKey        string `displayname:"Node public key" length:"40" lines:"2"`
-	Connect        string `displayname:"Host:port (opt)" length:"22"`
-	LocalAmt       int    `displayname:"Local amount" length:"12"`
-	PushAmt        int    `displayname:"Push amount" length:"12"`
+	NodeKey        string `displayname:"Node public key" length:"40" lines:"2" validate:"required,pubkey"`
+	Connect        string `displayname:"Host:port (opt)" length:"22" validate:"hostport"`
+	LocalAmt       int    `displayname:"Local amount" length:"12" unit:"sat" validate:"required,min=20000"`
+	PushAmt        int    `displayname:"Push amount" length:"12" unit:"sat" validate:"min=0"`
 	Private        bool   `displayname:"Private" length:"1"`
 	Block          bool   `displayname:"Block and wait" length:"1"`
-	MinConfs       int    `displayname:"Min confs (opt)" length:"12"`
-	ConfTarget     int    `displayname:"Conf target (opt)" length:"12"`
-	SatPerByte     int    `displayname:"Sat per byte (opt)" length:"12"`
-	MinHtlcmSat    int    `displayname:"Min htlc mSat (opt)" length:"12"`
-	RemoteCsvDelay int    `displayname:"Remote csv delay (opt)" length:"12"`
+	MinConfs       int    `displayname:"Min confs (opt)" length:"12" validate:"min=0"`
+	ConfTarget     int    `displayname:"Conf target (opt)" length:"12" validate:"min=0"`
+	SatPerByte     int    `displayname:"Sat per byte (opt)" length:"12" validate:"min=0"`
+	MinHtlcmSat    int    `displayname:"Min htlc (opt)" length:"12" unit:"msat" validate:"min=0"`
+	RemoteCsvDelay int    `displayname:"Remote csv delay (opt)" length:"12" validate:"min=0,max=2016"`
+}
+
+func (c *openChannelContainer) validate() map[string]string {
+	errs := make(map[string]string)
+	if c.PushAmt >= c.LocalAmt {
+		errs["PushAmt"] = "must be below local amount"
+	}
+	if c.ConfTarget > 0 && c.SatPerByte > 0 {
+		errs["SatPerByte"] = "conf target or sat per byte"
+	}
+	return errs
+}
+
+type channelPolicyContainer struct {
+	NodeAlias     string `displayname:"Node alias" length:"32" readonly:"1"`
+	ChannelPoint  string `displayname:"Channel point" length:"32" readonly:"1" lines:"2"`
+	BaseFeeMsat   int    `displayname:"Base fee" length:"12" unit:"msat" validate:"min=0"`
+	FeeRatePpm    int    `displayname:"Fee rate ppm" length:"12" validate:"min=0,max=1000000"`
+	TimeLockDelta int    `displayname:"Time lock delta" length:"6" validate:"required,min=1,max=65535"`
+	MinHtlcMsat   int    `displayname:"Min htlc (opt)" length:"12" unit:"msat" validate:"min=0"`
+	MaxHtlcMsat   int    `displayname:"Max htlc (opt)" length:"16" unit:"msat" validate:"min=0"`
+	AllChannels   bool   `displayname:"Apply to all channels"`
+}
+
+func (c *channelPolicyContainer) validate() map[string]string {
+	errs := make(map[string]string)
+	if c.MaxHtlcMsat > 0 && c.MaxHtlcMsat < c.MinHtlcMsat {
+		errs["MaxHtlcMsat"] = "below min htlc"
+	}
+	return errs
+}
+
+type channelDetailsContainer struct {
+	NodeAlias     string `displayname:"Node alias" length:"48" readonly:"1"`
+	RemotePubkey  string `displayname:"Remote public key" length:"48" readonly:"1" lines:"2"`
+	ChannelPoint  string `displayname:"Channel point" length:"48" readonly:"1" lines:"2"`
+	ChanID        string `displayname:"Channel id" length:"48" readonly:"1"`
+	State         string `displayname:"State" length:"48" readonly:"1"`
+	Capacity      string `displayname:"Capacity" length:"48" readonly:"1"`
+	LocalBalance  string `displayname:"Local balance" length:"48" readonly:"1"`
+	RemoteBalance string `displayname:"Remote balance" length:"48" readonly:"1"`
+	Reserves      string `displayname:"Reserve local/remote" length:"48" readonly:"1"`
+	CommitFee     string `displayname:"Commit fee" length:"48" readonly:"1"`
+	CommitWeight  string `displayname:"Commit weight" length:"48" readonly:"1"`
+	FeePerKw      string `displayname:"Fee per Kw" length:"48" readonly:"1"`
+	Unsettled     string `displayname:"Unsettled balance" length:"48" readonly:"1"`
+	SentReceived  string `displayname:"Sent/received" length:"48" readonly:"1"`
+	NumUpdates    string `displayname:"Updates" length:"48" readonly:"1"`
+	CsvDelay      string `displayname:"Csv delay" length:"48" readonly:"1"`
+	Uptime        string `displayname:"Uptime" length:"48" readonly:"1"`
+	LocalPolicy   string `displayname:"Local policy" length:"48" readonly:"1" lines:"2"`
+	RemotePolicy  string `displayname:"Remote policy" length:"48" readonly:"1" lines:"2"`
+	Addresses     string `displayname:"Node addresses" length:"48" readonly:"1" lines:"2"`
+	Features      string `displayname:"Node features" length:"48" readonly:"1" lines:"3"`
+	PendingHtlcs  string `displayname:"Pending htlcs" length:"48" readonly:"1" lines:"4"`
 }
 
 func newchannelListView(physicalView string, fmtnormal string, fmtheader string, fmtselected string) *channelListView {
@@ -52,26 +112,37 @@ func (cv *channelListView) init(fmtnormal string, fmtheader string, fmtselected
 	cv.grid.fmtHeader = fmtheader
 	cv.grid.fmtSelected = fmtselected
 
+	cv.grid.setMarkable("ChannelPoint")
+
 	cv.shortcuts = nil
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
-	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Close channel", "C", 'c', gocui.ModAlt, cv.closeChannel, true, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Close channel", "C", 'c', gocui.ModAlt, cv.grid.selectionAction(cv.closeChannel), true, ""})
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Open channel", "O", 'o', gocui.ModAlt, cv.openChannel, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Fee policy", "P", 'p', gocui.ModAlt, cv.editPolicy, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Details", "D", 'd', gocui.ModAlt, cv.detailsChannel, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Send to node", "K", 'k', gocui.ModAlt, cv.sendToNode, true, ""})
 
 	cv.grid.key = "channels"
-	cv.grid.addColumn("Active", "Active", boolRow)               //Active, 2
-	cv.grid.addColumn("Private", "Private", boolRow)             //Private, 2
-	cv.grid.addColumn("Node", "NodeAlias", stringRow)            //Node, 0
-	cv.grid.addColumn("NodeUpdate", "NodeUpdate", dateRow)       //NodeUpdate, 18
-	cv.grid.addColumn("Capacity", "Capacity", intRow)            //Capacity, 13
-	cv.grid.addColumn("Local", "LocalBalance", intRow)           //Local, 13
-	cv.grid.addColumn("Remote", "RemoteBalance", intRow)         //Remote, 13
-	cv.grid.addColumn("ComFee", "CommitFee", intRow)             //Com. fee, 9
-	cv.grid.addColumn("ComWeight", "CommitWeight", intRow)       //Com. weight, 12
-	cv.grid.addColumn("FeeKw", "FeePerKw", intRow)               //Fee/Kw, 7
-	cv.grid.addColumn("Unsettled", "UnsettledBalance", intRow)   //Unsettled, 13
-	cv.grid.addColumn("TotSent", "TotalSatoshisSent", intRow)    //Tot. sent, 13
-	cv.grid.addColumn("TotRec", "TotalSatoshisReceived", intRow) //Tot. rec., 13
+	cv.grid.addColumn("Active", "Active", boolRow)                      //Active, 2
+	cv.grid.addColumn("Private", "Private", boolRow)                    //Private, 2
+	cv.grid.addColumn("Node", "NodeAlias", stringRow)                   //Node, 0
+	cv.grid.addColumn("NodeUpdate", "NodeUpdate", dateRow)              //NodeUpdate, 18
+	cv.grid.addAmountColumn("Capacity", "Capacity", satUnit)            //Capacity, 13
+	cv.grid.addAmountColumn("Local", "LocalBalance", satUnit)           //Local, 13
+	cv.grid.addAmountColumn("Remote", "RemoteBalance", satUnit)         //Remote, 13
+	cv.grid.addAmountColumn("ComFee", "CommitFee", satUnit)             //Com. fee, 9
+	cv.grid.addColumn("ComWeight", "CommitWeight", intRow)              //Com. weight, 12
+	cv.grid.addColumn("FeeKw", "FeePerKw", intRow)                      //Fee/Kw, 7
+	cv.grid.addAmountColumn("Unsettled", "UnsettledBalance", satUnit)   //Unsettled, 13
+	cv.grid.addAmountColumn("TotSent", "TotalSatoshisSent", satUnit)    //Tot. sent, 13
+	cv.grid.addAmountColumn("TotRec", "TotalSatoshisReceived", satUnit) //Tot. rec., 13
+	cv.grid.addAmountColumn("BaseFee", "BaseFeeMsat", msatUnit)         //Base fee, 9
+	cv.grid.addColumn("FeeRate", "FeeRatePpm", intRow)                  //Fee ppm, 8
+	cv.grid.addFiatColumn("CapacityFiat", "Capacity", satUnit)
+	cv.grid.addFiatColumn("LocalFiat", "LocalBalance", satUnit)
+	cv.grid.addFiatColumn("RemoteFiat", "RemoteBalance", satUnit)
 	cv.grid.initConfig()
 }
 
@@ -79,21 +150,33 @@ func (cv *channelListView) getSelectedChannel() *lncliChannel {
 	return cv.grid.getSelectedItem().Interface().(*lncliChannel)
 }
 
+func (cv *channelListView) sendToNode() {
+	if c := cv.getSelectedChannel(); c != nil {
+		keysendTo(c.RemotePubkey)
+	}
+}
+
 func (cv *channelListView) openChannel() {
+	cv.openChannelFrom(nil)
+}
+
+// openChannelFrom opens the channel form, the funding transaction spending
+// the utxos when set.
+func (cv *channelListView) openChannelFrom(utxos []*lncliUtxo) {
 	cc := new(openChannelContainer)
 
 	cc.LocalAmt = 0
 	cc.PushAmt = 0
 	cc.MinConfs = 1
 
-	cv.form = newFormEdit("openChanVal", "Open channel", cc)
+	cv.form = newFormEdit("openChanVal", coinControlTitle("Open channel", utxos), cc)
 
 	cv.form.callback = func(valid bool) {
 		cv.form.getValue()
 		cv.form.close(context.gocui)
 		cv.form = nil
 		if valid {
-			txid, err := status.openChannel(&context, cc.NodeKey, cc.Connect, cc.LocalAmt, cc.PushAmt, cc.Private, cc.Block, cc.MinConfs, cc.ConfTarget, cc.SatPerByte, cc.MinHtlcmSat, cc.RemoteCsvDelay)
+			txid, err := status.openChannel(&context, cc.NodeKey, cc.Connect, cc.LocalAmt, cc.PushAmt, cc.Private, cc.Block, cc.MinConfs, cc.ConfTarget, cc.SatPerByte, cc.MinHtlcmSat, cc.RemoteCsvDelay, utxoOutpoints(utxos))
 			if err != nil {
 				logError(err.Error())
 				displayMessage("Error : "+err.Error(), nil)
@@ -106,13 +189,16 @@ func (cv *channelListView) openChannel() {
 	cv.form.initialize(context.gocui)
 }
 
-func (cv *channelListView) closeChannel() {
-	c := cv.getSelectedChannel()
-
-	if c == nil {
+// closeChannel closes the selected channel once confirmed, several marked
+// channels being closed from the bulk close view.
+func (cv *channelListView) closeChannel(items []reflect.Value) {
+	if len(items) > 1 {
+		cv.closeChannels(items)
 		return
 	}
 
+	c := items[0].Interface().(*lncliChannel)
+
 	cc := new(closeChannelContainer)
 
 	cc.ChannelPoint = c.RemotePubkey
@@ -140,6 +226,263 @@ func (cv *channelListView) closeChannel() {
 	cv.form.initialize(context.gocui)
 }
 
+func (cv *channelListView) closeChannels(items []reflect.Value) {
+	var channels []*lncliChannel
+	for _, item := range items {
+		channels = append(channels, item.Interface().(*lncliChannel))
+	}
+
+	if err := status.prepareBulkClose(channels); err != nil {
+		displayMessage("Error: "+err.Error(), nil)
+		return
+	}
+	cv.grid.clearMarks()
+
+	switchActiveView(closeListViewt)
+	context.views[closeListViewt].(*closeListView).close()
+}
+
+// editPolicy queries the policy of the selected channel in the background
+// and opens the policy form with it.
+func (cv *channelListView) editPolicy() {
+	c := cv.getSelectedChannel()
+
+	if c == nil {
+		return
+	}
+
+	status.store.background(func() {
+		policy, err := status.getChannelPolicy(&context, c)
+		if err != nil {
+			logError(err.Error())
+			runInGui(func() { displayMessage("Error : "+err.Error(), nil) })
+			return
+		}
+		runInGui(func() { cv.openPolicyForm(c, policy) })
+	})
+}
+
+// openPolicyForm opens the policy form of the channel, unless another form
+// was opened meanwhile. The update is sent in the background.
+func (cv *channelListView) openPolicyForm(c *lncliChannel, policy *lnrpc.RoutingPolicy) {
+	if cv.form != nil {
+		return
+	}
+
+	cc := new(channelPolicyContainer)
+
+	cc.NodeAlias = c.NodeAlias
+	cc.ChannelPoint = c.ChannelPoint
+	cc.BaseFeeMsat = int(policy.FeeBaseMsat)
+	cc.FeeRatePpm = int(policy.FeeRateMilliMsat)
+	cc.TimeLockDelta = int(policy.TimeLockDelta)
+	cc.MinHtlcMsat = int(policy.MinHtlc)
+	cc.MaxHtlcMsat = int(policy.MaxHtlcMsat)
+
+	cv.form = newFormEdit("chanPolicyVal", "Channel fee policy", cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.getValue()
+		cv.form.close(context.gocui)
+		cv.form = nil
+		if valid {
+			target := c
+			timeLockDelta, minHtlc, maxHtlc := cc.TimeLockDelta, cc.MinHtlcMsat, cc.MaxHtlcMsat
+			if cc.AllChannels {
+				// The other channels keep their own htlc limits and time
+				// lock delta unless edited.
+				target = nil
+				if timeLockDelta == int(policy.TimeLockDelta) {
+					timeLockDelta = 0
+				}
+				if minHtlc == int(policy.MinHtlc) {
+					minHtlc = 0
+				}
+				if maxHtlc == int(policy.MaxHtlcMsat) {
+					maxHtlc = 0
+				}
+			}
+			baseFee, feeRate := cc.BaseFeeMsat, cc.FeeRatePpm
+			view := context.activeMainView
+			status.store.background(func() {
+				err := status.updateChannelPolicy(&context, target, baseFee, feeRate, timeLockDelta, minHtlc, maxHtlc)
+				if err != nil {
+					logError(err.Error())
+					runInGui(func() { displayMessage("Error : "+err.Error(), nil) })
+					return
+				}
+				updateViewData(view)
+			})
+		}
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
+func (cv *channelListView) detailsChannel() {
+	c := cv.getSelectedChannel()
+
+	if c == nil {
+		return
+	}
+
+	cc := new(channelDetailsContainer)
+
+	state := []string{"inactive"}
+	if c.Active {
+		state[0] = "active"
+	}
+	if c.Private {
+		state = append(state, "private")
+	}
+	if c.Initiator {
+		state = append(state, "opened by us")
+	} else {
+		state = append(state, "opened by remote")
+	}
+	if len(c.ChanStatusFlags) > 0 {
+		state = append(state, c.ChanStatusFlags)
+	}
+
+	cc.NodeAlias = c.NodeAlias
+	cc.RemotePubkey = c.RemotePubkey
+	cc.ChannelPoint = c.ChannelPoint
+	cc.ChanID = fmt.Sprintf("%d (%s)", c.ChanId, shortChannelID(c.ChanId))
+	cc.State = strings.Join(state, ", ")
+	cc.Capacity = formatAmountUnit(c.Capacity, satUnit)
+	cc.LocalBalance = formatAmountUnit(c.LocalBalance, satUnit)
+	cc.RemoteBalance = formatAmountUnit(c.RemoteBalance, satUnit)
+	cc.Reserves = formatAmountUnit(c.LocalChanReserveSat, satUnit) + " / " + formatAmountUnit(c.RemoteChanReserveSat, satUnit)
+	cc.CommitFee = formatAmountUnit(c.CommitFee, satUnit)
+	cc.CommitWeight = context.printer.Sprintf("%d", c.CommitWeight)
+	cc.FeePerKw = formatAmountUnit(c.FeePerKw, satUnit)
+	cc.Unsettled = formatAmountUnit(c.UnsettledBalance, satUnit)
+	cc.SentReceived = formatAmountUnit(c.TotalSatoshisSent, satUnit) + " / " + formatAmountUnit(c.TotalSatoshisReceived, satUnit)
+	cc.NumUpdates = context.printer.Sprintf("%d", c.NumUpdates)
+	cc.CsvDelay = context.printer.Sprintf("%d blocks", c.CsvDelay)
+	cc.Uptime = formatChannelUptime(c.Uptime, c.Lifetime)
+
+	cc.LocalPolicy = "querying..."
+	cc.RemotePolicy = "querying..."
+	cc.Addresses = "querying..."
+	cc.Features = "querying..."
+
+	cc.PendingHtlcs = formatPendingHtlcs(c.PendingHtlcs, status.state().localNodeInfo.BlockHeight)
+
+	form := newFormEdit("chanDetailsVal", "Channel details", cc)
+	cv.form = form
+
+	cv.form.callback = func(valid bool) {
+		cv.form.close(context.gocui)
+		cv.form = nil
+		cc = nil
+	}
+
+	cv.form.initialize(context.gocui)
+	cv.form.switchActiveEditor(-1, context.gocui)
+
+	// the policies and the node are queried in the background
+	status.store.background(func() {
+		local, remote, err := status.getChannelPolicies(&context, c)
+		if err != nil {
+			logError(err.Error())
+		}
+		var node *lnrpc.LightningNode
+		if ni, err := status.getNodeInfo(&context, c.RemotePubkey); err == nil {
+			node = ni.Node
+		}
+		runInGui(func() { cv.setDetails(form, local, remote, node) })
+	})
+}
+
+// setDetails fills the details form with the channel policies and the remote
+// node, unless it was closed meanwhile.
+func (cv *channelListView) setDetails(form *formEdit, local *lnrpc.RoutingPolicy, remote *lnrpc.RoutingPolicy, node *lnrpc.LightningNode) {
+	if cv.form != form {
+		return
+	}
+
+	form.setValue("LocalPolicy", formatRoutingPolicy(local))
+	form.setValue("RemotePolicy", formatRoutingPolicy(remote))
+
+	if node != nil {
+		form.setValue("Addresses", formatNodeAddresses(node.Addresses))
+		form.setValue("Features", formatNodeFeatures(node.Features))
+	} else {
+		form.setValue("Addresses", "unknown")
+		form.setValue("Features", "unknown")
+	}
+
+	form.layout(context.gocui)
+}
+
+func formatChannelUptime(uptime int64, lifetime int64) string {
+	if lifetime <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%s of %s (%d%%)", time.Duration(uptime)*time.Second, time.Duration(lifetime)*time.Second, uptime*100/lifetime)
+}
+
+func formatRoutingPolicy(p *lnrpc.RoutingPolicy) string {
+	if p == nil {
+		return "unknown"
+	}
+	s := context.printer.Sprintf("base %d mSat, rate %d ppm, time lock delta %d, htlc %d to %d mSat", p.FeeBaseMsat, p.FeeRateMilliMsat, p.TimeLockDelta, p.MinHtlc, p.MaxHtlcMsat)
+	if p.Disabled {
+		s += ", disabled"
+	}
+	return s
+}
+
+func formatNodeAddresses(addresses []*lnrpc.NodeAddress) string {
+	if len(addresses) == 0 {
+		return "none"
+	}
+	var s []string
+	for _, a := range addresses {
+		s = append(s, a.Network+" "+a.Addr)
+	}
+	return strings.Join(s, ", ")
+}
+
+func formatNodeFeatures(features map[uint32]*lnrpc.Feature) string {
+	if len(features) == 0 {
+		return "none"
+	}
+	var bits []int
+	for b := range features {
+		bits = append(bits, int(b))
+	}
+	sort.Ints(bits)
+
+	var s []string
+	for _, b := range bits {
+		name := features[uint32(b)].Name
+		if len(name) == 0 {
+			name = "unknown"
+		}
+		s = append(s, fmt.Sprintf("%s(%d)", name, b))
+	}
+	return strings.Join(s, ", ")
+}
+
+// formatPendingHtlcs lists the htlcs with their expiry height and the blocks
+// left until then.
+func formatPendingHtlcs(htlcs []*lnrpc.HTLC, blockHeight uint32) string {
+	if len(htlcs) == 0 {
+		return "none"
+	}
+	var s []string
+	for _, h := range htlcs {
+		direction := "out"
+		if h.Incoming {
+			direction = "in"
+		}
+		s = append(s, context.printer.Sprintf("%s %d sat", direction, h.Amount)+fmt.Sprintf(", expiry %d (%d blocks)", h.ExpirationHeight, int64(h.ExpirationHeight)-int64(blockHeight)))
+	}
+	return strings.Join(s, "\n")
+}
+
 func (cv *channelListView) refreshView(g *gocui.Gui) {
 	v, err := g.View(cv.mappedToPhysicalView)
 	if err != nil {
@@ -150,6 +493,7 @@ func (cv *channelListView) refreshView(g *gocui.Gui) {
 
 	x, y := v.Size()
 
+	cv.grid.items = status.state().channels
 	cv.grid.setRenderSize(x, y)
 
 	for _, row := range cv.grid.getGridRows() {
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+	"github.com/spf13/viper"
+)
+
+func TestChannelListViewRender(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	updateData()
+
+	waitFor(t, "channel aliases", func() bool {
+		for _, c := range status.state().channels {
+			if len(c.NodeAlias) == 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	lines := renderMainView(t)
+
+	assertLine(t, lines, "[Channels]")
+	assertLine(t, lines, "Node", "Capacity", "Local", "Remote")
+	assertLine(t, lines, "X", "acinq", "2,000,000", "1,500,000", "490,950")
+	assertLine(t, lines, "bitrefill", "500,000")
+}
+
+func TestOpenChannelForm(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[channelListViewt].(*channelListView)
+	cv.openChannel()
+
+	typeInForm(t, cv.form, "NodeKey", testPubkey(3))
+	typeInForm(t, cv.form, "Connect", "yalls.example.com:9735")
+	typeInForm(t, cv.form, "LocalAmt", "250000")
+	typeInForm(t, cv.form, "PushAmt", "1000")
+	toggleInForm(t, cv.form, "Private")
+	typeInForm(t, cv.form, "ConfTarget", "6")
+
+	cv.form.callback(true)
+
+	if cv.form != nil {
+		t.Error("form still open")
+	}
+
+	req := fb.lastOpenChannel
+	if req == nil {
+		t.Fatal("openchannel not called")
+	}
+	if hex.EncodeToString(req.NodePubkey) != testPubkey(3) {
+		t.Errorf("node key = %x", req.NodePubkey)
+	}
+	if req.LocalFundingAmount != 250000 || req.PushSat != 1000 || !req.Private || req.TargetConf != 6 || req.MinConfs != 1 {
+		t.Errorf("unexpected openchannel request %+v", req)
+	}
+	if fb.lastOpenConnect != "yalls.example.com:9735" || fb.lastOpenBlock {
+		t.Errorf("connect = %q, block = %v", fb.lastOpenConnect, fb.lastOpenBlock)
+	}
+}
+
+func TestOpenChannelFormCancel(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[channelListViewt].(*channelListView)
+	cv.openChannel()
+	cv.form.callback(false)
+
+	if fb.callCount("openchannel") != 0 {
+		t.Error("openchannel called on cancel")
+	}
+}
+
+func TestCloseChannelForm(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	updateTestData(t)
+
+	cv := context.views[channelListViewt].(*channelListView)
+	cv.grid.moveSelectionDown()
+	cv.grid.selectionAction(cv.closeChannel)()
+
+	toggleInForm(t, cv.form, "Force")
+	cv.form.callback(true)
+
+	if fb.lastCloseChannel != fb.channels.Channels[1].ChannelPoint {
+		t.Errorf("closed channel %q", fb.lastCloseChannel)
+	}
+	if !fb.lastCloseForce {
+		t.Error("force close not requested")
+	}
+}
+
+func TestChannelListConfigFilter(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	original, err := ioutil.ReadFile("config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(config, original, 0600); err != nil {
+		t.Fatal(err)
+	}
+	viper.SetConfigFile(config)
+	if err := saveConfigGridFilter("channels", "Active=false"); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := ioutil.ReadFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(string(original), `"sortDescending" : true,
+            "filter" : "",`, `"sortDescending" : true,
+            "filter" : "Active=false",`, 1)
+	if string(saved) != want {
+		t.Errorf("config file rewritten beyond the filter\n%s", saved)
+	}
+
+	viper.Reset()
+	viper.SetConfigFile(config)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	initGrids()
+
+	updateData()
+	waitFor(t, "channel aliases", func() bool { return status.state().channels[1].NodeAlias == "bitrefill" })
+	lines := renderMainView(t)
+
+	assertLine(t, lines, "[Channels]", "Filter: Active=false (1/2)")
+	if _, ok := findLine(lines, "acinq"); ok {
+		t.Errorf("active channel displayed\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestGridSearchForm(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	updateData()
+	waitFor(t, "channel aliases", func() bool { return status.state().channels[1].NodeAlias == "bitrefill" })
+	renderMainView(t)
+
+	grid := context.views[channelListViewt].getGrid()
+	grid.openSearch()
+	if grid.form == nil {
+		t.Fatal("search form not opened")
+	}
+
+	typeInForm(t, grid.form, "Search", "bitrefill")
+	grid.form.callback(true)
+
+	if grid.form != nil {
+		t.Error("search form not closed")
+	}
+	if c := context.views[channelListViewt].(*channelListView).getSelectedChannel(); c.NodeAlias != "bitrefill" {
+		t.Errorf("selected %s", c.NodeAlias)
+	}
+}
+
+func TestGridSearchKeyBoundToGridView(t *testing.T) {
+	setupTestContext(t)
+
+	cv := context.views[channelListViewt].(*channelListView)
+	for _, kh := range cv.getShortCuts() {
+		if kh.key == '/' && kh.view != cv.getPhysicalView() {
+			t.Errorf("search bound to %q", kh.view)
+		}
+	}
+
+	cv.openChannel()
+	if v := context.gocui.CurrentView(); v == nil || v.Name() == cv.getPhysicalView() {
+		t.Error("grid view focused with the open channel form")
+	}
+}
+
+func TestOpenChannelFormValidation(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[channelListViewt].(*channelListView)
+	cv.openChannel()
+
+	typeInForm(t, cv.form, "NodeKey", "02abcd")
+	typeInForm(t, cv.form, "Connect", "yalls.example.com")
+	typeInForm(t, cv.form, "LocalAmt", "1000")
+	cv.form.submit(true)
+
+	if cv.form == nil || fb.lastOpenChannel != nil {
+		t.Fatal("invalid form submitted")
+	}
+	for name, msg := range map[string]string{"NodeKey": "invalid public key", "Connect": "host:port expected", "LocalAmt": "min 20,000 sat", "PushAmt": ""} {
+		if e := getTestEditor(t, cv.form, name).getError(); e != msg {
+			t.Errorf("%s error = %q, want %q", name, e, msg)
+		}
+	}
+	if cv.form.selectedEditorIndex != 0 {
+		t.Errorf("focus on editor %d", cv.form.selectedEditorIndex)
+	}
+	if v, err := context.gocui.View("NodeKeyerr"); err != nil || !strings.Contains(v.Buffer(), "invalid public key") {
+		t.Errorf("error not displayed next to the editor")
+	}
+
+	typeInForm(t, cv.form, "NodeKey", testPubkey(3))
+	typeInForm(t, cv.form, "Connect", "")
+	typeInForm(t, cv.form, "LocalAmt", "250000")
+	typeInForm(t, cv.form, "PushAmt", "250000")
+	cv.form.submit(true)
+
+	if cv.form == nil {
+		t.Fatal("form closed with a push amount above the local amount")
+	}
+	if e := getTestEditor(t, cv.form, "PushAmt").getError(); e != "must be below local amount" {
+		t.Errorf("push amount error = %q", e)
+	}
+	if cv.form.selectedEditorIndex != 3 {
+		t.Errorf("focus on editor %d", cv.form.selectedEditorIndex)
+	}
+
+	typeInForm(t, cv.form, "PushAmt", "0")
+	cv.form.submit(true)
+
+	if cv.form != nil || fb.lastOpenChannel == nil {
+		t.Error("valid form not submitted")
+	}
+}
+
+func TestChannelListFeeColumns(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	updateData()
+
+	if c := status.state().channels[1]; c.BaseFeeMsat != 0 || c.FeeRatePpm != 250 {
+		t.Errorf("channel fees = %d, %d", c.BaseFeeMsat, c.FeeRatePpm)
+	}
+
+	lines := renderMainView(t)
+	assertLine(t, lines, "Base fee", "Fee ppm")
+	assertLine(t, lines, "1,500,000", "│1       │1      │")
+}
+
+// openTestPolicyForm opens the policy form of the selected channel as
+// editPolicy does once the policy is queried.
+func openTestPolicyForm(cv *channelListView) {
+	c := cv.getSelectedChannel()
+	if c == nil {
+		return
+	}
+	if policy, err := status.getChannelPolicy(&context, c); err == nil {
+		cv.openPolicyForm(c, policy)
+	}
+}
+
+func TestChannelPolicyForm(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	updateTestData(t)
+
+	cv := context.views[channelListViewt].(*channelListView)
+	openTestPolicyForm(cv)
+
+	if cv.form == nil {
+		t.Fatal("policy form not opened")
+	}
+
+	cc := cv.form.toMap.(*channelPolicyContainer)
+	if cc.BaseFeeMsat != 1000 || cc.FeeRatePpm != 1 || cc.TimeLockDelta != 40 || cc.MinHtlcMsat != 1000 {
+		t.Errorf("form shows the remote policy %+v", cc)
+	}
+
+	typeInForm(t, cv.form, "FeeRatePpm", "3")
+	typeInForm(t, cv.form, "MaxHtlcMsat", "999msat")
+	cv.form.submit(true)
+
+	if e := getTestEditor(t, cv.form, "MaxHtlcMsat").getError(); e != "below min htlc" {
+		t.Errorf("max htlc error = %q", e)
+	}
+
+	typeInForm(t, cv.form, "MaxHtlcMsat", "1000000")
+	cv.form.submit(true)
+	status.store.wait()
+
+	req := fb.lastPolicyUpdate
+	if req == nil {
+		t.Fatal("updatechanpolicy not called")
+	}
+	if req.FeeRatePpm != 3 || req.BaseFeeMsat != 1000 || req.TimeLockDelta != 40 || req.MaxHtlcMsat != 1000000000 || !req.MinHtlcMsatSpecified || req.MinHtlcMsat != 1000 {
+		t.Errorf("unexpected policy update %+v", req)
+	}
+	if cp := req.GetChanPoint(); cp == nil || cp.GetFundingTxidStr() != strings.Repeat("a1", 32) || cp.OutputIndex != 0 {
+		t.Errorf("policy update scope %+v", req.Scope)
+	}
+	if fb.callCount("feereport") != 2 {
+		t.Errorf("channels not refreshed after the update")
+	}
+
+	openTestPolicyForm(cv)
+	toggleInForm(t, cv.form, "AllChannels")
+	typeInForm(t, cv.form, "TimeLockDelta", "80")
+	cv.form.submit(true)
+	status.store.wait()
+
+	req = fb.lastPolicyUpdate
+	if !req.GetGlobal() {
+		t.Errorf("policy update scope %+v", req.Scope)
+	}
+	if req.TimeLockDelta != 80 || req.MaxHtlcMsat != 0 || req.MinHtlcMsatSpecified {
+		t.Errorf("unedited limits sent to all the channels %+v", req)
+	}
+
+	// Without an edited time lock delta, each channel keeps its own, the
+	// second one has no announced policy.
+	calls := fb.callCount("updatechanpolicy")
+	openTestPolicyForm(cv)
+	toggleInForm(t, cv.form, "AllChannels")
+	cv.form.submit(true)
+	status.store.wait()
+
+	req = fb.lastPolicyUpdate
+	if fb.callCount("updatechanpolicy") != calls+1 || req.GetChanPoint() == nil || req.TimeLockDelta != 40 || req.MaxHtlcMsat != 0 {
+		t.Errorf("unexpected policy update %+v", req)
+	}
+	if logs := status.state().logs; len(logs) == 0 || !strings.Contains(logs[0].Message, strings.Repeat("b2", 32)+":1: edge not found") {
+		t.Errorf("failed channel not reported %+v", logs)
+	}
+}
+
+func TestChannelListWithoutFeeReport(t *testing.T) {
+	fb := setupTestContext(t)
+	fb.callErrors = map[string]error{"feereport": errors.New("fee report failed")}
+
+	if err := status.updateChannelList(&context); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.state().channels) != 2 {
+		t.Errorf("got %d channels", len(status.state().channels))
+	}
+	if logs := status.state().logs; len(logs) == 0 || logs[0].Message != "fee report failed" {
+		t.Errorf("error not logged")
+	}
+}
+
+func TestChannelPolicyFormUnknownEdge(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	updateTestData(t)
+
+	cv := context.views[channelListViewt].(*channelListView)
+	cv.grid.moveSelectionDown()
+	cv.editPolicy()
+	status.store.wait()
+
+	if cv.form != nil {
+		t.Error("policy form opened without a policy")
+	}
+	if len(status.state().logs) == 0 || status.state().logs[0].Message != "edge not found" {
+		t.Errorf("error not logged")
+	}
+}
+
+// fillTestDetails fills the details form as detailsChannel does once the
+// policies and the node are queried.
+func fillTestDetails(cv *channelListView) {
+	c := cv.getSelectedChannel()
+	local, remote, _ := status.getChannelPolicies(&context, c)
+	ni, _ := status.getNodeInfo(&context, c.RemotePubkey)
+	cv.setDetails(cv.form, local, remote, ni.Node)
+	cv.form.getValue()
+}
+
+func TestChannelDetailsForm(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	updateTestData(t)
+
+	cv := context.views[channelListViewt].(*channelListView)
+	cv.detailsChannel()
+	status.store.wait()
+
+	if cv.form == nil {
+		t.Fatal("details form not opened")
+	}
+
+	cc := cv.form.toMap.(*channelDetailsContainer)
+	if cc.LocalPolicy != "querying..." || cc.Addresses != "querying..." {
+		t.Errorf("policies and node not queried in the background %+v", cc)
+	}
+
+	fillTestDetails(cv)
+	for _, f := range []struct{ name, got, want string }{
+		{"ChanID", cc.ChanID, "659767854661451776 (600055x6177617x16384)"},
+		{"State", cc.State, "active, opened by us"},
+		{"CsvDelay", cc.CsvDelay, "144 blocks"},
+		{"Capacity", cc.Capacity, "2,000,000 sat"},
+		{"SentReceived", cc.SentReceived, "0 sat / 0 sat"},
+		{"LocalPolicy", cc.LocalPolicy, "base 1,000 mSat, rate 1 ppm, time lock delta 40, htlc 1,000 to 1,980,000,000 mSat"},
+		{"RemotePolicy", cc.RemotePolicy, "base 0 mSat, rate 10 ppm, time lock delta 144, htlc 1 to 1,980,000,000 mSat"},
+		{"Addresses", cc.Addresses, "tcp acinq.example.com:9735"},
+		{"Features", cc.Features, "data-loss-protect(0), upfront-shutdown-script(5)"},
+		{"PendingHtlcs", cc.PendingHtlcs, "in 1,000 sat, expiry 600100 (100 blocks)"},
+	} {
+		if f.got != f.want {
+			t.Errorf("%s = %q, want %q", f.name, f.got, f.want)
+		}
+	}
+
+	lines := renderView(t, "PendingHtlcs", func(g *gocui.Gui) { cv.refreshView(g) })
+	assertLine(t, lines, "in 1,000 sat, expiry 600100")
+
+	cv.form.callback(false)
+	if cv.form != nil {
+		t.Error("details form not closed")
+	}
+}
+
+func TestChannelDetailsFormUnknownEdge(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	updateTestData(t)
+
+	cv := context.views[channelListViewt].(*channelListView)
+	cv.grid.moveSelectionDown()
+	cv.detailsChannel()
+
+	if cv.form == nil {
+		t.Fatal("details form not opened without a graph edge")
+	}
+	fillTestDetails(cv)
+
+	cc := cv.form.toMap.(*channelDetailsContainer)
+	if cc.LocalPolicy != "unknown" || cc.RemotePolicy != "unknown" || cc.PendingHtlcs != "none" || cc.Features != "none" {
+		t.Errorf("unexpected details %+v", cc)
+	}
+	if cc.State != "inactive, private, opened by remote" {
+		t.Errorf("state = %q", cc.State)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+type closeListView struct {
+	viewBase
+	form *formEdit
+}
+
+type bulkCloseContainer struct {
+	Channels   string `displayname:"Channels" length:"66" readonly:"1" lines:"8"`
+	Summary    string `displayname:"Summary" length:"66" readonly:"1"`
+	ConfTarget int    `displayname:"Conf target (opt)" length:"6" validate:"min=0,max=1008"`
+	SatPerByte int    `displayname:"Sat per vbyte (opt)" length:"6" validate:"min=0"`
+}
+
+func (c *bulkCloseContainer) validate() map[string]string {
+	errs := make(map[string]string)
+	if c.ConfTarget > 0 && c.SatPerByte > 0 {
+		errs["SatPerByte"] = "conf target or sat per vbyte"
+	}
+	return errs
+}
+
+// newBulkCloseContainer lists the channels to close with their local balance,
+// state and mode.
+func newBulkCloseContainer(channels []*lncliBulkClose) *bulkCloseContainer {
+	cc := new(bulkCloseContainer)
+
+	var lines []string
+	var local int64
+	var force int
+	for _, c := range channels {
+		state := "inactive"
+		if c.Active {
+			state = "active"
+		}
+		lines = append(lines, fmt.Sprintf("%-24s %15s %-8s %s", cutTo(c.NodeAlias, 24), formatAmountUnit(c.LocalBalance, satUnit), state, c.getMode()))
+		local += c.LocalBalance
+		if c.Force {
+			force++
+		}
+	}
+
+	cc.Channels = strings.Join(lines, "\n")
+	cc.Summary = fmt.Sprintf("%d channel(s), %s local, %d cooperative, %d force", len(channels), formatAmountUnit(local, satUnit), len(channels)-force, force)
+	return cc
+}
+
+func newcloseListView(physicalView string, fmtnormal string, fmtheader string, fmtselected string) *closeListView {
+	cv := new(closeListView)
+
+	cv.grid = makeNewDataGrid()
+	cv.mappedToPhysicalView = physicalView
+
+	cv.init(fmtnormal, fmtheader, fmtselected)
+
+	return cv
+}
+
+func (cv *closeListView) init(fmtnormal string, fmtheader string, fmtselected string) {
+
+	cv.grid.fmtForeground = fmtnormal
+	cv.grid.fmtHeader = fmtheader
+	cv.grid.fmtSelected = fmtselected
+
+	cv.grid.setMarkable("ChannelPoint")
+
+	cv.shortcuts = nil
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Force/coop", "T", 't', gocui.ModAlt, cv.grid.selectionAction(cv.toggleForce), true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Close channels", "C", 'c', gocui.ModAlt, cv.close, true, ""})
+
+	cv.grid.header = "[Bulk close]"
+
+	cv.grid.addColumn("Node", "NodeAlias", stringRow)
+	cv.grid.addAmountColumn("Local", "LocalBalance", satUnit)
+	cv.grid.addColumn("Active", "Active", boolRow)
+	cv.grid.addColumn("Force", "Force", boolRow)
+	cv.grid.addColumn("Status", "Status", stringRow)
+	cv.grid.addColumn("Result", "Result", stringRow)
+	cv.grid.addColumn("ChannelPoint", "ChannelPoint", stringRow)
+
+	cv.grid.addDisplayColumn("Node", "Node", 24)
+	cv.grid.addDisplayColumn("Local", "Local", 13)
+	cv.grid.addDisplayColumn("Active", "A", 2)
+	cv.grid.addDisplayColumn("Force", "F", 2)
+	cv.grid.addDisplayColumn("Status", "Status", 9)
+	cv.grid.addDisplayColumn("Result", "Closing txid / error", 0)
+}
+
+// toggleForce switches the channels between a force and a cooperative close
+// until they're closed.
+func (cv *closeListView) toggleForce(items []reflect.Value) {
+	var points []string
+	for _, item := range items {
+		points = append(points, item.Interface().(*lncliBulkClose).ChannelPoint)
+	}
+	if err := status.toggleBulkCloseForce(points); err != nil {
+		displayMessage("Error: "+err.Error(), nil)
+	}
+}
+
+// close opens the bulk close form summarising the channels not closed yet,
+// closed one after the other in the background once confirmed.
+func (cv *closeListView) close() {
+	bulk := status.state().bulkClose
+	if bulk.running {
+		displayMessage("Error: bulk close in progress", nil)
+		return
+	}
+
+	pending := bulk.getPending()
+	if len(pending) == 0 {
+		return
+	}
+
+	cc := newBulkCloseContainer(pending)
+	cc.ConfTarget = int(bulk.targetConf)
+	cc.SatPerByte = int(bulk.satPerByte)
+
+	cv.form = newFormEdit("bulkCloseVal", fmt.Sprintf("Close %d channels", len(pending)), cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.getValue()
+		cv.form.close(context.gocui)
+		cv.form = nil
+		if valid {
+			status.store.background(func() {
+				if err := status.closeChannels(&context, int32(cc.ConfTarget), int64(cc.SatPerByte)); err != nil {
+					logError(err.Error())
+					runInGui(func() { displayMessage("Error: "+err.Error(), nil) })
+				}
+			})
+		}
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
+func (cv *closeListView) refreshView(g *gocui.Gui) {
+	v, err := g.View(cv.mappedToPhysicalView)
+	if err != nil {
+		log.Panicln(err.Error())
+		return
+	}
+	v.Clear()
+
+	x, y := v.Size()
+
+	bulk := status.state().bulkClose
+	cv.grid.items = bulk.channels
+	cv.grid.info = bulk.getInfo()
+	cv.grid.setRenderSize(x, y)
+
+	for _, row := range cv.grid.getGridRows() {
+		fmt.Fprintln(v, row)
+	}
+
+	if cv.form != nil {
+		cv.form.layout(g)
+	}
+}
+
+func (cv *closeListView) getShortCuts() []*keyHandle {
+	return cv.shortcuts
+}
+
+func (cv *closeListView) getGrid() *dataGrid {
+	return cv.grid
+}
+
+func (cv *closeListView) getPhysicalView() string {
+	return cv.mappedToPhysicalView
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// markTestChannels marks all the channels in the channel view once their
+// aliases are resolved.
+func markTestChannels(t *testing.T) *channelListView {
+	t.Helper()
+
+	updateData()
+	waitFor(t, "channel aliases", func() bool {
+		for _, c := range status.state().channels {
+			if len(c.NodeAlias) == 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	cv := context.views[channelListViewt].(*channelListView)
+	renderMainView(t)
+	cv.grid.toggleMarkAll()
+	return cv
+}
+
+func TestBulkCloseForm(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	cv := markTestChannels(t)
+	cv.grid.selectionAction(cv.closeChannel)()
+
+	if context.activeMainView != closeListViewt {
+		t.Fatal("bulk close view not activated")
+	}
+	if len(cv.grid.marked) != 0 {
+		t.Error("channels still marked in the channel view")
+	}
+
+	clv := context.views[closeListViewt].(*closeListView)
+	if clv.form == nil || clv.form.title != " Close 2 channels " {
+		t.Fatal("bulk close form not opened")
+	}
+	channels := strings.Split(getTestEditor(t, clv.form, "Channels").getValue().(string), "\n")
+	if len(channels) != 2 {
+		t.Fatalf("channels %q", channels)
+	}
+	for i, want := range [][]string{{"acinq", "1,500,000 sat", "active", "cooperative"}, {"bitrefill", "0 sat", "inactive", "force"}} {
+		for _, w := range want {
+			if !strings.Contains(channels[i], w) {
+				t.Errorf("channel %q, want %q", channels[i], w)
+			}
+		}
+	}
+	if v := getTestEditor(t, clv.form, "Summary").getValue(); v != "2 channel(s), 1,500,000 sat local, 1 cooperative, 1 force" {
+		t.Errorf("summary %q", v)
+	}
+
+	typeInForm(t, clv.form, "ConfTarget", "6")
+	typeInForm(t, clv.form, "SatPerByte", "10")
+	clv.form.submit(true)
+	if e := getTestEditor(t, clv.form, "SatPerByte").getError(); e != "conf target or sat per vbyte" {
+		t.Errorf("fee error %q", e)
+	}
+	typeInForm(t, clv.form, "SatPerByte", "")
+	clv.form.callback(true)
+	status.store.wait()
+
+	if strings.Join(fb.closed, ",") != fb.channels.Channels[0].ChannelPoint+","+fb.channels.Channels[1].ChannelPoint {
+		t.Errorf("closed %v, want the channels in order", fb.closed)
+	}
+	if !fb.lastCloseForce || fb.lastCloseConf != 0 {
+		t.Errorf("inactive channel closed with force %v, conf target %d", fb.lastCloseForce, fb.lastCloseConf)
+	}
+
+	lines := renderMainView(t)
+	assertLine(t, lines, "[Bulk close]", "2 channel(s), 1,500,000 sat local, 1 force, closed 2/2")
+	assertLine(t, lines, "acinq", "1,500,000", "closed", strings.Repeat("8e", 32))
+	if status.state().bulkClose.targetConf != 6 {
+		t.Errorf("fee target %d", status.state().bulkClose.targetConf)
+	}
+}
+
+func TestBulkCloseProgress(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	markTestChannels(t)
+	if err := status.prepareBulkClose(status.state().channels); err != nil {
+		t.Fatal(err)
+	}
+
+	activateView(closeListViewt)
+	lines := renderMainView(t)
+	assertLine(t, lines, "[Bulk close]", "2 channel(s), 1,500,000 sat local, 1 force")
+	assertLine(t, lines, "bitrefill", "X", "pending")
+
+	clv := context.views[closeListViewt].(*closeListView)
+	clv.grid.toggleMarkAll()
+	clv.grid.selectionAction(clv.toggleForce)()
+	bulk := status.state().bulkClose
+	if bulk.channels[0].Force != true || bulk.channels[1].Force != false {
+		t.Errorf("force not toggled %+v, %+v", bulk.channels[0], bulk.channels[1])
+	}
+
+	failed := fb.channels.Channels[1].ChannelPoint
+	fb.closeErrors = map[string]error{failed: errors.New("peer offline")}
+
+	if err := status.closeChannels(&context, 0, 20); err != nil {
+		t.Fatal(err)
+	}
+	if fb.lastCloseForce || fb.lastCloseSatPerByte != 20 {
+		t.Errorf("cooperative close with force %v, %d sat/vbyte", fb.lastCloseForce, fb.lastCloseSatPerByte)
+	}
+	if fb.callCount("listchannels") < 2 {
+		t.Error("channels not refreshed after closing")
+	}
+
+	lines = renderMainView(t)
+	assertLine(t, lines, "closed 1/2, 1 failed")
+	assertLine(t, lines, "bitrefill", "failed", "peer offline")
+
+	delete(fb.closeErrors, failed)
+	if err := status.closeChannels(&context, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if fb.lastCloseChannel != failed || fb.callCount("closechannel") != 3 {
+		t.Errorf("failed close not retried alone, last closed %s", fb.lastCloseChannel)
+	}
+	assertLine(t, renderMainView(t), "closed 2/2")
+
+	if err := status.closeChannels(&context, 0, 0); err == nil || err.Error() != "no channel to close" {
+		t.Errorf("close error %v", err)
+	}
+
+	// the failed closes stay reachable from any view until replaced
+	activateView(channelListViewt)
+	for _, kh := range context.globalShortcuts {
+		if kh.key == '0' {
+			kh.action()
+		}
+	}
+	if context.activeMainView != closeListViewt {
+		t.Error("bulk close view not reached with Alt+0")
+	}
+
+	status.store.update(func(st *lncliState) { st.bulkClose.running = true })
+	if err := status.prepareBulkClose(status.state().channels); err == nil {
+		t.Error("bulk close replaced while running")
+	}
+	if err := status.toggleBulkCloseForce([]string{failed}); err == nil {
+		t.Error("force toggled while closing")
+	}
+}
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
+	"unicode"
 
 	flags "github.com/jessevdk/go-flags"
 	"github.com/jroimartin/gocui"
@@ -11,8 +19,9 @@ import (
 )
 
 type cliOpts struct {
+	Backend         string `short:"b" long:"backend" description:"lnd access method, lncli or grpc"`
 	LncliExec       string `short:"l" long:"lnclicmd" description:"lncli executable"`
-	RefreshSec      int    `short:"r" long:"refresh" description:"lncli data refresh time in seconds"`
+	RefreshSec      int    `short:"r" long:"refresh" description:"default data refresh time in seconds"`
 	RPCServer       string `long:"rpcserver" description:"host:port of ln daemon"`
 	LndDir          string `long:"lnddir" description:"path to lnd's base directory"`
 	TLSCertPath     string `long:"tlscertpath" description:"path to TLS certificate"`
@@ -20,6 +29,9 @@ type cliOpts struct {
 	MacaroonPath    string `long:"macaroonpath" description:"path to macaroon file"`
 	MacaroonTimeOut int    `long:"macaroontimeout" description:"anti-replay macaroon validity time in seconds"`
 	MacaroonIP      string `long:"macaroonip" description:"if set, lock macaroon to specific IP address"`
+	NodeCacheTTL    int    `long:"nodecachettl" description:"node cache entries lifetime in minutes"`
+	NodeCacheSize   int    `long:"nodecachesize" description:"maximum number of nodes in the cache"`
+	AmountUnit      string `short:"u" long:"unit" description:"unit of the amounts, msat, sat, bits or BTC"`
 }
 
 var (
@@ -33,6 +45,13 @@ type gridColumnConfig struct {
 	width  int
 }
 
+func getBackend() string {
+	if len(cfgOpts.Backend) > 0 {
+		return cfgOpts.Backend
+	}
+	return lncliBackendName
+}
+
 func getLncliExec() string {
 	if len(cfgOpts.LncliExec) > 0 {
 		return cfgOpts.LncliExec
@@ -75,6 +94,63 @@ func getMacaroonIP() string {
 	return cfgOpts.MacaroonIP
 }
 
+func getNodeCacheTTL() time.Duration {
+	if cfgOpts.NodeCacheTTL > 0 {
+		return time.Duration(cfgOpts.NodeCacheTTL) * time.Minute
+	}
+	return 6 * time.Hour
+}
+
+func getNodeCacheSize() int {
+	if cfgOpts.NodeCacheSize > 0 {
+		return cfgOpts.NodeCacheSize
+	}
+	return 5000
+}
+
+func getAmountUnit() amountUnit {
+	if len(cfgOpts.AmountUnit) == 0 {
+		return satUnit
+	}
+	unit, err := parseAmountUnit(cfgOpts.AmountUnit)
+	if err != nil {
+		logError(fmt.Sprintf("AmountUnit: %s", err.Error()))
+	}
+	return unit
+}
+
+func getNodeCachePath() string {
+	return filepath.Join(os.Getenv("HOME"), ".lncli-curses", "nodecache.json")
+}
+
+func getLndDirOrDefault() string {
+	if len(getLndDir()) > 0 {
+		return getLndDir()
+	}
+	return filepath.Join(os.Getenv("HOME"), ".lnd")
+}
+
+func getRPCServerOrDefault() string {
+	if len(getRPCServer()) > 0 {
+		return getRPCServer()
+	}
+	return "localhost:10009"
+}
+
+func getTLSCertPathOrDefault() string {
+	if len(getTLSCertPath()) > 0 {
+		return getTLSCertPath()
+	}
+	return filepath.Join(getLndDirOrDefault(), "tls.cert")
+}
+
+func getMacaroonPathOrDefault() string {
+	if len(getMacaroonPath()) > 0 {
+		return getMacaroonPath()
+	}
+	return filepath.Join(getLndDirOrDefault(), "data", "chain", "bitcoin", "mainnet", "admin.macaroon")
+}
+
 func getShowHeader() bool {
 	return cfgShowHeader
 }
@@ -117,6 +193,185 @@ func getConfigGridShortcutHeader(gridKey string) string {
 	return viper.GetString("grids." + gridKey + ".shortcutHeader")
 }
 
+func getConfigGridSort(gridKey string) (string, bool) {
+	return viper.GetString("grids." + gridKey + ".sort"), viper.GetBool("grids." + gridKey + ".sortDescending")
+}
+
+func getConfigGridFilter(gridKey string) string {
+	return viper.GetString("grids." + gridKey + ".filter")
+}
+
+// saveConfigGridFilter writes the filter back to the config file. Only the
+// filter of the grid entry is patched, viper rewriting the whole file with
+// lowercased keys and without its layout.
+func saveConfigGridFilter(gridKey string, filter string) error {
+	path := viper.ConfigFileUsed()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	data, err = patchConfigGridFilter(data, gridKey, filter)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	viper.Set("grids."+gridKey+".filter", filter)
+	return nil
+}
+
+// patchConfigGridFilter replaces the filter value of the grid entry in the
+// JSON config, adding it at the start of the entry when missing.
+func patchConfigGridFilter(data []byte, gridKey string, filter string) ([]byte, error) {
+	value, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	type frame struct {
+		object    bool
+		expectKey bool
+		key       string
+	}
+
+	var stack []*frame
+	gridStart := -1
+
+	isGrid := func() bool {
+		return len(stack) == 3 && stack[0].key == "grids" && stack[1].key == gridKey
+	}
+	valueDone := func() {
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				stack = append(stack, &frame{object: t == '{', expectKey: t == '{'})
+				if isGrid() {
+					gridStart = int(dec.InputOffset())
+				}
+			default:
+				if isGrid() {
+					return insertConfigGridFilter(data, gridStart, value), nil
+				}
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+		case string:
+			if len(stack) == 0 {
+				continue
+			}
+			top := stack[len(stack)-1]
+			if top.object && top.expectKey {
+				top.key = t
+				top.expectKey = false
+				if isGrid() && t == "filter" {
+					keyEnd := int(dec.InputOffset())
+					if _, err := dec.Token(); err != nil {
+						return nil, err
+					}
+					end := int(dec.InputOffset())
+					start := keyEnd + bytes.IndexByte(data[keyEnd:end], ':') + 1
+					for data[start] == ' ' || data[start] == '\t' || data[start] == '\n' || data[start] == '\r' {
+						start++
+					}
+					return concatBytes(data[:start], value, data[end:]), nil
+				}
+				continue
+			}
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+
+	return nil, fmt.Errorf("no %s grid", gridKey)
+}
+
+// insertConfigGridFilter adds the filter at the start of the grid entry, with
+// the indentation of its first key.
+func insertConfigGridFilter(data []byte, gridStart int, value []byte) []byte {
+	rest := data[gridStart:]
+	first := bytes.IndexFunc(rest, func(r rune) bool { return !unicode.IsSpace(r) })
+	if first >= 0 && rest[first] == '}' {
+		return concatBytes(data[:gridStart], []byte(` "filter" : `), value, []byte(" "), rest[first:])
+	}
+	indent := rest[:first]
+	return concatBytes(data[:gridStart], indent, []byte(`"filter" : `), value, []byte(","), rest)
+}
+
+func concatBytes(parts ...[]byte) []byte {
+	var buffer bytes.Buffer
+	for _, p := range parts {
+		buffer.Write(p)
+	}
+	return buffer.Bytes()
+}
+
+func getConfigForwardingDays() int {
+	if days := viper.GetInt("grids.forwards.days"); days > 0 {
+		return days
+	}
+	return 30
+}
+
+// getFiatCurrency returns the currency the amounts are converted to, none
+// when empty.
+func getFiatCurrency() string {
+	return strings.ToUpper(viper.GetString("fiat.currency"))
+}
+
+func getConfigFiatSource() string {
+	return viper.GetString("fiat.source")
+}
+
+func getConfigFiatRate() float64 {
+	return viper.GetFloat64("fiat.rate")
+}
+
+func getConfigFiatPath() string {
+	return viper.GetString("fiat.path")
+}
+
+func getConfigFiatURL() string {
+	return viper.GetString("fiat.url")
+}
+
+// getConfigRefreshSec returns the update interval of a refresh source,
+// RefreshSec when not configured.
+func getConfigRefreshSec(key string) int {
+	if sec := viper.GetInt("refresh." + key); sec > 0 {
+		return sec
+	}
+	return getRefreshSec()
+}
+
+func getConfigInactiveRefreshFactor() int {
+	if f := viper.GetInt("refresh.inactiveFactor"); f > 0 {
+		return f
+	}
+	return 4
+}
+
 func getConfigGridHeader(gridKey string) string {
 	return viper.GetString("grids." + gridKey + ".header")
 }
@@ -148,6 +403,10 @@ func readCommandLine() bool {
 		return false
 	}
 
+	if len(opts.Backend) > 0 {
+		cfgOpts.Backend = opts.Backend
+	}
+
 	if len(opts.LncliExec) > 0 {
 		cfgOpts.LncliExec = opts.LncliExec
 	}
@@ -184,11 +443,24 @@ func readCommandLine() bool {
 		cfgOpts.LndDir = opts.LndDir
 	}
 
+	if opts.NodeCacheTTL > 0 {
+		cfgOpts.NodeCacheTTL = opts.NodeCacheTTL
+	}
+
+	if opts.NodeCacheSize > 0 {
+		cfgOpts.NodeCacheSize = opts.NodeCacheSize
+	}
+
+	if len(opts.AmountUnit) > 0 {
+		cfgOpts.AmountUnit = opts.AmountUnit
+	}
+
 	return true
 }
 
 func initBaseConfig() {
 	cfgShowHeader = viper.GetBool("showheader")
+	cfgOpts.Backend = viper.GetString("Backend")
 	cfgOpts.LncliExec = viper.GetString("LncliExec")
 	cfgOpts.RefreshSec = viper.GetInt("RefreshSec")
 	cfgOpts.RPCServer = viper.GetString("RPCServer")
@@ -198,6 +470,9 @@ func initBaseConfig() {
 	cfgOpts.MacaroonPath = viper.GetString("MacaroonPath")
 	cfgOpts.MacaroonTimeOut = viper.GetInt("MacaroonTimeOut")
 	cfgOpts.MacaroonIP = viper.GetString("MacaroonIP")
+	cfgOpts.NodeCacheTTL = viper.GetInt("NodeCacheTTL")
+	cfgOpts.NodeCacheSize = viper.GetInt("NodeCacheSize")
+	cfgOpts.AmountUnit = viper.GetString("AmountUnit")
 }
 
 func initTheme() {
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatchConfigGridFilter(t *testing.T) {
+	config := `{
+    "grids" :
+    {
+        "peers" : { "filter" : "Inbound=true" },
+        "channels" :
+        {
+            "header" : "[Channels]",
+            "columns" : [ { "key": "Node", "width": 0 } ]
+        },
+        "routes" : {}
+    }
+}`
+
+	tests := []struct {
+		grid   string
+		filter string
+		want   string
+	}{
+		{"peers", "Alias~\"acinq\"", `"peers" : { "filter" : "Alias~\"acinq\"" },`},
+		{"channels", "Active=false", `{
+            "filter" : "Active=false",
+            "header" : "[Channels]",`},
+		{"routes", "", `"routes" : { "filter" : "" }`},
+	}
+
+	for _, tt := range tests {
+		data, err := patchConfigGridFilter([]byte(config), tt.grid, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.grid, err)
+		}
+		if !strings.Contains(string(data), tt.want) {
+			t.Errorf("%s patched to\n%s", tt.grid, data)
+		}
+	}
+
+	if _, err := patchConfigGridFilter([]byte(config), "payments", ""); err == nil {
+		t.Error("no error for a missing grid")
+	}
+	if _, err := patchConfigGridFilter([]byte(`{"grids": {`), "peers", ""); err == nil {
+		t.Error("no error for an invalid file")
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 	"unicode/utf8"
+
+	"github.com/jroimartin/gocui"
 )
 
 type rowFormat int
@@ -17,16 +21,22 @@ const (
 	boolRow   rowFormat = 3
 	dateRow   rowFormat = 4
 	sliceRow  rowFormat = 5
+	amountRow rowFormat = 6
+	fiatRow   rowFormat = 7
 )
 
+// dataGridColumn is a column available to a grid, unit being the native unit
+// of the amount and fiat columns.
 type dataGridColumn struct {
 	propertyName string
 	displayWidth int
 	format       rowFormat
+	unit         amountUnit
 }
 
 type dataGridColumnDisplay struct {
 	dataGridColumn
+	key    string
 	header string
 	width  int
 }
@@ -34,6 +44,8 @@ type dataGridColumnDisplay struct {
 type dataGrid struct {
 	key               string
 	header            string
+	info              string
+	refreshStatus     string
 	items             interface{}
 	availableColumns  map[string]*dataGridColumn
 	columns           []*dataGridColumnDisplay
@@ -44,16 +56,327 @@ type dataGrid struct {
 	fmtForeground     string
 	fmtHeader         string
 	fmtSelected       string
+	sortColumn        int
+	sortDescending    bool
+	rows              []int
+	filter            *gridFilter
+	searchText        string
+	searchFound       bool
+	form              *formEdit
+	markProperty      string
+	marked            map[string]bool
+	markAnchor        int
+}
+
+type gridSearchContainer struct {
+	Search string `displayname:"Search" length:"40"`
+}
+
+type gridFilterContainer struct {
+	Filter string `displayname:"Filter" length:"50"`
+	Save   bool   `displayname:"Save as default"`
 }
 
 func makeNewDataGrid() *dataGrid {
 	g := new(dataGrid)
 	g.availableColumns = make(map[string]*dataGridColumn)
+	g.sortColumn = -1
 	return g
 }
 
+// getShortCuts returns the grid key bindings, the ones without modifier being
+// bound to the physical view displaying the grid.
+func (dg *dataGrid) getShortCuts(view string) []*keyHandle {
+	var shortcuts []*keyHandle
+	shortcuts = append(shortcuts, &keyHandle{"Sort", "S", 's', gocui.ModAlt, dg.nextSortColumn, true, ""})
+	shortcuts = append(shortcuts, &keyHandle{"Reverse", "R", 'r', gocui.ModAlt, dg.reverseSort, true, ""})
+	shortcuts = append(shortcuts, &keyHandle{"Filter", "F", 'f', gocui.ModAlt, dg.openFilter, true, ""})
+	shortcuts = append(shortcuts, &keyHandle{"Search", "/", '/', gocui.ModNone, dg.openSearch, false, view})
+	shortcuts = append(shortcuts, &keyHandle{"Next match", "F3", gocui.KeyF3, gocui.ModNone, dg.findNextMatch, false, view})
+	if dg.isMarkable() {
+		shortcuts = append(shortcuts, dg.getMarkShortCuts(view)...)
+	}
+	return shortcuts
+}
+
 func (dg *dataGrid) getSelectedItem() reflect.Value {
-	return reflect.ValueOf(dg.items).Index(dg.selectedIndex)
+	if dg.items == nil {
+		return reflect.Value{}
+	}
+	dg.refreshRows()
+	if dg.selectedIndex >= len(dg.rows) {
+		return reflect.Zero(reflect.TypeOf(dg.items).Elem())
+	}
+	return reflect.ValueOf(dg.items).Index(dg.rows[dg.selectedIndex])
+}
+
+// refreshRows rebuilds the displayed order of the items, dg.rows holds the
+// indexes of the items passing the filter in display order.
+func (dg *dataGrid) refreshRows() {
+	dg.rows = dg.rows[:0]
+
+	if dg.items == nil {
+		return
+	}
+
+	items := reflect.ValueOf(dg.items)
+
+	for i := 0; i < items.Len(); i++ {
+		if dg.filter != nil && !dg.filter.match(dg, items.Index(i).Elem()) {
+			continue
+		}
+		dg.rows = append(dg.rows, i)
+	}
+
+	dg.sortRows(items)
+	dg.pruneMarks(items)
+
+	if dg.selectedIndex >= len(dg.rows) {
+		dg.selectedIndex = len(dg.rows) - 1
+	}
+	if dg.selectedIndex < 0 {
+		dg.selectedIndex = 0
+	}
+	if dg.visibleStartIndex > dg.selectedIndex {
+		dg.visibleStartIndex = dg.selectedIndex
+	}
+}
+
+func (dg *dataGrid) sortRows(items reflect.Value) {
+	if dg.sortColumn < 0 || dg.sortColumn >= len(dg.columns) {
+		return
+	}
+
+	col := dg.columns[dg.sortColumn]
+
+	sort.SliceStable(dg.rows, func(i, j int) bool {
+		a := dg.getRowValue(items.Index(dg.rows[i]).Elem(), col.propertyName)
+		b := dg.getRowValue(items.Index(dg.rows[j]).Elem(), col.propertyName)
+		if dg.sortDescending {
+			return compareRowValues(b, a, col.format) < 0
+		}
+		return compareRowValues(a, b, col.format) < 0
+	})
+}
+
+func compareRowValues(a reflect.Value, b reflect.Value, format rowFormat) int {
+	if !a.IsValid() || !b.IsValid() {
+		switch {
+		case a.IsValid():
+			return 1
+		case b.IsValid():
+			return -1
+		}
+		return 0
+	}
+
+	switch format {
+	case intRow, dateRow, amountRow, fiatRow:
+		switch a.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Int16, reflect.Int32:
+			return compareInt64(a.Int(), b.Int())
+		case reflect.Uint, reflect.Uint64, reflect.Uint16, reflect.Uint32:
+			return compareUint64(a.Uint(), b.Uint())
+		}
+	case boolRow:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case b.Bool():
+			return -1
+		}
+		return 1
+	case stringRow:
+		return strings.Compare(strings.ToLower(a.String()), strings.ToLower(b.String()))
+	case sliceRow:
+		return strings.Compare(getSliceString(a), getSliceString(b))
+	}
+	return 0
+}
+
+func compareInt64(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat64(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUint64(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (dg *dataGrid) setSortColumn(key string, descending bool) {
+	dg.sortColumn = -1
+	dg.sortDescending = descending
+	for i, col := range dg.columns {
+		if col.key == key {
+			dg.sortColumn = i
+			return
+		}
+	}
+	if len(key) > 0 {
+		logError(fmt.Sprintf("Sort column '%s' not displayed", key))
+	}
+}
+
+// nextSortColumn cycles the sort through the displayed columns, ending with
+// the unsorted order.
+func (dg *dataGrid) nextSortColumn() {
+	dg.sortColumn++
+	dg.sortDescending = false
+	if dg.sortColumn >= len(dg.columns) {
+		dg.sortColumn = -1
+	}
+	refreshView()
+}
+
+func (dg *dataGrid) reverseSort() {
+	dg.sortDescending = !dg.sortDescending
+	refreshView()
+}
+
+func (dg *dataGrid) setFilter(expression string) error {
+	f, err := parseGridFilter(dg, expression)
+	if err != nil {
+		return err
+	}
+	dg.filter = f
+	return nil
+}
+
+func (dg *dataGrid) getFilterExpression() string {
+	if dg.filter == nil {
+		return ""
+	}
+	return dg.filter.expression
+}
+
+func (dg *dataGrid) openFilter() {
+	if dg.form != nil {
+		return
+	}
+
+	fc := &gridFilterContainer{dg.getFilterExpression(), false}
+
+	dg.form = newFormEdit("gridFilter", "Filter", fc)
+	dg.form.submitOnEnter = true
+
+	dg.form.callback = func(valid bool) {
+		dg.form.getValue()
+		dg.form.close(context.gocui)
+		dg.form = nil
+		if !valid {
+			return
+		}
+		if err := dg.setFilter(fc.Filter); err != nil {
+			logError(err.Error())
+			displayMessage("Error : "+err.Error(), nil)
+			return
+		}
+		if fc.Save {
+			if err := saveConfigGridFilter(dg.key, dg.getFilterExpression()); err != nil {
+				logError(err.Error())
+				displayMessage("Error : "+err.Error(), nil)
+			}
+		}
+	}
+
+	dg.form.initialize(context.gocui)
+}
+
+// openSearch prompts for the text to search.
+func (dg *dataGrid) openSearch() {
+	if dg.form != nil {
+		return
+	}
+
+	sc := &gridSearchContainer{dg.searchText}
+
+	dg.form = newFormEdit("gridSearch", "Search", sc)
+	dg.form.submitOnEnter = true
+
+	dg.form.callback = func(valid bool) {
+		dg.form.getValue()
+		dg.form.close(context.gocui)
+		dg.form = nil
+		if valid {
+			dg.search(sc.Search)
+		}
+	}
+
+	dg.form.initialize(context.gocui)
+}
+
+// search selects the first row, from the selected one, with a displayed
+// column containing text.
+func (dg *dataGrid) search(text string) {
+	dg.searchText = text
+	dg.findMatch(dg.selectedIndex)
+}
+
+func (dg *dataGrid) findNextMatch() {
+	dg.findMatch(dg.selectedIndex + 1)
+}
+
+func (dg *dataGrid) findMatch(start int) {
+	dg.searchFound = false
+
+	if len(dg.searchText) == 0 || dg.items == nil {
+		return
+	}
+
+	dg.refreshRows()
+
+	items := reflect.ValueOf(dg.items)
+	text := strings.ToLower(dg.searchText)
+
+	for i := 0; i < len(dg.rows); i++ {
+		index := (start + i) % len(dg.rows)
+		if dg.rowContains(items.Index(dg.rows[index]).Elem(), text) {
+			dg.searchFound = true
+			dg.selectedIndex = index
+			dg.scrollToSelection()
+			return
+		}
+	}
+}
+
+func (dg *dataGrid) rowContains(rowData reflect.Value, text string) bool {
+	for _, col := range dg.columns {
+		val := dg.getRowValue(rowData, col.propertyName)
+		if strings.Contains(strings.ToLower(dg.getCellString(&col.dataGridColumn, val)), text) {
+			return true
+		}
+	}
+	return false
+}
+
+func (dg *dataGrid) scrollToSelection() {
+	if dg.selectedIndex < dg.visibleStartIndex {
+		dg.visibleStartIndex = dg.selectedIndex
+	}
+	if dg.selectedIndex > dg.visibleStartIndex+dg.visibleHeight-3 {
+		dg.visibleStartIndex = dg.selectedIndex - dg.visibleHeight + 3
+	}
 }
 
 func (dg *dataGrid) setRenderSize(width int, height int) {
@@ -67,6 +390,10 @@ func (dg *dataGrid) initConfig() {
 	for _, col := range getConfigGridColumns(dg.key) {
 		dg.addDisplayColumn(col.key, col.header, col.width)
 	}
+	dg.setSortColumn(getConfigGridSort(dg.key))
+	if err := dg.setFilter(getConfigGridFilter(dg.key)); err != nil {
+		logError(fmt.Sprintf("Grid '%s' filter: %s", dg.key, err.Error()))
+	}
 }
 
 func (dg *dataGrid) addDisplayColumn(key string, header string, width int) {
@@ -78,17 +405,33 @@ func (dg *dataGrid) addDisplayColumn(key string, header string, width int) {
 		return
 	}
 
-	dg.columns = append(dg.columns, &dataGridColumnDisplay{*c, header, width})
+	dg.columns = append(dg.columns, &dataGridColumnDisplay{*c, key, header, width})
 }
 
 func (dg *dataGrid) addColumn(key string, propertyName string, format rowFormat) {
-	dg.availableColumns[key] = &dataGridColumn{propertyName, 0, format}
+	dg.availableColumns[key] = &dataGridColumn{propertyName, 0, format, satUnit}
+}
+
+// addAmountColumn adds a column of amounts in the native unit, displayed in
+// the unit set.
+func (dg *dataGrid) addAmountColumn(key string, propertyName string, unit amountUnit) {
+	dg.availableColumns[key] = &dataGridColumn{propertyName, 0, amountRow, unit}
+}
+
+// addFiatColumn adds a column of amounts in the native unit, displayed in the
+// fiat currency when its price is known.
+func (dg *dataGrid) addFiatColumn(key string, propertyName string, unit amountUnit) {
+	dg.availableColumns[key] = &dataGridColumn{propertyName, 0, fiatRow, unit}
 }
 
 func (dg *dataGrid) balanceColumnsWidth() {
 	var usedWidth = 0
 	var autoCount = 0
 
+	if dg.isMarkable() {
+		usedWidth++
+	}
+
 	for i := 0; i < len(dg.columns); i++ {
 		usedWidth += dg.columns[i].width
 		dg.columns[i].displayWidth = dg.columns[i].width
@@ -111,6 +454,19 @@ func (dg *dataGrid) displayRow(rowData reflect.Value, selected bool) string {
 
 	var buffer bytes.Buffer
 
+	if dg.isMarkable() {
+		if selected {
+			buffer.WriteString(dg.fmtSelected)
+		} else {
+			buffer.WriteString(dg.fmtForeground)
+		}
+		if dg.isMarked(rowData) {
+			buffer.WriteString("*")
+		} else {
+			buffer.WriteString(" ")
+		}
+	}
+
 	for _, col := range dg.columns {
 		val := dg.getRowValue(rowData, col.propertyName)
 
@@ -120,36 +476,7 @@ func (dg *dataGrid) displayRow(rowData reflect.Value, selected bool) string {
 			buffer.WriteString(dg.fmtForeground)
 		}
 
-		var tmpStr string
-		colWidth := strconv.Itoa(col.displayWidth - 1)
-
-		switch col.format {
-		case boolRow:
-			if val.Bool() {
-				tmpStr = "X"
-			} else {
-				tmpStr = " "
-			}
-		case intRow:
-			switch val.Kind() {
-			case reflect.Int64, reflect.Int16, reflect.Int32:
-				tmpStr = context.printer.Sprintf("%-"+colWidth+"d", val.Int())
-			case reflect.Uint64, reflect.Uint16, reflect.Uint32:
-				tmpStr = context.printer.Sprintf("%-"+colWidth+"d", val.Uint())
-			}
-		case stringRow:
-			tmpStr = fmt.Sprintf("%-"+colWidth+"s", val.String())
-		case dateRow:
-			if val.IsValid() {
-				tmpStr = fmt.Sprintf("%-"+colWidth+"s", time.Unix(val.Int(), 0).Format("02-01-06 15:04:05"))
-			} else {
-				tmpStr = fmt.Sprintf("%-"+colWidth+"s", " ")
-			}
-		case sliceRow:
-			tmpStr = fmt.Sprintf("%-"+colWidth+"s", getSliceString(val))
-		default:
-			tmpStr = fmt.Sprintf("%-"+colWidth+"s", " ")
-		}
+		tmpStr := fmt.Sprintf("%-"+strconv.Itoa(col.displayWidth-1)+"s", dg.getCellString(&col.dataGridColumn, val))
 
 		buffer.WriteString(cutTo(tmpStr, col.displayWidth-1))
 		buffer.WriteString("│")
@@ -158,6 +485,38 @@ func (dg *dataGrid) displayRow(rowData reflect.Value, selected bool) string {
 	return buffer.String()
 }
 
+func (dg *dataGrid) getCellString(col *dataGridColumn, val reflect.Value) string {
+	if !val.IsValid() {
+		return ""
+	}
+
+	switch col.format {
+	case boolRow:
+		if val.Bool() {
+			return "X"
+		}
+	case intRow:
+		switch val.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Int16, reflect.Int32:
+			return context.printer.Sprintf("%d", val.Int())
+		case reflect.Uint, reflect.Uint64, reflect.Uint16, reflect.Uint32:
+			return context.printer.Sprintf("%d", val.Uint())
+		}
+	case stringRow:
+		return val.String()
+	case dateRow:
+		return time.Unix(getRowValueInt64(val), 0).Format("02-01-06 15:04:05")
+	case sliceRow:
+		return getSliceString(val)
+	case amountRow:
+		return formatAmount(getRowValueInt64(val), col.unit, context.unit)
+	case fiatRow:
+		return status.state().price.formatFiat(getRowValueInt64(val), col.unit)
+	}
+
+	return ""
+}
+
 func getSliceString(val reflect.Value) string {
 
 	var buffer bytes.Buffer
@@ -211,12 +570,14 @@ func (dg *dataGrid) getGridRows() []string {
 		return nil
 	}
 
+	dg.refreshRows()
+
 	lastIndex := dg.visibleStartIndex + dg.visibleHeight - 2
 
 	items := reflect.ValueOf(dg.items)
 
-	if lastIndex > items.Len() {
-		lastIndex = items.Len()
+	if lastIndex > len(dg.rows) {
+		lastIndex = len(dg.rows)
 	}
 
 	var ret = make([]string, dg.visibleHeight)
@@ -227,7 +588,7 @@ func (dg *dataGrid) getGridRows() []string {
 	var dest = 2
 
 	for i := dg.visibleStartIndex; i < lastIndex; i++ {
-		o := items.Index(i).Elem()
+		o := items.Index(dg.rows[i]).Elem()
 		if !o.IsValid() {
 			break
 		}
@@ -253,7 +614,32 @@ func cutTo(s string, length int) string {
 }
 
 func (dg *dataGrid) generateHeader() string {
-	return fmt.Sprintf(dg.fmtHeader+context.theme.bold+"%-"+strconv.Itoa(dg.visibleWidth)+"s", dg.header)
+	header := dg.header
+
+	if len(dg.refreshStatus) > 0 {
+		header += "  " + dg.refreshStatus
+	}
+
+	if len(dg.info) > 0 {
+		header += "  " + dg.info
+	}
+
+	if dg.filter != nil && dg.items != nil {
+		header += fmt.Sprintf("  Filter: %s (%d/%d)", dg.filter.expression, len(dg.rows), reflect.ValueOf(dg.items).Len())
+	}
+
+	if n := dg.markedCount(); n > 0 {
+		header += fmt.Sprintf("  Marked: %d", n)
+	}
+
+	if len(dg.searchText) > 0 {
+		header += "  Search: " + dg.searchText
+		if !dg.searchFound {
+			header += " (not found)"
+		}
+	}
+
+	return fmt.Sprintf(dg.fmtHeader+context.theme.bold+"%-"+strconv.Itoa(dg.visibleWidth)+"s", header)
 }
 
 func (dg *dataGrid) generateColumnHeaders() string {
@@ -261,14 +647,28 @@ func (dg *dataGrid) generateColumnHeaders() string {
 
 	buffer.WriteString(dg.fmtHeader)
 
-	for _, col := range dg.columns {
+	if dg.isMarkable() {
+		buffer.WriteString(" ")
+	}
+
+	for i, col := range dg.columns {
 		display := cutTo(col.header, col.displayWidth-1)
+		if i == dg.sortColumn {
+			display = fmt.Sprintf("%-"+strconv.Itoa(col.displayWidth-2)+"s%s", cutTo(col.header, col.displayWidth-2), dg.getSortIndicator())
+		}
 		buffer.WriteString(fmt.Sprintf("%-"+strconv.Itoa(col.displayWidth-1)+"s│", display))
 	}
 
 	return buffer.String()
 }
 
+func (dg *dataGrid) getSortIndicator() string {
+	if dg.sortDescending {
+		return "▼"
+	}
+	return "▲"
+}
+
 func (dg *dataGrid) moveSelectionUp() {
 
 	if dg.selectedIndex == 0 {
@@ -284,15 +684,17 @@ func (dg *dataGrid) moveSelectionUp() {
 
 func (dg *dataGrid) moveSelectionDown() {
 
-	len := reflect.ValueOf(dg.items).Len()
+	dg.refreshRows()
+
+	count := len(dg.rows)
 
-	if dg.selectedIndex == len-1 {
+	if count == 0 || dg.selectedIndex == count-1 {
 		return
 	}
 
 	dg.selectedIndex++
 
-	lastPossibleIndex := len - 1
+	lastPossibleIndex := count - 1
 
 	if dg.selectedIndex > lastPossibleIndex {
 		dg.selectedIndex = lastPossibleIndex
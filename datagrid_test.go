@@ -0,0 +1,283 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+type testGridItem struct {
+	Name    string
+	Amount  int64
+	Flag    bool
+	Created int64
+	Tags    []string
+}
+
+func (i *testGridItem) GetUpperName() string {
+	return strings.ToUpper(i.Name)
+}
+
+func newTestGrid(items []*testGridItem) *dataGrid {
+	context.printer = message.NewPrinter(language.English)
+
+	dg := makeNewDataGrid()
+	dg.header = "[Test]"
+	dg.addColumn("Name", "Name", stringRow)
+	dg.addColumn("Upper", "GetUpperName", stringRow)
+	dg.addColumn("Amount", "Amount", intRow)
+	dg.addColumn("Flag", "Flag", boolRow)
+	dg.addColumn("Created", "Created", dateRow)
+	dg.addColumn("Tags", "Tags", sliceRow)
+	dg.addDisplayColumn("Name", "Name", 0)
+	dg.addDisplayColumn("Upper", "Upper", 8)
+	dg.addDisplayColumn("Amount", "Amount", 12)
+	dg.addDisplayColumn("Flag", "F", 2)
+	dg.addDisplayColumn("Created", "Created", 18)
+	dg.addDisplayColumn("Tags", "Tags", 20)
+	dg.items = items
+	dg.setRenderSize(80, 6)
+	return dg
+}
+
+func testGridItems(n int) []*testGridItem {
+	var items []*testGridItem
+	for i := 0; i < n; i++ {
+		items = append(items, &testGridItem{
+			Name:    "item" + string(rune('a'+i)),
+			Amount:  int64(1000000 * (i + 1)),
+			Flag:    i%2 == 0,
+			Created: time.Date(2019, 1, 1+i, 12, 0, 0, 0, time.Local).Unix(),
+			Tags:    []string{"x", "y"},
+		})
+	}
+	return items
+}
+
+func TestDisplayRowFormats(t *testing.T) {
+	dg := newTestGrid(testGridItems(1))
+
+	row := dg.displayRow(reflect.ValueOf(dg.items).Index(0).Elem(), false)
+	cells := strings.Split(row, "│")
+
+	expected := []string{"itema", "ITEMA", "1,000,000", "X", "01-01-19 12:00:00", "x, y"}
+	for i, e := range expected {
+		if strings.TrimSpace(cells[i]) != e {
+			t.Errorf("cell %d = %q, want %q", i, cells[i], e)
+		}
+	}
+}
+
+func TestBalanceColumnsWidth(t *testing.T) {
+	dg := newTestGrid(nil)
+
+	if dg.columns[0].displayWidth != 80-8-12-2-18-20 {
+		t.Errorf("auto column width = %d", dg.columns[0].displayWidth)
+	}
+}
+
+func TestGetGridRows(t *testing.T) {
+	dg := newTestGrid(testGridItems(3))
+
+	rows := dg.getGridRows()
+
+	if len(rows) != 6 {
+		t.Fatalf("got %d rows", len(rows))
+	}
+	if !strings.HasPrefix(rows[0], "[Test]") {
+		t.Errorf("header row = %q", rows[0])
+	}
+	if !strings.HasPrefix(rows[1], "Name") {
+		t.Errorf("column header row = %q", rows[1])
+	}
+	for i, name := range []string{"itema", "itemb", "itemc"} {
+		if !strings.HasPrefix(rows[i+2], name) {
+			t.Errorf("row %d = %q", i+2, rows[i+2])
+		}
+	}
+}
+
+func TestGridSelectionScrolling(t *testing.T) {
+	dg := newTestGrid(testGridItems(6))
+	dg.fmtSelected = ">"
+
+	for i := 0; i < 10; i++ {
+		dg.moveSelectionDown()
+	}
+
+	if dg.selectedIndex != 5 {
+		t.Errorf("selected index = %d", dg.selectedIndex)
+	}
+	if dg.visibleStartIndex != 2 {
+		t.Errorf("visible start index = %d", dg.visibleStartIndex)
+	}
+
+	rows := dg.getGridRows()
+	if !strings.HasPrefix(rows[5], ">itemf") {
+		t.Errorf("last row = %q", rows[5])
+	}
+
+	for i := 0; i < 10; i++ {
+		dg.moveSelectionUp()
+	}
+
+	if dg.selectedIndex != 0 || dg.visibleStartIndex != 0 {
+		t.Errorf("selected index = %d, visible start index = %d", dg.selectedIndex, dg.visibleStartIndex)
+	}
+}
+
+func TestCutTo(t *testing.T) {
+	if s := cutTo("│abc│", 3); s != "│ab" {
+		t.Errorf("cutTo = %q", s)
+	}
+	if s := cutTo("abc", 0); s != "" {
+		t.Errorf("cutTo = %q", s)
+	}
+}
+
+func gridRowNames(dg *dataGrid) []string {
+	var names []string
+	for _, r := range dg.getGridRows()[2:] {
+		if len(r) > 0 {
+			names = append(names, strings.TrimSpace(strings.Split(r, "│")[0]))
+		}
+	}
+	return names
+}
+
+func TestGridSort(t *testing.T) {
+	items := testGridItems(4)
+	items[0].Name = "Delta"
+	items[1].Name = "alpha"
+	items[2].Name = "charlie"
+	items[3].Name = "Bravo"
+	dg := newTestGrid(items)
+	dg.setRenderSize(80, 8)
+
+	dg.setSortColumn("Name", false)
+	if names := strings.Join(gridRowNames(dg), ","); names != "alpha,Bravo,charlie,Delta" {
+		t.Errorf("ascending string sort = %s", names)
+	}
+
+	dg.reverseSort()
+	if names := strings.Join(gridRowNames(dg), ","); names != "Delta,charlie,Bravo,alpha" {
+		t.Errorf("descending string sort = %s", names)
+	}
+
+	dg.setSortColumn("Amount", true)
+	if names := strings.Join(gridRowNames(dg), ","); names != "Bravo,charlie,alpha,Delta" {
+		t.Errorf("descending int sort = %s", names)
+	}
+
+	dg.setSortColumn("Flag", false)
+	if names := strings.Join(gridRowNames(dg), ","); names != "alpha,Bravo,Delta,charlie" {
+		t.Errorf("bool sort = %s", names)
+	}
+
+	dg.setSortColumn("", false)
+	if names := strings.Join(gridRowNames(dg), ","); names != "Delta,alpha,charlie,Bravo" {
+		t.Errorf("unsorted = %s", names)
+	}
+}
+
+func TestGridSortSelection(t *testing.T) {
+	dg := newTestGrid(testGridItems(3))
+	dg.setSortColumn("Created", true)
+
+	if item := dg.getSelectedItem().Interface().(*testGridItem); item.Name != "itemc" {
+		t.Errorf("selected %s", item.Name)
+	}
+}
+
+func TestGridSortPersistsAcrossRefresh(t *testing.T) {
+	dg := newTestGrid(testGridItems(3))
+	dg.setSortColumn("Amount", true)
+
+	dg.items = testGridItems(2)
+
+	if names := strings.Join(gridRowNames(dg), ","); names != "itemb,itema" {
+		t.Errorf("rows after refresh = %s", names)
+	}
+}
+
+func TestGridSortIndicator(t *testing.T) {
+	dg := newTestGrid(nil)
+
+	dg.nextSortColumn()
+	dg.nextSortColumn()
+	if h := dg.generateColumnHeaders(); !strings.Contains(h, "Upper ▲│") {
+		t.Errorf("column headers = %q", h)
+	}
+
+	dg.reverseSort()
+	if h := dg.generateColumnHeaders(); !strings.Contains(h, "Upper ▼│") {
+		t.Errorf("column headers = %q", h)
+	}
+}
+
+func TestGridFilterHeader(t *testing.T) {
+	dg := newTestGrid(testGridItems(3))
+
+	if err := dg.setFilter("Flag=true"); err != nil {
+		t.Fatal(err)
+	}
+
+	if rows := dg.getGridRows(); !strings.Contains(rows[0], "[Test]  Filter: Flag=true (2/3)") {
+		t.Errorf("header row = %q", rows[0])
+	}
+}
+
+func TestGridFilterKeepsSelectionInRange(t *testing.T) {
+	dg := newTestGrid(testGridItems(4))
+	dg.selectedIndex = 3
+
+	if err := dg.setFilter("Amount<3000000"); err != nil {
+		t.Fatal(err)
+	}
+
+	if item := dg.getSelectedItem().Interface().(*testGridItem); item.Name != "itemb" {
+		t.Errorf("selected %s", item.Name)
+	}
+}
+
+func TestGridSearch(t *testing.T) {
+	items := testGridItems(8)
+	items[1].Tags = []string{"peer"}
+	items[6].Tags = []string{"peer"}
+	dg := newTestGrid(items)
+
+	dg.search("PEER")
+	if dg.selectedIndex != 1 || !dg.searchFound {
+		t.Fatalf("selected index = %d, found = %v", dg.selectedIndex, dg.searchFound)
+	}
+
+	dg.findNextMatch()
+	if dg.selectedIndex != 6 {
+		t.Errorf("selected index = %d", dg.selectedIndex)
+	}
+	if dg.visibleStartIndex != 3 {
+		t.Errorf("visible start index = %d", dg.visibleStartIndex)
+	}
+
+	dg.findNextMatch()
+	if dg.selectedIndex != 1 || dg.visibleStartIndex != 1 {
+		t.Errorf("selected index = %d, visible start index = %d", dg.selectedIndex, dg.visibleStartIndex)
+	}
+
+	dg.search("1,000,000")
+	if item := dg.getSelectedItem().Interface().(*testGridItem); item.Name != "itema" {
+		t.Errorf("formatted amount search selected %s", item.Name)
+	}
+
+	dg.search("nothing")
+	if dg.searchFound || dg.selectedIndex != 0 {
+		t.Errorf("selected index = %d, found = %v", dg.selectedIndex, dg.searchFound)
+	}
+	if rows := dg.getGridRows(); !strings.Contains(rows[0], "Search: nothing (not found)") {
+		t.Errorf("header row = %q", rows[0])
+	}
+}
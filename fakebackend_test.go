@@ -0,0 +1,860 @@
+package main
+
+import (
+	gocontext "context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+)
+
+// fakeBackend is an in-process nodeBackend returning canned lnrpc responses
+// and recording the requests issued by the views.
+type fakeBackend struct {
+	mutex sync.Mutex
+
+	info         *lnrpc.GetInfoResponse
+	balance      *lnrpc.WalletBalanceResponse
+	channels     *lnrpc.ListChannelsResponse
+	pending      *lnrpc.PendingChannelsResponse
+	peers        *lnrpc.ListPeersResponse
+	invoices     *lnrpc.ListInvoiceResponse
+	payments     *lnrpc.ListPaymentsResponse
+	transactions *lnrpc.TransactionDetails
+	utxos        []*lnrpc.Utxo
+	leases       []*walletrpc.UtxoLease
+	forwards     []*lnrpc.ForwardingEvent
+	fees         *lnrpc.FeeReportResponse
+	edges        map[uint64]*lnrpc.ChannelEdge
+	nodes        map[string]*lnrpc.NodeInfo
+	address      string
+	payReqResult string
+	payReq       *lnrpc.PayReq
+	paymentError string
+	paymentRoute *lnrpc.Route
+	// paymentDone, when set, holds the payments until closed.
+	paymentDone chan struct{}
+
+	// err, when set, is returned by every call.
+	err error
+	// callErrors are returned by the calls they are keyed by.
+	callErrors map[string]error
+
+	calls               []string
+	lastListInvoices    *lnrpc.ListInvoiceRequest
+	lastListPayments    *lnrpc.ListPaymentsRequest
+	lastForwarding      *lnrpc.ForwardingHistoryRequest
+	lastPolicyUpdate    *lnrpc.PolicyUpdateRequest
+	lastOpenChannel     *lnrpc.OpenChannelRequest
+	lastOpenConnect     string
+	lastOpenBlock       bool
+	lastCloseChannel    string
+	lastCloseForce      bool
+	lastCloseConf       int32
+	lastCloseSatPerByte int64
+	closed              []string
+	closeErrors         map[string]error
+	lastConnect         string
+	lastDisconnect      string
+	lastInvoice         *lnrpc.Invoice
+	lastReceipt         string
+	lastPayment         *lnrpc.SendRequest
+	lastNewAddressType  string
+	lastSendCoins       *lnrpc.SendCoinsRequest
+	lastSendMany        *lnrpc.SendManyRequest
+	lastLease           *walletrpc.LeaseOutputRequest
+
+	// the streams' updates, closing one drops its stream.
+	invoiceEvents     chan *lnrpc.Invoice
+	channelEvents     chan *lnrpc.ChannelEventUpdate
+	transactionEvents chan *lnrpc.Transaction
+	peerEvents        chan *lnrpc.PeerEvent
+}
+
+func testPubkey(n byte) string {
+	return "02" + strings.Repeat(hex.EncodeToString([]byte{n}), 32)
+}
+
+func testNodeInfo(pubkey string, alias string, lastUpdate uint32) *lnrpc.NodeInfo {
+	return &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{
+			PubKey:     pubkey,
+			Alias:      alias,
+			LastUpdate: lastUpdate,
+			Addresses:  []*lnrpc.NodeAddress{{Network: "tcp", Addr: alias + ".example.com:9735"}},
+		},
+		NumChannels:   12,
+		TotalCapacity: 50000000,
+	}
+}
+
+func newFakeBackend() *fakeBackend {
+	f := new(fakeBackend)
+
+	f.info = &lnrpc.GetInfoResponse{
+		Alias:              "fakenode",
+		IdentityPubkey:     testPubkey(0xff),
+		Version:            "0.5.1-beta",
+		Chains:             []string{"bitcoin"},
+		Testnet:            true,
+		SyncedToChain:      true,
+		NumPeers:           2,
+		NumActiveChannels:  1,
+		NumPendingChannels: 4,
+		BlockHeight:        600000,
+	}
+
+	f.balance = &lnrpc.WalletBalanceResponse{
+		TotalBalance:       1500000,
+		ConfirmedBalance:   1000000,
+		UnconfirmedBalance: 500000,
+	}
+
+	f.channels = &lnrpc.ListChannelsResponse{
+		Channels: []*lnrpc.Channel{
+			{
+				Active:        true,
+				RemotePubkey:  testPubkey(1),
+				ChannelPoint:  strings.Repeat("a1", 32) + ":0",
+				ChanId:        659767854661451776,
+				Capacity:      2000000,
+				LocalBalance:  1500000,
+				RemoteBalance: 490950,
+				CommitFee:     9050,
+				CommitWeight:  724,
+				FeePerKw:      12500,
+				CsvDelay:      144,
+				Initiator:     true,
+				PendingHtlcs: []*lnrpc.HTLC{
+					{Incoming: true, Amount: 1000, ExpirationHeight: 600100},
+				},
+			},
+			{
+				Active:        false,
+				Private:       true,
+				RemotePubkey:  testPubkey(2),
+				ChannelPoint:  strings.Repeat("b2", 32) + ":1",
+				ChanId:        659767854661451777,
+				Capacity:      500000,
+				LocalBalance:  0,
+				RemoteBalance: 490950,
+				CommitFee:     9050,
+				CommitWeight:  724,
+				FeePerKw:      12500,
+				CsvDelay:      144,
+			},
+		},
+	}
+
+	f.pending = &lnrpc.PendingChannelsResponse{
+		TotalLimboBalance: 250000,
+		PendingOpenChannels: []*lnrpc.PendingChannelsResponse_PendingOpenChannel{
+			{
+				Channel: &lnrpc.PendingChannelsResponse_PendingChannel{
+					RemoteNodePub: testPubkey(3),
+					ChannelPoint:  strings.Repeat("c3", 32) + ":0",
+					Capacity:      300000,
+					LocalBalance:  290950,
+				},
+				CommitFee: 9050,
+			},
+		},
+		PendingClosingChannels: []*lnrpc.PendingChannelsResponse_ClosedChannel{
+			{
+				Channel: &lnrpc.PendingChannelsResponse_PendingChannel{
+					RemoteNodePub: testPubkey(1),
+					ChannelPoint:  strings.Repeat("d4", 32) + ":0",
+					Capacity:      100000,
+					LocalBalance:  50000,
+				},
+				ClosingTxid: strings.Repeat("e5", 32),
+			},
+		},
+		PendingForceClosingChannels: []*lnrpc.PendingChannelsResponse_ForceClosedChannel{
+			{
+				Channel: &lnrpc.PendingChannelsResponse_PendingChannel{
+					RemoteNodePub: testPubkey(2),
+					ChannelPoint:  strings.Repeat("f6", 32) + ":0",
+					Capacity:      400000,
+					LocalBalance:  250000,
+				},
+				ClosingTxid:       strings.Repeat("a7", 32),
+				LimboBalance:      250000,
+				BlocksTilMaturity: 120,
+			},
+		},
+		WaitingCloseChannels: []*lnrpc.PendingChannelsResponse_WaitingCloseChannel{
+			{
+				Channel: &lnrpc.PendingChannelsResponse_PendingChannel{
+					RemoteNodePub: testPubkey(3),
+					ChannelPoint:  strings.Repeat("b8", 32) + ":2",
+					Capacity:      200000,
+					LocalBalance:  10000,
+				},
+				LimboBalance: 10000,
+			},
+		},
+	}
+
+	f.peers = &lnrpc.ListPeersResponse{
+		Peers: []*lnrpc.Peer{
+			{PubKey: testPubkey(1), Address: "203.0.113.1:9735", BytesSent: 1024, BytesRecv: 2048, PingTime: 150},
+			{PubKey: testPubkey(2), Address: "203.0.113.2:9735", Inbound: true, SatSent: 1000, SatRecv: 2000},
+		},
+	}
+
+	f.invoices = &lnrpc.ListInvoiceResponse{
+		Invoices: []*lnrpc.Invoice{
+			{Memo: "coffee", Value: 25000, Settled: true, CreationDate: 1546300800, SettleDate: 1546300860, Expiry: 3600, AmtPaidMsat: 25000000, PaymentRequest: "lntb250u1fakecoffee", AddIndex: 2, SettleIndex: 1},
+			{Memo: "donation", Value: 100000, CreationDate: 1546387200, Expiry: 86400, PaymentRequest: "lntb1m1fakedonation", AddIndex: 1},
+		},
+		FirstIndexOffset: 1,
+		LastIndexOffset:  2,
+	}
+
+	f.payments = &lnrpc.ListPaymentsResponse{
+		Payments: []*lnrpc.Payment{
+			{PaymentHash: strings.Repeat("01", 32), ValueMsat: 10000000, CreationDate: 1546300800, Fee: 1, PaymentPreimage: strings.Repeat("02", 32), Path: []string{testPubkey(1), testPubkey(3)}, PaymentIndex: 1},
+		},
+	}
+
+	f.transactions = &lnrpc.TransactionDetails{
+		Transactions: []*lnrpc.Transaction{
+			{TxHash: strings.Repeat("0a", 32), Amount: 1000000, NumConfirmations: 6, BlockHeight: 599994, TimeStamp: 1546300800, TotalFees: 282, DestAddresses: []string{"tb1qfakeaddress0"}},
+			{TxHash: strings.Repeat("0b", 32), Amount: -2009050, NumConfirmations: 0, TimeStamp: 1546387200, TotalFees: 9050, DestAddresses: []string{"tb1qfakeaddress1", "tb1qfakeaddress2"}},
+		},
+	}
+
+	f.utxos = []*lnrpc.Utxo{
+		{AddressType: lnrpc.AddressType_WITNESS_PUBKEY_HASH, Address: "tb1qfakeaddress0", AmountSat: 600000, Outpoint: &lnrpc.OutPoint{TxidStr: strings.Repeat("0a", 32), OutputIndex: 0}, Confirmations: 6},
+		{AddressType: lnrpc.AddressType_NESTED_PUBKEY_HASH, Address: "2NfakeNestedAddress", AmountSat: 400000, Outpoint: &lnrpc.OutPoint{TxidStr: strings.Repeat("0a", 32), OutputIndex: 1}, Confirmations: 6},
+		{AddressType: lnrpc.AddressType_TAPROOT_PUBKEY, Address: "tb1pfakeaddress3", AmountSat: 500000, Outpoint: &lnrpc.OutPoint{TxidStr: strings.Repeat("0b", 32), OutputIndex: 2}, Confirmations: 0},
+	}
+
+	now := uint64(time.Now().Unix())
+	f.forwards = []*lnrpc.ForwardingEvent{
+		{Timestamp: now - 3600, ChanIdIn: 659767854661451776, ChanIdOut: 659767854661451777, AmtIn: 100010, AmtOut: 100000, Fee: 10, FeeMsat: 10500},
+		{Timestamp: now - 3*24*3600, ChanIdIn: 659767854661451777, ChanIdOut: 659767854661451776, AmtIn: 50002, AmtOut: 50000, Fee: 2, FeeMsat: 2000},
+		{Timestamp: now - 20*24*3600, ChanIdIn: 659767854661451776, ChanIdOut: 123, AmtIn: 1000100, AmtOut: 1000000, Fee: 100, FeeMsat: 100000},
+	}
+
+	f.fees = &lnrpc.FeeReportResponse{
+		ChannelFees: []*lnrpc.ChannelFeeReport{
+			{ChannelPoint: strings.Repeat("a1", 32) + ":0", BaseFeeMsat: 1000, FeePerMil: 1, FeeRate: 0.000001},
+			{ChannelPoint: strings.Repeat("b2", 32) + ":1", BaseFeeMsat: 0, FeePerMil: 250, FeeRate: 0.00025},
+		},
+	}
+
+	f.edges = map[uint64]*lnrpc.ChannelEdge{
+		659767854661451776: {
+			ChannelId:   659767854661451776,
+			ChanPoint:   strings.Repeat("a1", 32) + ":0",
+			Node1Pub:    testPubkey(1),
+			Node2Pub:    testPubkey(0xff),
+			Capacity:    2000000,
+			Node1Policy: &lnrpc.RoutingPolicy{TimeLockDelta: 144, MinHtlc: 1, FeeBaseMsat: 0, FeeRateMilliMsat: 10, MaxHtlcMsat: 1980000000},
+			Node2Policy: &lnrpc.RoutingPolicy{TimeLockDelta: 40, MinHtlc: 1000, FeeBaseMsat: 1000, FeeRateMilliMsat: 1, MaxHtlcMsat: 1980000000},
+		},
+	}
+
+	f.nodes = make(map[string]*lnrpc.NodeInfo)
+	f.nodes[testPubkey(1)] = testNodeInfo(testPubkey(1), "acinq", 1546300000)
+	f.nodes[testPubkey(1)].Node.Features = map[uint32]*lnrpc.Feature{
+		5: {Name: "upfront-shutdown-script", IsKnown: true},
+		0: {Name: "data-loss-protect", IsRequired: true, IsKnown: true},
+	}
+	f.nodes[testPubkey(2)] = testNodeInfo(testPubkey(2), "bitrefill", 1546200000)
+	f.nodes[testPubkey(3)] = testNodeInfo(testPubkey(3), "yalls", 1546100000)
+
+	f.invoiceEvents = make(chan *lnrpc.Invoice)
+	f.channelEvents = make(chan *lnrpc.ChannelEventUpdate)
+	f.transactionEvents = make(chan *lnrpc.Transaction)
+	f.peerEvents = make(chan *lnrpc.PeerEvent)
+
+	f.payReq = &lnrpc.PayReq{
+		Destination: testPubkey(3),
+		PaymentHash: strings.Repeat("03", 32),
+		NumSatoshis: 25000,
+		Timestamp:   time.Now().Unix(),
+		Expiry:      3600,
+		Description: "coffee",
+		CltvExpiry:  40,
+	}
+
+	f.address = "tb1qfakenewaddress"
+	f.payReqResult = "lntb1fakeaddedinvoice"
+
+	return f
+}
+
+func (f *fakeBackend) record(call string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls = append(f.calls, call)
+	if err, ok := f.callErrors[call]; ok {
+		return err
+	}
+	return f.err
+}
+
+func (f *fakeBackend) setError(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.err = err
+}
+
+func (f *fakeBackend) callCount(call string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	count := 0
+	for _, c := range f.calls {
+		if c == call {
+			count++
+		}
+	}
+	return count
+}
+
+func (f *fakeBackend) getInfo() (*lnrpc.GetInfoResponse, error) {
+	if err := f.record("getinfo"); err != nil {
+		return nil, err
+	}
+	return f.info, nil
+}
+
+func (f *fakeBackend) walletBalance() (*lnrpc.WalletBalanceResponse, error) {
+	if err := f.record("walletbalance"); err != nil {
+		return nil, err
+	}
+	return f.balance, nil
+}
+
+func (f *fakeBackend) listChannels() (*lnrpc.ListChannelsResponse, error) {
+	if err := f.record("listchannels"); err != nil {
+		return nil, err
+	}
+	return f.channels, nil
+}
+
+func (f *fakeBackend) pendingChannels() (*lnrpc.PendingChannelsResponse, error) {
+	if err := f.record("pendingchannels"); err != nil {
+		return nil, err
+	}
+	return f.pending, nil
+}
+
+func (f *fakeBackend) listPeers() (*lnrpc.ListPeersResponse, error) {
+	if err := f.record("listpeers"); err != nil {
+		return nil, err
+	}
+	return f.peers, nil
+}
+
+// fakePage returns the positions in items of the page lnd returns from
+// offset, items being sorted by index.
+func fakePage(indexes []uint64, offset uint64, max uint64, reversed bool) (int, int) {
+	if !reversed {
+		start := 0
+		for start < len(indexes) && indexes[start] <= offset {
+			start++
+		}
+		end := start + int(max)
+		if end > len(indexes) {
+			end = len(indexes)
+		}
+		return start, end
+	}
+	end := len(indexes)
+	if offset > 0 {
+		end = 0
+		for end < len(indexes) && indexes[end] < offset {
+			end++
+		}
+	}
+	start := end - int(max)
+	if start < 0 {
+		start = 0
+	}
+	return start, end
+}
+
+// listInvoices pages the invoices as lnd does, by add index.
+func (f *fakeBackend) listInvoices(req *lnrpc.ListInvoiceRequest) (*lnrpc.ListInvoiceResponse, error) {
+	if err := f.record("listinvoices"); err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastListInvoices = req
+
+	var invoices []*lnrpc.Invoice
+	var indexes []uint64
+	for _, i := range f.invoices.Invoices {
+		if req.PendingOnly && (i.Settled || i.State == lnrpc.Invoice_CANCELED) {
+			continue
+		}
+		invoices = append(invoices, i)
+	}
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].AddIndex < invoices[j].AddIndex })
+	for _, i := range invoices {
+		indexes = append(indexes, i.AddIndex)
+	}
+
+	start, end := fakePage(indexes, req.IndexOffset, req.NumMaxInvoices, req.Reversed)
+	resp := &lnrpc.ListInvoiceResponse{Invoices: invoices[start:end]}
+	if start < end {
+		resp.FirstIndexOffset, resp.LastIndexOffset = indexes[start], indexes[end-1]
+	}
+	return resp, nil
+}
+
+// listPayments pages the payments as lnd does, by payment index.
+func (f *fakeBackend) listPayments(req *lnrpc.ListPaymentsRequest) (*lnrpc.ListPaymentsResponse, error) {
+	if err := f.record("listpayments"); err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastListPayments = req
+
+	payments := f.payments.Payments
+	var indexes []uint64
+	for _, p := range payments {
+		indexes = append(indexes, p.PaymentIndex)
+	}
+
+	start, end := fakePage(indexes, req.IndexOffset, req.MaxPayments, req.Reversed)
+	resp := &lnrpc.ListPaymentsResponse{Payments: payments[start:end]}
+	if start < end {
+		resp.FirstIndexOffset, resp.LastIndexOffset = indexes[start], indexes[end-1]
+	}
+	return resp, nil
+}
+
+func (f *fakeBackend) listChainTxns() (*lnrpc.TransactionDetails, error) {
+	if err := f.record("listchaintxns"); err != nil {
+		return nil, err
+	}
+	return f.transactions, nil
+}
+
+// forwardingHistory returns the forwards in the requested time range, paged
+// as lnd does by IndexOffset and NumMaxEvents.
+func (f *fakeBackend) forwardingHistory(req *lnrpc.ForwardingHistoryRequest) (*lnrpc.ForwardingHistoryResponse, error) {
+	if err := f.record("fwdinghistory"); err != nil {
+		return nil, err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.lastForwarding = req
+
+	var events []*lnrpc.ForwardingEvent
+	for _, e := range f.forwards {
+		if e.Timestamp >= req.StartTime && e.Timestamp < req.EndTime {
+			events = append(events, e)
+		}
+	}
+
+	resp := &lnrpc.ForwardingHistoryResponse{LastOffsetIndex: req.IndexOffset}
+	for i := int(req.IndexOffset); i < len(events) && len(resp.ForwardingEvents) < int(req.NumMaxEvents); i++ {
+		resp.ForwardingEvents = append(resp.ForwardingEvents, events[i])
+		resp.LastOffsetIndex = uint32(i + 1)
+	}
+
+	return resp, nil
+}
+
+func (f *fakeBackend) getChanInfo(chanID uint64) (*lnrpc.ChannelEdge, error) {
+	if err := f.record("getchaninfo"); err != nil {
+		return nil, err
+	}
+	edge, ok := f.edges[chanID]
+	if !ok {
+		return nil, errors.New("edge not found")
+	}
+	return edge, nil
+}
+
+func (f *fakeBackend) feeReport() (*lnrpc.FeeReportResponse, error) {
+	if err := f.record("feereport"); err != nil {
+		return nil, err
+	}
+	return f.fees, nil
+}
+
+func (f *fakeBackend) updateChannelPolicy(req *lnrpc.PolicyUpdateRequest) error {
+	if err := f.record("updatechanpolicy"); err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	f.lastPolicyUpdate = req
+	f.mutex.Unlock()
+	return nil
+}
+
+func (f *fakeBackend) getNodeInfo(pubkey string) (*lnrpc.NodeInfo, error) {
+	if err := f.record("getnodeinfo"); err != nil {
+		return nil, err
+	}
+	ni, ok := f.nodes[pubkey]
+	if !ok {
+		return nil, errors.New("unable to find node")
+	}
+	return ni, nil
+}
+
+func (f *fakeBackend) openChannel(req *lnrpc.OpenChannelRequest, connect string, block bool) (string, error) {
+	if err := f.record("openchannel"); err != nil {
+		return "", err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastOpenChannel = req
+	f.lastOpenConnect = connect
+	f.lastOpenBlock = block
+	return strings.Repeat("9f", 32), nil
+}
+
+func (f *fakeBackend) closeChannel(channelPoint string, force bool, targetConf int32, satPerByte int64) (string, error) {
+	if err := f.record("closechannel"); err != nil {
+		return "", err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastCloseChannel = channelPoint
+	f.lastCloseForce = force
+	f.lastCloseConf = targetConf
+	f.lastCloseSatPerByte = satPerByte
+	f.closed = append(f.closed, channelPoint)
+	if err, ok := f.closeErrors[channelPoint]; ok {
+		return "", err
+	}
+	return strings.Repeat("8e", 32), nil
+}
+
+func (f *fakeBackend) connectPeer(pubkey string, host string) error {
+	if err := f.record("connect"); err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastConnect = pubkey + "@" + host
+	return nil
+}
+
+func (f *fakeBackend) disconnectPeer(pubkey string) error {
+	if err := f.record("disconnect"); err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastDisconnect = pubkey
+	return nil
+}
+
+func (f *fakeBackend) addInvoice(invoice *lnrpc.Invoice, receipt string) (*lnrpc.AddInvoiceResponse, error) {
+	if err := f.record("addinvoice"); err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastInvoice = invoice
+	f.lastReceipt = receipt
+	return &lnrpc.AddInvoiceResponse{PaymentRequest: f.payReqResult}, nil
+}
+
+func (f *fakeBackend) decodePayReq(payReq string) (*lnrpc.PayReq, error) {
+	if err := f.record("decodepayreq"); err != nil {
+		return nil, err
+	}
+	return f.payReq, nil
+}
+
+func (f *fakeBackend) payInvoice(req *lnrpc.SendRequest) (*lnrpc.SendResponse, error) {
+	if err := f.record("payinvoice"); err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	f.lastPayment = req
+	done := f.paymentDone
+	f.mutex.Unlock()
+	if done != nil {
+		<-done
+	}
+	return &lnrpc.SendResponse{PaymentError: f.paymentError, PaymentPreimage: []byte{1, 2, 3}, PaymentRoute: f.paymentRoute}, nil
+}
+
+// queryRoutes returns a route through the outgoing channel to the
+// destination, its first hop charging 1,000 mSat plus 1 mSat per channel id
+// unit modulo 1,000.
+func (f *fakeBackend) queryRoutes(req *lnrpc.QueryRoutesRequest) (*lnrpc.QueryRoutesResponse, error) {
+	if err := f.record("queryroutes"); err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, c := range f.channels.Channels {
+		if req.OutgoingChanId != 0 && c.ChanId != req.OutgoingChanId {
+			continue
+		}
+		fee := 1000 + int64(c.ChanId%1000)
+		return &lnrpc.QueryRoutesResponse{Routes: []*lnrpc.Route{{
+			TotalTimeLock: f.info.BlockHeight + 184,
+			TotalFees:     fee / 1000,
+			TotalFeesMsat: fee,
+			TotalAmt:      req.Amt + fee/1000,
+			Hops: []*lnrpc.Hop{
+				{ChanId: c.ChanId, PubKey: c.RemotePubkey, FeeMsat: fee},
+				{ChanId: 1, PubKey: req.PubKey},
+			},
+		}}}, nil
+	}
+	return nil, errors.New("unable to find a path to destination")
+}
+
+func (f *fakeBackend) newAddress(addressType string) (string, error) {
+	if err := f.record("newaddress"); err != nil {
+		return "", err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastNewAddressType = addressType
+	return f.address, nil
+}
+
+// dropInvoiceStream closes the invoice stream, the next subscription getting
+// a new one.
+func (f *fakeBackend) dropInvoiceStream() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	close(f.invoiceEvents)
+	f.invoiceEvents = make(chan *lnrpc.Invoice)
+}
+
+func (f *fakeBackend) subscribeInvoices(ctx gocontext.Context) (func() (*lnrpc.Invoice, error), error) {
+	if err := f.record("subscribeinvoices"); err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	events := f.invoiceEvents
+	f.mutex.Unlock()
+	return func() (*lnrpc.Invoice, error) {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil, io.EOF
+			}
+			return e, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}, nil
+}
+
+func (f *fakeBackend) subscribeChannelEvents(ctx gocontext.Context) (func() (*lnrpc.ChannelEventUpdate, error), error) {
+	if err := f.record("subscribechannelevents"); err != nil {
+		return nil, err
+	}
+	return func() (*lnrpc.ChannelEventUpdate, error) {
+		select {
+		case e := <-f.channelEvents:
+			return e, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}, nil
+}
+
+func (f *fakeBackend) subscribeTransactions(ctx gocontext.Context) (func() (*lnrpc.Transaction, error), error) {
+	if err := f.record("subscribetransactions"); err != nil {
+		return nil, err
+	}
+	return func() (*lnrpc.Transaction, error) {
+		select {
+		case e := <-f.transactionEvents:
+			return e, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}, nil
+}
+
+func (f *fakeBackend) subscribePeerEvents(ctx gocontext.Context) (func() (*lnrpc.PeerEvent, error), error) {
+	if err := f.record("subscribepeerevents"); err != nil {
+		return nil, err
+	}
+	return func() (*lnrpc.PeerEvent, error) {
+		select {
+		case e := <-f.peerEvents:
+			return e, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}, nil
+}
+
+// estimateFee charges 110 vbytes plus 31 per output, 141 for a single
+// payment, at 20 sat/vbyte for the next block, 5 sat/vbyte otherwise, failing above the confirmed balance.
+func (f *fakeBackend) estimateFee(req *lnrpc.EstimateFeeRequest) (*lnrpc.EstimateFeeResponse, error) {
+	if err := f.record("estimatefee"); err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var total int64
+	for _, amt := range req.AddrToAmount {
+		total += amt
+	}
+	if total > f.balance.ConfirmedBalance {
+		return nil, errors.New("insufficient funds available to construct transaction")
+	}
+
+	rate := int64(5)
+	if req.TargetConf == 1 {
+		rate = 20
+	}
+	vbytes := 110 + 31*int64(len(req.AddrToAmount))
+	return &lnrpc.EstimateFeeResponse{FeeSat: vbytes * rate, FeerateSatPerByte: rate}, nil
+}
+
+// sendCoins lists the transaction as unconfirmed, sweeping the confirmed
+// balance less 705 sat of fee with SendAll.
+func (f *fakeBackend) sendCoins(req *lnrpc.SendCoinsRequest) (string, error) {
+	if err := f.record("sendcoins"); err != nil {
+		return "", err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastSendCoins = req
+
+	amount := req.Amount
+	if req.SendAll {
+		amount = f.balance.ConfirmedBalance - 705
+	}
+	txid := strings.Repeat("0c", 32)
+	f.transactions = &lnrpc.TransactionDetails{Transactions: append([]*lnrpc.Transaction{{
+		TxHash:        txid,
+		Amount:        -amount - 705,
+		TotalFees:     705,
+		TimeStamp:     time.Now().Unix(),
+		DestAddresses: []string{req.Addr},
+		Label:         req.Label,
+	}}, f.transactions.Transactions...)}
+	return txid, nil
+}
+
+// sendMany lists the batch as an unconfirmed transaction, with 5 sat/vbyte
+// of fee.
+func (f *fakeBackend) sendMany(req *lnrpc.SendManyRequest) (string, error) {
+	if err := f.record("sendmany"); err != nil {
+		return "", err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastSendMany = req
+
+	fee := (110 + 31*int64(len(req.AddrToAmount))) * 5
+	amount := fee
+	var addresses []string
+	for addr, amt := range req.AddrToAmount {
+		amount += amt
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+
+	txid := strings.Repeat("0d", 32)
+	f.transactions = &lnrpc.TransactionDetails{Transactions: append([]*lnrpc.Transaction{{
+		TxHash:        txid,
+		Amount:        -amount,
+		TotalFees:     fee,
+		TimeStamp:     time.Now().Unix(),
+		DestAddresses: addresses,
+		Label:         req.Label,
+	}}, f.transactions.Transactions...)}
+	return txid, nil
+}
+
+func (f *fakeBackend) listUnspent() (*lnrpc.ListUnspentResponse, error) {
+	if err := f.record("listunspent"); err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	resp := new(lnrpc.ListUnspentResponse)
+	for _, u := range f.utxos {
+		if f.findLease(u.Outpoint) < 0 {
+			resp.Utxos = append(resp.Utxos, u)
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeBackend) findLease(op *lnrpc.OutPoint) int {
+	for i, l := range f.leases {
+		if outPointString(l.Outpoint) == outPointString(op) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *fakeBackend) listLeases() (*walletrpc.ListLeasesResponse, error) {
+	if err := f.record("listleases"); err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return &walletrpc.ListLeasesResponse{LockedUtxos: append([]*walletrpc.UtxoLease(nil), f.leases...)}, nil
+}
+
+// leaseOutput leases the output, no longer listed as unspent as with lnd.
+func (f *fakeBackend) leaseOutput(req *walletrpc.LeaseOutputRequest) (uint64, error) {
+	if err := f.record("leaseoutput"); err != nil {
+		return 0, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastLease = req
+
+	if f.findLease(req.Outpoint) >= 0 {
+		return 0, errors.New("output already locked")
+	}
+	for _, u := range f.utxos {
+		if outPointString(u.Outpoint) == outPointString(req.Outpoint) {
+			expiration := uint64(time.Now().Unix()) + req.ExpirationSeconds
+			f.leases = append(f.leases, &walletrpc.UtxoLease{Id: req.Id, Outpoint: u.Outpoint, Expiration: expiration, Value: uint64(u.AmountSat)})
+			return expiration, nil
+		}
+	}
+	return 0, errors.New("unknown output")
+}
+
+// releaseOutput releases the lease, only under its id.
+func (f *fakeBackend) releaseOutput(req *walletrpc.ReleaseOutputRequest) error {
+	if err := f.record("releaseoutput"); err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	i := f.findLease(req.Outpoint)
+	if i < 0 {
+		return errors.New("unknown output")
+	}
+	if hex.EncodeToString(f.leases[i].Id) != hex.EncodeToString(req.Id) {
+		return errors.New("output unlock not allowed")
+	}
+	f.leases = append(f.leases[:i:i], f.leases[i+1:]...)
+	return nil
+}
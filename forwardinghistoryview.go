@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+type forwardingHistoryView struct {
+	viewBase
+	form *formEdit
+}
+
+type forwardingTimeRangeContainer struct {
+	Start string `displayname:"Start (YYYY-MM-DD)" length:"10" validate:"required" regex:"^[0-9]{4}-[0-9]{2}-[0-9]{2}$"`
+	End   string `displayname:"End (YYYY-MM-DD, opt)" length:"10" regex:"^[0-9]{4}-[0-9]{2}-[0-9]{2}$"`
+}
+
+func (c *forwardingTimeRangeContainer) validate() map[string]string {
+	errs := make(map[string]string)
+	start, err := time.ParseInLocation("2006-01-02", c.Start, time.Local)
+	if err != nil {
+		errs["Start"] = "invalid date"
+	}
+	if len(c.End) > 0 {
+		end, err := time.ParseInLocation("2006-01-02", c.End, time.Local)
+		switch {
+		case err != nil:
+			errs["End"] = "invalid date"
+		case end.Before(start):
+			errs["End"] = "before start"
+		}
+	}
+	return errs
+}
+
+func newforwardingHistoryView(physicalView string, fmtnormal string, fmtheader string, fmtselected string) *forwardingHistoryView {
+	cv := new(forwardingHistoryView)
+
+	cv.grid = makeNewDataGrid()
+	cv.mappedToPhysicalView = physicalView
+
+	cv.init(fmtnormal, fmtheader, fmtselected)
+
+	return cv
+}
+
+func (cv *forwardingHistoryView) init(fmtnormal string, fmtheader string, fmtselected string) {
+
+	cv.grid.fmtForeground = fmtnormal
+	cv.grid.fmtHeader = fmtheader
+	cv.grid.fmtSelected = fmtselected
+
+	cv.shortcuts = nil
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Time range", "T", 't', gocui.ModAlt, cv.setTimeRange, true, ""})
+
+	cv.grid.key = "forwards"
+	cv.grid.addColumn("Time", "Timestamp", dateRow)         //"Time", 18
+	cv.grid.addColumn("ChanIn", "GetChanIn", stringRow)     //"Chan in", 20
+	cv.grid.addColumn("AliasIn", "AliasIn", stringRow)      //"In", 0
+	cv.grid.addColumn("ChanOut", "GetChanOut", stringRow)   //"Chan out", 20
+	cv.grid.addColumn("AliasOut", "AliasOut", stringRow)    //"Out", 0
+	cv.grid.addAmountColumn("AmtIn", "AmtIn", satUnit)      //"Amt in", 13
+	cv.grid.addAmountColumn("AmtOut", "AmtOut", satUnit)    //"Amt out", 13
+	cv.grid.addAmountColumn("Fee", "Fee", satUnit)          //"Fee", 9
+	cv.grid.addAmountColumn("FeeMsat", "FeeMsat", msatUnit) //"Fee mSat", 12
+	cv.grid.addFiatColumn("FeeFiat", "FeeMsat", msatUnit)
+	cv.grid.initConfig()
+}
+
+func (cv *forwardingHistoryView) setTimeRange() {
+	start, end := status.getForwardingTimeRange()
+
+	cc := new(forwardingTimeRangeContainer)
+	cc.Start = time.Unix(start, 0).Format("2006-01-02")
+	if status.state().forwards.endTime > 0 {
+		cc.End = time.Unix(end-24*3600, 0).Format("2006-01-02")
+	}
+
+	cv.form = newFormEdit("forwardingRangeVal", "Forwarding history range", cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.getValue()
+		cv.form.close(context.gocui)
+		cv.form = nil
+		if valid {
+			s, _ := time.ParseInLocation("2006-01-02", cc.Start, time.Local)
+			var end int64
+			if e, err := time.ParseInLocation("2006-01-02", cc.End, time.Local); err == nil {
+				end = e.AddDate(0, 0, 1).Unix()
+			}
+			status.store.update(func(st *lncliState) {
+				st.forwards.startTime = s.Unix()
+				st.forwards.endTime = end
+			})
+			updateDataInBackground()
+		}
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
+func (cv *forwardingHistoryView) refreshView(g *gocui.Gui) {
+	v, err := g.View(cv.mappedToPhysicalView)
+	if err != nil {
+		log.Panicln(err.Error())
+		return
+	}
+	v.Clear()
+
+	x, y := v.Size()
+
+	forwards := status.state().forwards
+	cv.grid.items = forwards.events
+	cv.grid.info = forwards.getInfo()
+	cv.grid.setRenderSize(x, y)
+
+	for _, row := range cv.grid.getGridRows() {
+		fmt.Fprintln(v, row)
+	}
+
+	if cv.form != nil {
+		cv.form.layout(g)
+	}
+}
+
+func (cv *forwardingHistoryView) getShortCuts() []*keyHandle {
+	return cv.shortcuts
+}
+
+func (cv *forwardingHistoryView) getGrid() *dataGrid {
+	return cv.grid
+}
+
+func (cv *forwardingHistoryView) getPhysicalView() string {
+	return cv.mappedToPhysicalView
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+func TestForwardingHistoryViewRender(t *testing.T) {
+	setupTestContext(t)
+
+	activateView(forwardingHistoryViewt)
+	updateData()
+
+	waitFor(t, "forward aliases", func() bool {
+		for _, e := range status.state().forwards.events {
+			if len(e.AliasIn) == 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	lines := renderMainView(t)
+
+	assertLine(t, lines, "[Forwarding history]", "Fees day: 10.500 sat  week: 12.500 sat  month: 112.500 sat")
+	assertLine(t, lines, "Time", "Chan in", "In", "Chan out", "Out", "Fee")
+	assertLine(t, lines, "600055x6177617x1638", "acinq", "bitrefill", "100,010", "100,000")
+	assertLine(t, lines, "acinq", "0x0x123", "1,000,100")
+}
+
+func TestForwardingHistoryInfoUnits(t *testing.T) {
+	setupTestContext(t)
+	activateView(forwardingHistoryViewt)
+
+	if err := status.updateForwardingHistory(&context); err != nil {
+		t.Fatal(err)
+	}
+	context.unit = msatUnit
+	status.store.update(func(st *lncliState) { st.price = lncliPrice{"EUR", 50000} })
+
+	start, _ := status.getForwardingTimeRange()
+	lines := renderMainView(t)
+	assertLine(t, lines, "[Forwarding history]", "day: 10,500 msat (0.01 EUR)", time.Unix(start, 0).Format("2006-01-02")+" to ")
+}
+
+func TestForwardingHistoryIncremental(t *testing.T) {
+	fb := setupTestContext(t)
+
+	if err := status.updateForwardingHistory(&context); err != nil {
+		t.Fatal(err)
+	}
+	first := *fb.lastForwarding
+
+	fb.mutex.Lock()
+	fb.forwards = append(fb.forwards, &lnrpc.ForwardingEvent{Timestamp: uint64(time.Now().Unix()) - 60, ChanIdIn: 123, ChanIdOut: 124, FeeMsat: 1000})
+	fb.mutex.Unlock()
+
+	if err := status.updateForwardingHistory(&context); err != nil {
+		t.Fatal(err)
+	}
+
+	if fb.lastForwarding.StartTime != first.StartTime || fb.lastForwarding.IndexOffset != 3 {
+		t.Errorf("queried from %d offset %d, want %d offset 3", fb.lastForwarding.StartTime, fb.lastForwarding.IndexOffset, first.StartTime)
+	}
+	forwards := status.state().forwards
+	if len(forwards.events) != 4 || forwards.feeDay != 11500 || forwards.feeTotal != 113500 {
+		t.Errorf("got %d events, fees %+v", len(forwards.events), forwards)
+	}
+}
+
+func TestForwardingHistoryPaging(t *testing.T) {
+	fb := setupTestContext(t)
+
+	defer func(size uint32) { forwardingHistoryPageSize = size }(forwardingHistoryPageSize)
+	forwardingHistoryPageSize = 2
+
+	if err := status.updateForwardingHistory(&context); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(status.state().forwards.events) != 3 {
+		t.Errorf("got %d events", len(status.state().forwards.events))
+	}
+	if fb.callCount("fwdinghistory") != 2 {
+		t.Errorf("fwdinghistory called %d times", fb.callCount("fwdinghistory"))
+	}
+	if status.state().forwards.feeTotal != 112500 {
+		t.Errorf("total fee = %d", status.state().forwards.feeTotal)
+	}
+}
+
+func TestForwardingHistoryTimeRangeForm(t *testing.T) {
+	fb := setupTestContext(t)
+	activateView(forwardingHistoryViewt)
+
+	cv := context.views[forwardingHistoryViewt].(*forwardingHistoryView)
+
+	updateData()
+	days := time.Duration(fb.lastForwarding.EndTime-fb.lastForwarding.StartTime) * time.Second
+	if days != 30*24*time.Hour {
+		t.Errorf("default range = %v", days)
+	}
+
+	cv.setTimeRange()
+	typeInForm(t, cv.form, "Start", "2019-01-10")
+	typeInForm(t, cv.form, "End", "2019-01-01")
+	cv.form.submit(true)
+
+	if e := getTestEditor(t, cv.form, "End").getError(); e != "before start" {
+		t.Errorf("end error = %q", e)
+	}
+
+	typeInForm(t, cv.form, "End", "2019-01-31")
+	cv.form.submit(true)
+
+	if cv.form != nil {
+		t.Fatal("form not closed")
+	}
+	status.store.wait()
+
+	start := time.Date(2019, 1, 10, 0, 0, 0, 0, time.Local).Unix()
+	end := time.Date(2019, 2, 1, 0, 0, 0, 0, time.Local).Unix()
+	if fb.lastForwarding.StartTime != uint64(start) || fb.lastForwarding.EndTime != uint64(end) {
+		t.Errorf("unexpected request %+v", fb.lastForwarding)
+	}
+	if len(status.state().forwards.events) != 0 {
+		t.Errorf("got %d events", len(status.state().forwards.events))
+	}
+
+	forwards := status.state().forwards
+	if forwards.feeTotal != 0 || forwards.feeDay != 10500 || forwards.feeWeek != 12500 || forwards.feeMonth != 112500 {
+		t.Errorf("unexpected fees %+v", forwards)
+	}
+}
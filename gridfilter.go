@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gridFilter hides the rows of a dataGrid not matching all its conditions.
+// Conditions are separated by commas, e.g. "Active=false, Local>1000000",
+// each one compares a grid column, by its configuration key, to a value.
+type gridFilter struct {
+	expression string
+	conditions []*gridFilterCondition
+}
+
+type gridFilterCondition struct {
+	column   *dataGridColumn
+	operator string
+	value    string
+	// amount is the value of an amount condition in the column unit, parsed
+	// in the display unit when set.
+	amount int64
+}
+
+// Longest operators first so that "<=" isn't read as "<".
+var gridFilterOperators = []string{"!=", "!~", "<=", ">=", "=", "~", "<", ">"}
+
+var gridFilterDateFormats = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+func parseGridFilter(dg *dataGrid, expression string) (*gridFilter, error) {
+	f := new(gridFilter)
+	f.expression = strings.TrimSpace(expression)
+
+	if len(f.expression) == 0 {
+		return nil, nil
+	}
+
+	for _, cond := range strings.Split(f.expression, ",") {
+		c, err := parseGridFilterCondition(dg, strings.TrimSpace(cond))
+		if err != nil {
+			return nil, err
+		}
+		f.conditions = append(f.conditions, c)
+	}
+
+	return f, nil
+}
+
+func parseGridFilterCondition(dg *dataGrid, cond string) (*gridFilterCondition, error) {
+	for _, op := range gridFilterOperators {
+		i := strings.Index(cond, op)
+		if i <= 0 {
+			continue
+		}
+
+		key := strings.TrimSpace(cond[:i])
+		col, ok := dg.availableColumns[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown column '%s'", key)
+		}
+
+		c := &gridFilterCondition{column: col, operator: op, value: strings.TrimSpace(cond[i+len(op):])}
+
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("invalid condition '%s'", cond)
+}
+
+func (c *gridFilterCondition) validate() error {
+	if c.operator == "~" || c.operator == "!~" {
+		return nil
+	}
+
+	switch c.column.format {
+	case boolRow:
+		if c.operator != "=" && c.operator != "!=" {
+			return fmt.Errorf("operator '%s' not supported on boolean '%s'", c.operator, c.column.propertyName)
+		}
+		_, err := parseFilterBool(c.value)
+		return err
+	case intRow:
+		_, err := parseFilterInt(c.value)
+		return err
+	case amountRow:
+		a, err := parseAmount(c.value, c.column.unit, context.unit)
+		c.amount = a
+		return err
+	case fiatRow:
+		_, err := parseFilterFloat(c.value)
+		return err
+	case dateRow:
+		_, err := parseFilterDate(c.value)
+		return err
+	}
+
+	return nil
+}
+
+func (f *gridFilter) match(dg *dataGrid, rowData reflect.Value) bool {
+	for _, c := range f.conditions {
+		if !c.match(dg, rowData) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *gridFilterCondition) match(dg *dataGrid, rowData reflect.Value) bool {
+	val := dg.getRowValue(rowData, c.column.propertyName)
+
+	if !val.IsValid() {
+		return false
+	}
+
+	switch c.operator {
+	case "~":
+		return strings.Contains(strings.ToLower(dg.getCellString(c.column, val)), strings.ToLower(c.value))
+	case "!~":
+		return !strings.Contains(strings.ToLower(dg.getCellString(c.column, val)), strings.ToLower(c.value))
+	}
+
+	var cmp int
+
+	switch c.column.format {
+	case boolRow:
+		b, _ := parseFilterBool(c.value)
+		cmp = compareRowValues(val, reflect.ValueOf(b), boolRow)
+	case intRow:
+		i, _ := parseFilterInt(c.value)
+		cmp = compareInt64(getRowValueInt64(val), i)
+	case amountRow:
+		cmp = compareInt64(getRowValueInt64(val), c.amount)
+	case fiatRow:
+		fiat, ok := status.state().price.toFiat(getRowValueInt64(val), c.column.unit)
+		if !ok {
+			return false
+		}
+		f, _ := parseFilterFloat(c.value)
+		cmp = compareFloat64(fiat, f)
+	case dateRow:
+		d, _ := parseFilterDate(c.value)
+		cmp = compareInt64(getRowValueInt64(val), d)
+	default:
+		cmp = strings.Compare(strings.ToLower(dg.getCellString(c.column, val)), strings.ToLower(c.value))
+	}
+
+	switch c.operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case ">=":
+		return cmp >= 0
+	}
+
+	return false
+}
+
+func getRowValueInt64(val reflect.Value) int64 {
+	switch val.Kind() {
+	case reflect.Uint, reflect.Uint64, reflect.Uint16, reflect.Uint32:
+		return int64(val.Uint())
+	case reflect.Struct:
+		if t, ok := val.Interface().(time.Time); ok {
+			return t.Unix()
+		}
+		return 0
+	}
+	return val.Int()
+}
+
+func parseFilterBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "x":
+		return true, nil
+	case "":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func parseFilterInt(value string) (int64, error) {
+	return strconv.ParseInt(strings.Replace(value, "_", "", -1), 10, 64)
+}
+
+func parseFilterFloat(value string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(value, "_", "", -1), 64)
+}
+
+func parseFilterDate(value string) (int64, error) {
+	for _, f := range gridFilterDateFormats {
+		if t, err := time.ParseInLocation(f, value, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i, nil
+	}
+	return 0, fmt.Errorf("invalid date '%s', expected YYYY-MM-DD [hh:mm[:ss]]", value)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGridFilter(t *testing.T) {
+	dg := newTestGrid(testGridItems(4))
+	dg.setRenderSize(80, 8)
+
+	tests := []struct {
+		filter string
+		names  string
+	}{
+		{"Flag=true", "itema,itemc"},
+		{"Flag!=x", "itemb,itemd"},
+		{"Amount>2000000", "itemc,itemd"},
+		{"Amount<=2_000_000", "itema,itemb"},
+		{"Name~EMB", "itemb"},
+		{"Upper!~B", "itema,itemc,itemd"},
+		{"Name=ITEMC", "itemc"},
+		{"Created>=2019-01-03", "itemc,itemd"},
+		{"Created<2019-01-02 12:00", "itema"},
+		{"Tags~y", "itema,itemb,itemc,itemd"},
+		{"Flag=false, Amount>=4000000", "itemd"},
+		{" ", "itema,itemb,itemc,itemd"},
+	}
+
+	for _, tt := range tests {
+		if err := dg.setFilter(tt.filter); err != nil {
+			t.Errorf("%q: %v", tt.filter, err)
+			continue
+		}
+		if names := strings.Join(gridRowNames(dg), ","); names != tt.names {
+			t.Errorf("%q: rows = %s, want %s", tt.filter, names, tt.names)
+		}
+	}
+}
+
+func TestGridFilterErrors(t *testing.T) {
+	dg := newTestGrid(testGridItems(2))
+
+	for _, filter := range []string{"Unknown=1", "Amount>many", "Flag>true", "Created<yesterday", "Name", "=abc"} {
+		if err := dg.setFilter(filter); err == nil {
+			t.Errorf("%q: no error", filter)
+		}
+	}
+
+	if err := dg.setFilter("Flag=true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dg.setFilter("Amount>"); err == nil {
+		t.Error("no error for a missing int value")
+	}
+	if dg.getFilterExpression() != "Flag=true" {
+		t.Errorf("invalid filter replaced the previous one, got %q", dg.getFilterExpression())
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jroimartin/gocui"
+)
+
+// setMarkable lets the rows of the grid be marked, for the view actions to
+// apply to several items at once. The items are identified by their
+// propertyName value, the marks being kept across refreshes. It must be called
+// before getShortCuts.
+func (dg *dataGrid) setMarkable(propertyName string) {
+	dg.markProperty = propertyName
+	dg.marked = make(map[string]bool)
+	dg.markAnchor = -1
+}
+
+func (dg *dataGrid) isMarkable() bool {
+	return len(dg.markProperty) > 0
+}
+
+func (dg *dataGrid) getMarkShortCuts(view string) []*keyHandle {
+	var shortcuts []*keyHandle
+	shortcuts = append(shortcuts, &keyHandle{"Mark", "Space", gocui.KeySpace, gocui.ModNone, dg.toggleMark, false, view})
+	shortcuts = append(shortcuts, &keyHandle{"Mark all", "^A", gocui.KeyCtrlA, gocui.ModNone, dg.toggleMarkAll, false, view})
+	shortcuts = append(shortcuts, &keyHandle{"Mark range", "^X", gocui.KeyCtrlX, gocui.ModNone, dg.markRange, false, view})
+	return shortcuts
+}
+
+func (dg *dataGrid) getMarkKey(rowData reflect.Value) string {
+	return fmt.Sprint(dg.getRowValue(rowData, dg.markProperty).Interface())
+}
+
+func (dg *dataGrid) isMarked(rowData reflect.Value) bool {
+	return dg.isMarkable() && dg.marked[dg.getMarkKey(rowData)]
+}
+
+func (dg *dataGrid) setMarked(rowData reflect.Value, marked bool) {
+	if marked {
+		dg.marked[dg.getMarkKey(rowData)] = true
+	} else {
+		delete(dg.marked, dg.getMarkKey(rowData))
+	}
+}
+
+// toggleMark marks or unmarks the selected row and moves to the next one.
+func (dg *dataGrid) toggleMark() {
+	if dg.form != nil {
+		return
+	}
+
+	item := dg.getSelectedItem()
+	if !item.IsValid() || item.IsNil() {
+		return
+	}
+
+	dg.setMarked(item.Elem(), !dg.isMarked(item.Elem()))
+	dg.markAnchor = dg.selectedIndex
+	dg.moveSelectionDown()
+}
+
+// toggleMarkAll marks all the displayed rows, unmarks them when they're all
+// marked.
+func (dg *dataGrid) toggleMarkAll() {
+	if dg.form != nil || dg.items == nil {
+		return
+	}
+
+	dg.refreshRows()
+	items := reflect.ValueOf(dg.items)
+
+	all := true
+	for _, i := range dg.rows {
+		if !dg.isMarked(items.Index(i).Elem()) {
+			all = false
+			break
+		}
+	}
+
+	for _, i := range dg.rows {
+		dg.setMarked(items.Index(i).Elem(), !all)
+	}
+}
+
+// markRange marks the rows from the last one marked or unmarked to the
+// selected one.
+func (dg *dataGrid) markRange() {
+	if dg.form != nil || dg.items == nil {
+		return
+	}
+
+	dg.refreshRows()
+	if len(dg.rows) == 0 {
+		return
+	}
+
+	from, to := dg.markAnchor, dg.selectedIndex
+	if from < 0 || from >= len(dg.rows) {
+		from = to
+	}
+	if from > to {
+		from, to = to, from
+	}
+
+	items := reflect.ValueOf(dg.items)
+	for i := from; i <= to; i++ {
+		dg.setMarked(items.Index(dg.rows[i]).Elem(), true)
+	}
+	dg.markAnchor = dg.selectedIndex
+}
+
+func (dg *dataGrid) clearMarks() {
+	dg.marked = make(map[string]bool)
+	dg.markAnchor = -1
+}
+
+// pruneMarks drops the marks of the items no longer listed.
+func (dg *dataGrid) pruneMarks(items reflect.Value) {
+	if len(dg.marked) == 0 {
+		return
+	}
+
+	listed := make(map[string]bool)
+	for i := 0; i < items.Len(); i++ {
+		if item := items.Index(i); !item.IsNil() {
+			listed[dg.getMarkKey(item.Elem())] = true
+		}
+	}
+	for key := range dg.marked {
+		if !listed[key] {
+			delete(dg.marked, key)
+		}
+	}
+}
+
+// markedCount counts the marked items passing the filter, the ones the view
+// actions apply to.
+func (dg *dataGrid) markedCount() int {
+	if len(dg.marked) == 0 {
+		return 0
+	}
+
+	items := reflect.ValueOf(dg.items)
+
+	count := 0
+	for _, i := range dg.rows {
+		if dg.isMarked(items.Index(i).Elem()) {
+			count++
+		}
+	}
+	return count
+}
+
+// getMarkedItems returns the marked items passing the filter, in display
+// order.
+func (dg *dataGrid) getMarkedItems() []reflect.Value {
+	if !dg.isMarkable() || dg.items == nil {
+		return nil
+	}
+
+	dg.refreshRows()
+	items := reflect.ValueOf(dg.items)
+
+	var marked []reflect.Value
+	for _, i := range dg.rows {
+		if item := items.Index(i); dg.isMarked(item.Elem()) {
+			marked = append(marked, item)
+		}
+	}
+	return marked
+}
+
+// getSelectedItems returns the marked items, the selected one when none is
+// marked.
+func (dg *dataGrid) getSelectedItems() []reflect.Value {
+	if marked := dg.getMarkedItems(); len(marked) > 0 {
+		return marked
+	}
+	if item := dg.getSelectedItem(); item.IsValid() && !item.IsNil() {
+		return []reflect.Value{item}
+	}
+	return nil
+}
+
+// selectionAction returns a view action applying to the marked items, or the
+// selected one when none is marked, skipped when there's none.
+func (dg *dataGrid) selectionAction(action func(items []reflect.Value)) func() {
+	return func() {
+		if items := dg.getSelectedItems(); len(items) > 0 {
+			action(items)
+		}
+	}
+}
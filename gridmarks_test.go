@@ -0,0 +1,153 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+)
+
+func markedNames(dg *dataGrid) []string {
+	var names []string
+	for _, item := range dg.getMarkedItems() {
+		names = append(names, item.Interface().(*testGridItem).Name)
+	}
+	return names
+}
+
+func TestGridMarks(t *testing.T) {
+	setupTestContext(t)
+
+	dg := newTestGrid(testGridItems(5))
+	dg.setMarkable("Name")
+	dg.setRenderSize(80, 6)
+
+	if dg.columns[0].displayWidth != 80-8-12-2-18-20-1 {
+		t.Errorf("auto column width = %d, want the marker column reserved", dg.columns[0].displayWidth)
+	}
+
+	dg.toggleMark()
+	dg.moveSelectionDown()
+	dg.toggleMark()
+	if got := strings.Join(markedNames(dg), ","); got != "itema,itemc" {
+		t.Errorf("marked %s", got)
+	}
+	if dg.selectedIndex != 3 {
+		t.Errorf("selected index = %d, want moved down after each mark", dg.selectedIndex)
+	}
+
+	rows := dg.getGridRows()
+	if !strings.Contains(rows[0], "[Test]  Marked: 2") {
+		t.Errorf("header row = %q", rows[0])
+	}
+	if !strings.HasPrefix(rows[1], " Name") {
+		t.Errorf("column header row = %q", rows[1])
+	}
+	if !strings.HasPrefix(rows[2], "*itema") || !strings.HasPrefix(rows[3], " itemb") {
+		t.Errorf("rows %q, %q", rows[2], rows[3])
+	}
+
+	dg.moveSelectionDown()
+	dg.markRange()
+	if got := strings.Join(markedNames(dg), ","); got != "itema,itemc,itemd,iteme" {
+		t.Errorf("range marked %s", got)
+	}
+
+	dg.selectedIndex = 2
+	dg.toggleMark()
+	if got := strings.Join(markedNames(dg), ","); got != "itema,itemd,iteme" {
+		t.Errorf("unmarked %s", got)
+	}
+
+	dg.toggleMarkAll()
+	if n := len(dg.getMarkedItems()); n != 5 {
+		t.Errorf("%d marked, want all", n)
+	}
+	dg.toggleMarkAll()
+	if n := len(dg.getMarkedItems()); n != 0 {
+		t.Errorf("%d marked, want none", n)
+	}
+	if rows := dg.getGridRows(); strings.Contains(rows[0], "Marked") {
+		t.Errorf("header row = %q", rows[0])
+	}
+}
+
+func TestGridMarksFilterAndRefresh(t *testing.T) {
+	setupTestContext(t)
+
+	items := testGridItems(4)
+	dg := newTestGrid(items)
+	dg.setMarkable("Name")
+
+	dg.toggleMarkAll()
+	if err := dg.setFilter("Flag=true"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(markedNames(dg), ","); got != "itema,itemc" {
+		t.Errorf("marked %s, want the ones passing the filter", got)
+	}
+	if rows := dg.getGridRows(); !strings.Contains(rows[0], "Marked: 2") {
+		t.Errorf("header row = %q", rows[0])
+	}
+
+	dg.setFilter("")
+	dg.items = []*testGridItem{items[3], {Name: "itema"}, items[2]}
+	if got := strings.Join(markedNames(dg), ","); got != "itemd,itema,itemc" {
+		t.Errorf("marked %s, want kept across refreshes", got)
+	}
+	dg.items = items[2:]
+	dg.refreshRows()
+	if len(dg.marked) != 2 {
+		t.Errorf("%d marks, want the removed items unmarked", len(dg.marked))
+	}
+}
+
+func TestGridSelectionAction(t *testing.T) {
+	setupTestContext(t)
+
+	dg := newTestGrid(testGridItems(3))
+	dg.setMarkable("Name")
+
+	var got []string
+	action := dg.selectionAction(func(items []reflect.Value) {
+		got = nil
+		for _, item := range items {
+			got = append(got, item.Interface().(*testGridItem).Name)
+		}
+	})
+
+	dg.selectedIndex = 1
+	action()
+	if strings.Join(got, ",") != "itemb" {
+		t.Errorf("action on %v, want the selected item", got)
+	}
+
+	dg.markRange()
+	dg.selectedIndex = 2
+	dg.markRange()
+	action()
+	if strings.Join(got, ",") != "itemb,itemc" {
+		t.Errorf("action on %v, want the marked items", got)
+	}
+
+	got = nil
+	dg.items = []*testGridItem{}
+	action()
+	if got != nil {
+		t.Errorf("action on %v, want skipped without item", got)
+	}
+}
+
+func TestGridKeysBoundToView(t *testing.T) {
+	setupTestContext(t)
+
+	dg := newTestGrid(testGridItems(1))
+	dg.setMarkable("Name")
+
+	for _, kh := range dg.getShortCuts("main") {
+		if kh.mod == gocui.ModNone && kh.view != "main" {
+			t.Errorf("%q bound to %q", kh.header, kh.view)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	gocontext "context"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/lightningnetwork/lnd/macaroons"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	macaroon "gopkg.in/macaroon.v2"
+)
+
+const grpcCallTimeout = 30 * time.Second
+
+// grpcMaxRecvMsgSize raises grpc's 4 MB limit on the responses as lncli
+// does, the payment, invoice and forwarding lists of large nodes going over.
+const grpcMaxRecvMsgSize = 200 * 1024 * 1024
+
+// paymentTimeout is lnd's default payment timeout, the payment call given
+// grpcCallTimeout more to return its outcome.
+const paymentTimeout = 60 * time.Second
+
+// lndGrpcBackend talks to lnd directly through its gRPC interface, using the
+// same TLS certificate and macaroon options as lncli.
+type lndGrpcBackend struct {
+	conn   *grpc.ClientConn
+	client lnrpc.LightningClient
+	wallet walletrpc.WalletKitClient
+}
+
+func newlndGrpcBackend() (*lndGrpcBackend, error) {
+	creds, err := credentials.NewClientTLSFromFile(getTLSCertPathOrDefault(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(grpcMaxRecvMsgSize)),
+	}
+
+	if !getNoMacaroons() {
+		macOpt, err := getMacaroonDialOption()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, macOpt)
+	}
+
+	conn, err := grpc.Dial(getRPCServerOrDefault(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	b := new(lndGrpcBackend)
+	b.conn = conn
+	b.client = lnrpc.NewLightningClient(conn)
+	b.wallet = walletrpc.NewWalletKitClient(conn)
+	return b, nil
+}
+
+func getMacaroonDialOption() (grpc.DialOption, error) {
+	macBytes, err := ioutil.ReadFile(getMacaroonPathOrDefault())
+	if err != nil {
+		return nil, err
+	}
+
+	mac := &macaroon.Macaroon{}
+	if err = mac.UnmarshalBinary(macBytes); err != nil {
+		return nil, err
+	}
+
+	var constraints []macaroons.Constraint
+
+	if getMacaroonTimeOut() > 0 {
+		constraints = append(constraints, macaroons.TimeoutConstraint(int64(getMacaroonTimeOut())))
+	}
+
+	if len(getMacaroonIP()) > 0 {
+		constraints = append(constraints, macaroons.IPLockConstraint(getMacaroonIP()))
+	}
+
+	constrainedMac, err := macaroons.AddConstraints(mac, constraints...)
+	if err != nil {
+		return nil, err
+	}
+
+	return grpc.WithPerRPCCredentials(macaroons.NewMacaroonCredential(constrainedMac)), nil
+}
+
+func (b *lndGrpcBackend) callContext() (gocontext.Context, gocontext.CancelFunc) {
+	return gocontext.WithTimeout(gocontext.Background(), grpcCallTimeout)
+}
+
+func (b *lndGrpcBackend) getInfo() (*lnrpc.GetInfoResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+}
+
+func (b *lndGrpcBackend) walletBalance() (*lnrpc.WalletBalanceResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.WalletBalance(ctx, &lnrpc.WalletBalanceRequest{})
+}
+
+func (b *lndGrpcBackend) listChannels() (*lnrpc.ListChannelsResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+}
+
+func (b *lndGrpcBackend) pendingChannels() (*lnrpc.PendingChannelsResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.PendingChannels(ctx, &lnrpc.PendingChannelsRequest{})
+}
+
+func (b *lndGrpcBackend) listPeers() (*lnrpc.ListPeersResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.ListPeers(ctx, &lnrpc.ListPeersRequest{})
+}
+
+func (b *lndGrpcBackend) listInvoices(req *lnrpc.ListInvoiceRequest) (*lnrpc.ListInvoiceResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.ListInvoices(ctx, req)
+}
+
+func (b *lndGrpcBackend) listPayments(req *lnrpc.ListPaymentsRequest) (*lnrpc.ListPaymentsResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.ListPayments(ctx, req)
+}
+
+func (b *lndGrpcBackend) listChainTxns() (*lnrpc.TransactionDetails, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.GetTransactions(ctx, &lnrpc.GetTransactionsRequest{})
+}
+
+func (b *lndGrpcBackend) getChanInfo(chanID uint64) (*lnrpc.ChannelEdge, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.GetChanInfo(ctx, &lnrpc.ChanInfoRequest{ChanId: chanID})
+}
+
+func (b *lndGrpcBackend) feeReport() (*lnrpc.FeeReportResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.FeeReport(ctx, &lnrpc.FeeReportRequest{})
+}
+
+func (b *lndGrpcBackend) updateChannelPolicy(req *lnrpc.PolicyUpdateRequest) error {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	resp, err := b.client.UpdateChannelPolicy(ctx, req)
+	if err != nil {
+		return err
+	}
+	return policyUpdateError(resp)
+}
+
+func (b *lndGrpcBackend) forwardingHistory(req *lnrpc.ForwardingHistoryRequest) (*lnrpc.ForwardingHistoryResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.ForwardingHistory(ctx, req)
+}
+
+func (b *lndGrpcBackend) getNodeInfo(pubkey string) (*lnrpc.NodeInfo, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{PubKey: pubkey})
+}
+
+func (b *lndGrpcBackend) openChannel(req *lnrpc.OpenChannelRequest, connect string, block bool) (string, error) {
+
+	if len(connect) > 0 {
+		// lncli ignores connection errors here as well, the peer may already
+		// be connected and the open request reports any real problem.
+		if err := b.connectPeer(hex.EncodeToString(req.NodePubkey), connect); err != nil {
+			logError(err.Error())
+		}
+	}
+
+	if !block {
+		ctx, cancel := b.callContext()
+		defer cancel()
+		cp, err := b.client.OpenChannelSync(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		return channelPointTxid(cp), nil
+	}
+
+	stream, err := b.client.OpenChannel(gocontext.Background(), req)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		if u, ok := update.Update.(*lnrpc.OpenStatusUpdate_ChanOpen); ok {
+			return channelPointTxid(u.ChanOpen.ChannelPoint), nil
+		}
+	}
+}
+
+func (b *lndGrpcBackend) closeChannel(channelPoint string, force bool, targetConf int32, satPerByte int64) (string, error) {
+	cp, err := parseChannelPoint(channelPoint)
+	if err != nil {
+		return "", err
+	}
+
+	req := &lnrpc.CloseChannelRequest{
+		ChannelPoint: cp,
+		Force:        force,
+		TargetConf:   targetConf,
+		SatPerByte:   satPerByte,
+	}
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	defer cancel()
+
+	stream, err := b.client.CloseChannel(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		switch u := update.Update.(type) {
+		case *lnrpc.CloseStatusUpdate_ClosePending:
+			return txidString(u.ClosePending.Txid), nil
+		case *lnrpc.CloseStatusUpdate_ChanClose:
+			return txidString(u.ChanClose.ClosingTxid), nil
+		}
+	}
+}
+
+func (b *lndGrpcBackend) connectPeer(pubkey string, host string) error {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	_, err := b.client.ConnectPeer(ctx, &lnrpc.ConnectPeerRequest{Addr: &lnrpc.LightningAddress{Pubkey: pubkey, Host: host}})
+	return err
+}
+
+func (b *lndGrpcBackend) disconnectPeer(pubkey string) error {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	_, err := b.client.DisconnectPeer(ctx, &lnrpc.DisconnectPeerRequest{PubKey: pubkey})
+	return err
+}
+
+// addInvoice adds the invoice, lnd's rpc having no receipt field.
+func (b *lndGrpcBackend) addInvoice(invoice *lnrpc.Invoice, receipt string) (*lnrpc.AddInvoiceResponse, error) {
+	if len(receipt) > 0 {
+		return nil, errors.New("receipt not supported by the grpc backend")
+	}
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.AddInvoice(ctx, invoice)
+}
+
+func (b *lndGrpcBackend) decodePayReq(payReq string) (*lnrpc.PayReq, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.DecodePayReq(ctx, &lnrpc.PayReqString{PayReq: payReq})
+}
+
+func (b *lndGrpcBackend) payInvoice(req *lnrpc.SendRequest) (*lnrpc.SendResponse, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), paymentTimeout+grpcCallTimeout)
+	defer cancel()
+	return b.client.SendPaymentSync(ctx, req)
+}
+
+func (b *lndGrpcBackend) queryRoutes(req *lnrpc.QueryRoutesRequest) (*lnrpc.QueryRoutesResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.QueryRoutes(ctx, req)
+}
+
+func (b *lndGrpcBackend) newAddress(addressType string) (string, error) {
+	req := &lnrpc.NewAddressRequest{}
+
+	switch addressType {
+	case "p2wkh":
+		req.Type = lnrpc.NewAddressRequest_WITNESS_PUBKEY_HASH
+	case "np2wkh":
+		req.Type = lnrpc.NewAddressRequest_NESTED_PUBKEY_HASH
+	default:
+		return "", errors.New("address type must be p2wkh or np2wkh")
+	}
+
+	ctx, cancel := b.callContext()
+	defer cancel()
+
+	na, err := b.client.NewAddress(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return na.Address, nil
+}
+
+func (b *lndGrpcBackend) estimateFee(req *lnrpc.EstimateFeeRequest) (*lnrpc.EstimateFeeResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.EstimateFee(ctx, req)
+}
+
+func (b *lndGrpcBackend) sendCoins(req *lnrpc.SendCoinsRequest) (string, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	r, err := b.client.SendCoins(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return r.Txid, nil
+}
+
+func (b *lndGrpcBackend) sendMany(req *lnrpc.SendManyRequest) (string, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	r, err := b.client.SendMany(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return r.Txid, nil
+}
+
+func (b *lndGrpcBackend) listUnspent() (*lnrpc.ListUnspentResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.client.ListUnspent(ctx, &lnrpc.ListUnspentRequest{MinConfs: 0, MaxConfs: math.MaxInt32})
+}
+
+func (b *lndGrpcBackend) listLeases() (*walletrpc.ListLeasesResponse, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	return b.wallet.ListLeases(ctx, &walletrpc.ListLeasesRequest{})
+}
+
+func (b *lndGrpcBackend) leaseOutput(req *walletrpc.LeaseOutputRequest) (uint64, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	r, err := b.wallet.LeaseOutput(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	return r.Expiration, nil
+}
+
+func (b *lndGrpcBackend) releaseOutput(req *walletrpc.ReleaseOutputRequest) error {
+	ctx, cancel := b.callContext()
+	defer cancel()
+	_, err := b.wallet.ReleaseOutput(ctx, req)
+	return err
+}
+
+func (b *lndGrpcBackend) subscribeInvoices(ctx gocontext.Context) (func() (*lnrpc.Invoice, error), error) {
+	stream, err := b.client.SubscribeInvoices(ctx, &lnrpc.InvoiceSubscription{})
+	if err != nil {
+		return nil, err
+	}
+	return stream.Recv, nil
+}
+
+func (b *lndGrpcBackend) subscribeChannelEvents(ctx gocontext.Context) (func() (*lnrpc.ChannelEventUpdate, error), error) {
+	stream, err := b.client.SubscribeChannelEvents(ctx, &lnrpc.ChannelEventSubscription{})
+	if err != nil {
+		return nil, err
+	}
+	return stream.Recv, nil
+}
+
+func (b *lndGrpcBackend) subscribeTransactions(ctx gocontext.Context) (func() (*lnrpc.Transaction, error), error) {
+	stream, err := b.client.SubscribeTransactions(ctx, &lnrpc.GetTransactionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return stream.Recv, nil
+}
+
+func (b *lndGrpcBackend) subscribePeerEvents(ctx gocontext.Context) (func() (*lnrpc.PeerEvent, error), error) {
+	stream, err := b.client.SubscribePeerEvents(ctx, &lnrpc.PeerEventSubscription{})
+	if err != nil {
+		return nil, err
+	}
+	return stream.Recv, nil
+}
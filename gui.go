@@ -8,8 +8,6 @@ import (
 	"strings"
 
 	"github.com/jroimartin/gocui"
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
 )
 
 type viewType int
@@ -23,6 +21,11 @@ const (
 	pendingChannelListViewt viewType = 5
 	paymentListViewt        viewType = 6
 	invoiceListViewt        viewType = 7
+	forwardingHistoryViewt  viewType = 8
+	routeListViewt          viewType = 9
+	batchListViewt          viewType = 10
+	utxoListViewt           viewType = 11
+	closeListViewt          viewType = 12
 	channelDetailsViewt     viewType = 102
 	menuViewt               viewType = 1000
 	globalViewt             viewType = 1001
@@ -66,6 +69,9 @@ func initViews() {
 
 	g.SetManagerFunc(layout)
 
+	context.scheduler.start()
+	context.streams = startStreams(&context)
+
 	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
 		log.Panicln(err)
 	}
@@ -110,9 +116,22 @@ func registerKeyHandler(g *gocui.Gui, handle *keyHandle) error {
 }
 
 func quit(g *gocui.Gui, v *gocui.View) error {
+	if context.streams != nil {
+		context.streams.stop()
+	}
+	manageError(status.nodes.save())
 	return gocui.ErrQuit
 }
 
+// runInGui has fn run by the gocui goroutine, the only one allowed to touch the
+// views, forms and key bindings.
+func runInGui(fn func()) {
+	context.gocui.Update(func(g *gocui.Gui) error {
+		fn()
+		return nil
+	})
+}
+
 func refreshView() {
 	context.gocui.Update(func(g *gocui.Gui) error {
 		if getShowHeader() {
@@ -133,10 +152,17 @@ func refreshWalletBalanceView(g *gocui.Gui) {
 		return
 	}
 	v.Clear()
-	p := message.NewPrinter(language.English)
-	p.Fprintf(v, context.theme.normal+"Total       "+context.theme.highlight+"%14d"+context.theme.normal+" mSat\n", status.walletBalance.TotalBalance)
-	p.Fprintf(v, context.theme.normal+"Confirmed   "+context.theme.highlight+"%14d"+context.theme.normal+" mSat\n", status.walletBalance.ConfirmedBalance)
-	p.Fprintf(v, context.theme.normal+"Unconfirmed "+context.theme.highlight+"%14d"+context.theme.normal+" mSat", status.walletBalance.UnconfirmedBalance)
+	balance := status.state().walletBalance
+	unit := " " + context.unit.String()
+	fmt.Fprintf(v, context.theme.normal+"Total       "+context.theme.highlight+"%14s"+context.theme.normal+"%s\n", formatAmount(balance.TotalBalance, satUnit, context.unit), unit)
+	fmt.Fprintf(v, context.theme.normal+"Confirmed   "+context.theme.highlight+"%14s"+context.theme.normal+"%s\n", formatAmount(balance.ConfirmedBalance, satUnit, context.unit), unit)
+	fmt.Fprintf(v, context.theme.normal+"Unconfirmed "+context.theme.highlight+"%14s"+context.theme.normal+"%s", formatAmount(balance.UnconfirmedBalance, satUnit, context.unit), unit)
+
+	price := status.state().price
+	if fiat := price.formatFiat(balance.TotalBalance, satUnit); len(fiat) > 0 {
+		fmt.Fprintf(v, "\n"+context.theme.normal+"Total       "+context.theme.highlight+"%14s"+context.theme.normal+" %s\n", fiat, price.currency)
+		fmt.Fprintf(v, context.theme.normal+"BTC price   "+context.theme.highlight+"%14s"+context.theme.normal+" %s", context.printer.Sprintf("%.2f", price.rate), price.currency)
+	}
 }
 
 func refreshNodeInfoView(g *gocui.Gui) {
@@ -146,26 +172,34 @@ func refreshNodeInfoView(g *gocui.Gui) {
 		return
 	}
 	v.Clear()
-	fmt.Fprintf(v, context.theme.normal+"Alias    "+context.theme.highlight+"%s\n", status.localNodeInfo.Alias)
-	fmt.Fprintf(v, context.theme.normal+"Pubkey   "+context.theme.highlight+"%s\n", status.localNodeInfo.IdentityPubkey)
-	fmt.Fprintf(v, context.theme.normal+"Version  "+context.theme.highlight+"%s\n", status.localNodeInfo.Version)
-	fmt.Fprintf(v, context.theme.normal+"Chains   "+context.theme.highlight+"%s", strings.Join(status.localNodeInfo.Chains, ","))
-	if status.localNodeInfo.Testnet {
+	info := status.state().localNodeInfo
+	fmt.Fprintf(v, context.theme.normal+"Alias    "+context.theme.highlight+"%s\n", info.Alias)
+	fmt.Fprintf(v, context.theme.normal+"Pubkey   "+context.theme.highlight+"%s\n", info.IdentityPubkey)
+	fmt.Fprintf(v, context.theme.normal+"Version  "+context.theme.highlight+"%s\n", info.Version)
+	fmt.Fprintf(v, context.theme.normal+"Chains   "+context.theme.highlight+"%s", strings.Join(info.Chains, ","))
+	if info.Testnet {
 		fmt.Fprint(v, " testnet")
 	} else {
 		fmt.Fprint(v, " mainnet")
 	}
-	if status.localNodeInfo.SyncedToChain {
+	if info.SyncedToChain {
 		fmt.Fprintln(v, " synced")
 	} else {
 		fmt.Fprintln(v, " not synced")
 	}
-	fmt.Fprintf(v, context.theme.normal+"Peers    "+context.theme.highlight+"%d\n", status.localNodeInfo.NumPeers)
-	fmt.Fprintf(v, context.theme.normal+"Channels active "+context.theme.highlight+"%d "+context.theme.normal+"inactive "+context.theme.highlight+"%d "+context.theme.normal+"pending "+context.theme.highlight+"%d", status.localNodeInfo.NumActiveChannels, status.localNodeInfo.NumInactiveChannels, status.localNodeInfo.NumPendingChannels)
+	fmt.Fprintf(v, context.theme.normal+"Peers    "+context.theme.highlight+"%d\n", info.NumPeers)
+	fmt.Fprintf(v, context.theme.normal+"Channels active "+context.theme.highlight+"%d "+context.theme.normal+"inactive "+context.theme.highlight+"%d "+context.theme.normal+"pending "+context.theme.highlight+"%d", info.NumActiveChannels, info.NumInactiveChannels, info.NumPendingChannels)
 }
 
 func refreshMainView(g *gocui.Gui) {
-	context.views[context.activeMainView].refreshView(g)
+	view := context.views[context.activeMainView]
+	if grid := view.getGrid(); grid != nil {
+		grid.refreshStatus = context.scheduler.getStatus(context.activeMainView)
+	}
+	view.refreshView(g)
+	if grid := view.getGrid(); grid != nil && grid.form != nil {
+		grid.form.layout(g)
+	}
 }
 
 func getModifierString(mod gocui.Modifier) string {
@@ -259,6 +293,7 @@ func layout(g *gocui.Gui) error {
 		v.BgColor = context.theme.background
 	}
 	refreshMainView(g)
+	focusMainView(g)
 
 	if v, err := g.SetView("menu", -1, maxY-2, maxX, maxY); err != nil {
 		if err != gocui.ErrUnknownView {
@@ -272,3 +307,14 @@ func layout(g *gocui.Gui) error {
 
 	return nil
 }
+
+// focusMainView gives the focus back to the main view once the forms are
+// closed, for its bound keys to fire.
+func focusMainView(g *gocui.Gui) {
+	if cv := g.CurrentView(); cv != nil {
+		if _, err := g.View(cv.Name()); err == nil {
+			return
+		}
+	}
+	g.SetCurrentView("main")
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"reflect"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/jroimartin/gocui"
 )
@@ -49,6 +51,8 @@ type baseEditI interface {
 	getContentWidth() int
 	setContentWidth(int)
 	setActive(bool)
+	getError() string
+	setError(string)
 	getValue() interface{}
 	delete(*gocui.Gui)
 }
@@ -63,6 +67,7 @@ type baseEdit struct {
 	active       bool
 	labelWidth   int
 	contentWidth int
+	errorMsg     string
 	keyHandles   []*editKeyHandle
 }
 
@@ -140,6 +145,14 @@ func (b *baseEdit) setActive(a bool) {
 	b.active = a
 }
 
+func (b *baseEdit) getError() string {
+	return b.errorMsg
+}
+
+func (b *baseEdit) setError(msg string) {
+	b.errorMsg = msg
+}
+
 func (b *baseEdit) setShowLabel(a bool) {
 	b.showLabel = a
 }
@@ -177,12 +190,38 @@ func (b *baseEdit) baseLayout(g *gocui.Gui) *gocui.View {
 		b.drawLabel(v)
 	}
 
+	b.layoutError(g)
+
 	return v
 }
 
+// layoutError shows the validation error on the right of the editor.
+func (b *baseEdit) layoutError(g *gocui.Gui) {
+	name := b.name + "err"
+
+	if len(b.errorMsg) == 0 {
+		g.DeleteView(name)
+		return
+	}
+
+	x := b.getX() + b.getLabelWidth() + b.getContentWidth() + 2
+
+	v, _ := g.SetView(name, x, b.getY(), x+len(b.errorMsg)+3, b.getY()+2)
+
+	v.Clear()
+
+	v.BgColor = context.theme.background
+	v.Editable = false
+	v.Frame = false
+
+	fmt.Fprint(v, context.theme.error+" "+b.errorMsg)
+}
+
 func (b *baseEdit) drawLabel(v *gocui.View) {
 	v.MoveCursor(0, 0, false)
-	if b.active {
+	if len(b.errorMsg) > 0 {
+		fmt.Fprint(v, context.theme.error)
+	} else if b.active {
 		fmt.Fprint(v, context.theme.highlight)
 	} else {
 		fmt.Fprint(v, context.theme.normal)
@@ -192,6 +231,7 @@ func (b *baseEdit) drawLabel(v *gocui.View) {
 
 func (b *baseEdit) delete(g *gocui.Gui) {
 	b.unregisterKeyHandlers(g)
+	g.DeleteView(b.name + "err")
 	g.DeleteView(b.name)
 }
 
@@ -300,6 +340,74 @@ func (b *intEdit) getValue() interface{} {
 	return r
 }
 
+////////////////////////////////////////////
+// amountEdit edits an amount in the display unit, or in the unit it's
+// entered with, its value being in the native unit of the field.
+type amountEdit struct {
+	baseEdit
+	value  string
+	native amountUnit
+	unit   amountUnit
+}
+
+func newAmountEdit(name string, label string, width int, def int64, native amountUnit) *amountEdit {
+	b := new(amountEdit)
+	b.setLabel(label + " (" + context.unit.String() + ")")
+	b.setName(name)
+	b.setHeight(1)
+	b.setContentWidth(width)
+	b.setShowLabel(true)
+	b.native = native
+	b.unit = context.unit
+	b.value = formatAmountInput(def, native, b.unit)
+	return b
+}
+
+func (b *amountEdit) layout(g *gocui.Gui) error {
+	v := b.baseLayout(g)
+
+	v.Editor = gocui.EditorFunc(b.editor)
+
+	v, err := g.View(b.name)
+
+	if err != nil {
+		log.Printf("layout %s\n", err.Error())
+		return err
+	}
+
+	fmt.Fprint(v, context.theme.inverted)
+	fmt.Fprintf(v, "%-"+strconv.Itoa(b.getContentWidth())+"s", b.value)
+
+	return nil
+}
+
+func (b *amountEdit) editor(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	switch {
+	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+		if len(b.value) > 0 {
+			b.value = b.value[:len(b.value)-1]
+		}
+	case len(b.value) < b.getContentWidth() && key == gocui.KeySpace:
+		b.value += " "
+	case len(b.value) < b.getContentWidth() && (ch == '.' || ch == ' ' || unicode.IsDigit(ch) || unicode.IsLetter(ch)):
+		b.value += string(ch)
+	}
+}
+
+// parseError returns why the amount entered can't be parsed, if it can't.
+func (b *amountEdit) parseError() error {
+	if len(strings.TrimSpace(b.value)) == 0 {
+		return nil
+	}
+	_, err := parseAmount(b.value, b.native, b.unit)
+	return err
+}
+
+func (b *amountEdit) getValue() interface{} {
+	r, _ := parseAmount(b.value, b.native, b.unit)
+	return int(r)
+}
+
 ////////////////////////////////////////////
 type textEdit struct {
 	baseEdit
@@ -421,6 +529,9 @@ type formEdit struct {
 	toMap               interface{}
 	minWidth            int
 	minHeight           int
+	submitOnEnter       bool
+	// changed, when set, is called with the name of the editor left
+	changed func(name string)
 }
 
 func newFormEditWithSize(name string, title string, toMap interface{}, width int, height int) *formEdit {
@@ -496,6 +607,14 @@ func (f *formEdit) boolEditor(v reflect.StructField, def bool) {
 func (f *formEdit) intEditor(v reflect.StructField, def int64) {
 	displayName := v.Tag.Get("displayname")
 	length := f.getWidth(strconv.Atoi(v.Tag.Get("length")))
+	if unit, ok := v.Tag.Lookup("unit"); ok {
+		native, err := parseAmountUnit(unit)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid unit '%s'", unit))
+		}
+		f.addEditor(newAmountEdit(v.Name, displayName, length, def, native))
+		return
+	}
 	e := newIntEdit(v.Name, displayName, length, def)
 	f.addEditor(e)
 }
@@ -543,9 +662,12 @@ func (f *formEdit) registerKeys(g *gocui.Gui) {
 	khe.mode = gocui.ModNone
 	khe.action = func(g *gocui.Gui, v *gocui.View) error {
 		if !f.ok.selected && !f.cancel.selected {
+			if f.submitOnEnter {
+				f.submit(true)
+			}
 			return nil
 		}
-		f.callback(f.ok.selected)
+		f.submit(f.ok.selected)
 		return nil
 	}
 	f.addKeyHandler(khe)
@@ -562,6 +684,10 @@ func (f *formEdit) registerKeys(g *gocui.Gui) {
 }
 
 func (f *formEdit) switchActiveEditor(delta int, g *gocui.Gui) {
+	if f.changed != nil && delta != 0 && f.selectedEditorIndex >= 0 && f.selectedEditorIndex < len(f.editors) {
+		f.changed(f.editors[f.selectedEditorIndex].getName())
+	}
+
 	if delta == 0 {
 		f.selectedEditorIndex = 0
 	}
@@ -685,6 +811,9 @@ func (f *formEdit) setSize() {
 	for _, e := range f.editors {
 		height += e.getHeight()
 		w := e.getLabelWidth() + e.getContentWidth() + 5
+		if len(e.getError()) > 0 {
+			w += len(e.getError()) + 1
+		}
 		if w > maxWidth {
 			maxWidth = w
 		}
@@ -721,6 +850,73 @@ func (f *formEdit) getValue() interface{} {
 	return f.toMap
 }
 
+// submit closes the form through its callback, unless it's validated and
+// has invalid fields.
+func (f *formEdit) submit(valid bool) {
+	if valid && !f.validate(context.gocui) {
+		return
+	}
+	f.callback(valid)
+}
+
+// validate checks the editor values against the tags of the mapped struct
+// fields, then its formValidator if any. The errors are shown next to their
+// editors and the first invalid one gets the focus.
+func (f *formEdit) validate(g *gocui.Gui) bool {
+	v := reflect.ValueOf(f.toMap).Elem()
+	t := reflect.TypeOf(f.toMap).Elem()
+
+	if v.Kind() != reflect.Struct {
+		return true
+	}
+
+	errs := make(map[string]string)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if e := f.getEditor(sf.Name); e != nil {
+			if ae, ok := (*e).(*amountEdit); ok {
+				if err := ae.parseError(); err != nil {
+					errs[sf.Name] = err.Error()
+					continue
+				}
+			}
+			if err := validateField(sf, (*e).getValue()); err != nil {
+				errs[sf.Name] = err.Error()
+			}
+		}
+	}
+
+	if fv, ok := f.toMap.(formValidator); ok && len(errs) == 0 {
+		f.getValue()
+		errs = fv.validate()
+	}
+
+	first := -1
+
+	for i, e := range f.editors {
+		e.setError(errs[e.getName()])
+		if first < 0 && len(e.getError()) > 0 {
+			first = i
+		}
+	}
+
+	f.setSize()
+
+	if first < 0 {
+		return true
+	}
+
+	f.selectedEditorIndex = first
+	f.ok.selected = false
+	f.cancel.selected = false
+	g.SetCurrentView(f.editors[first].getName())
+
+	f.layout(g)
+
+	return false
+}
+
 func (f *formEdit) getEditor(name string) *baseEditI {
 	for _, e := range f.editors {
 		if e.getName() == name {
@@ -731,6 +927,16 @@ func (f *formEdit) getEditor(name string) *baseEditI {
 	return nil
 }
 
+// setValue sets the text of the editor name, for the fields derived from
+// others.
+func (f *formEdit) setValue(name string, value string) {
+	if e := f.getEditor(name); e != nil {
+		if te, ok := (*e).(*textEdit); ok {
+			te.value = value
+		}
+	}
+}
+
 func (f *formEdit) close(g *gocui.Gui) {
 	for _, e := range f.editors {
 		e.delete(g)
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/spf13/viper"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	testViewWidth  = 201
+	testViewHeight = 41
+)
+
+// setupTestContext resets the global context and status the way main does,
+// with a fake backend and a gocui.Gui that is never attached to a terminal.
+// The views render into in-memory gocui views that the tests read back.
+func setupTestContext(t *testing.T) *fakeBackend {
+	t.Helper()
+
+	// the background work of the previous test reads the globals reset here
+	if status.store != nil {
+		status.store.wait()
+	}
+
+	viper.Reset()
+	viper.SetConfigFile("config.json")
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	initBaseConfig()
+
+	fb := newFakeBackend()
+
+	status = lncliStatus{}
+	status.store = newStateStore()
+	status.nodes = newNodeCache("", getNodeCacheTTL(), getNodeCacheSize())
+	// the addresses are validated against the network of the node
+	status.store.update(func(st *lncliState) { st.localNodeInfo = *fb.info })
+
+	context = lnclicursesContext{}
+	context.printer = message.NewPrinter(language.English)
+	context.activeMainView = channelListViewt
+	context.views = make(map[viewType]viewI)
+	context.backend = fb
+	context.gocui = new(gocui.Gui)
+	context.scheduler = newRefreshScheduler()
+	context.unit = getAmountUnit()
+
+	for _, name := range []string{"main", "nodeinfo", "balance", "menu"} {
+		if _, err := context.gocui.SetView(name, 0, 0, testViewWidth, testViewHeight); err != nil && err != gocui.ErrUnknownView {
+			t.Fatal(err)
+		}
+	}
+
+	initGrids()
+
+	return fb
+}
+
+// seedNodeCache fills the node cache from the fake backend so alias lookups
+// don't have to go through getnodeinfo.
+func seedNodeCache(fb *fakeBackend) {
+	for k, v := range fb.nodes {
+		status.nodes.set(k, *v)
+	}
+}
+
+// updateTestData updates the data of the active view and renders it, handing
+// the published state to its grid as the gocui layout does.
+func updateTestData(t *testing.T) []string {
+	t.Helper()
+	updateData()
+	return renderMainView(t)
+}
+
+func activateView(view viewType) {
+	context.activeMainView = view
+}
+
+// renderView refreshes a gocui view through fn and returns its lines.
+func renderView(t *testing.T, name string, fn func(g *gocui.Gui)) []string {
+	t.Helper()
+
+	fn(context.gocui)
+
+	v, err := context.gocui.View(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Split(strings.TrimRight(v.Buffer(), "\n"), "\n")
+}
+
+func renderMainView(t *testing.T) []string {
+	t.Helper()
+	return renderView(t, "main", refreshMainView)
+}
+
+func findLine(lines []string, substrings ...string) (string, bool) {
+	for _, l := range lines {
+		found := true
+		for _, s := range substrings {
+			if !strings.Contains(l, s) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+func assertLine(t *testing.T, lines []string, substrings ...string) string {
+	t.Helper()
+	l, ok := findLine(lines, substrings...)
+	if !ok {
+		t.Fatalf("no line containing %q in\n%s", substrings, strings.Join(lines, "\n"))
+	}
+	return l
+}
+
+// waitFor polls cond until it returns true, the alias lookups and payments
+// are run in their own goroutines.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func getTestEditor(t *testing.T, f *formEdit, name string) baseEditI {
+	t.Helper()
+	e := f.getEditor(name)
+	if e == nil {
+		t.Fatalf("form has no editor '%s'", name)
+	}
+	return *e
+}
+
+// typeInForm replaces the content of a text, int or amount editor by feeding its
+// editor function one key at a time, as gocui would.
+func typeInForm(t *testing.T, f *formEdit, name string, text string) {
+	t.Helper()
+
+	var editor func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier)
+	var length func() int
+
+	switch e := getTestEditor(t, f, name).(type) {
+	case *textEdit:
+		editor = e.editor
+		length = func() int { return len(e.value) }
+	case *intEdit:
+		editor = e.editor
+		length = func() int { return len(e.value) }
+	case *amountEdit:
+		editor = e.editor
+		length = func() int { return len(e.value) }
+	default:
+		t.Fatalf("editor '%s' doesn't accept text", name)
+	}
+
+	for length() > 0 {
+		editor(nil, gocui.KeyBackspace, 0, gocui.ModNone)
+	}
+
+	for _, ch := range text {
+		editor(nil, 0, ch, gocui.ModNone)
+	}
+}
+
+func toggleInForm(t *testing.T, f *formEdit, name string) {
+	t.Helper()
+	e, ok := getTestEditor(t, f, name).(*boolEdit)
+	if !ok {
+		t.Fatalf("editor '%s' is not a bool editor", name)
+	}
+	e.editor(nil, gocui.KeySpace, 0, gocui.ModNone)
+}
@@ -14,16 +14,24 @@ type invoiceListView struct {
 }
 
 type addInvoiceContainer struct {
-	Memo            string `displayname:"Memo (opt)" length:"64"`
+	Memo            string `displayname:"Memo (opt)" length:"64" validate:"max=639"`
 	Receipt         string `displayname:"Receipt (opt)" length:"64"`
-	Preimage        string `displayname:"Preimage" length:"64"`
-	Amt             int    `displayname:"Amount" length:"16"`
-	DescriptionHash string `displayname:"Description hash" length:"64"`
-	FallbackAddr    string `displayname:"Fallback Adddress" length:"64"`
-	Expiry          int    `displayname:"Expiry sec" length:"8"`
+	Preimage        string `displayname:"Preimage" length:"64" validate:"hex=32"`
+	Amt             int    `displayname:"Amount" length:"16" unit:"sat" validate:"min=0"`
+	DescriptionHash string `displayname:"Description hash" length:"64" validate:"hex=32"`
+	FallbackAddr    string `displayname:"Fallback Adddress" length:"64" validate:"address"`
+	Expiry          int    `displayname:"Expiry sec" length:"8" validate:"min=0"`
 	Private         bool   `displayname:"Private"`
 }
 
+func (c *addInvoiceContainer) validate() map[string]string {
+	errs := make(map[string]string)
+	if len(c.Memo) > 0 && len(c.DescriptionHash) > 0 {
+		errs["DescriptionHash"] = "memo or description hash"
+	}
+	return errs
+}
+
 type invoiceDisplayContainer struct {
 	Memo        string `displayname:"Memo" length:"50" readonly:"1"`
 	Private     string `displayname:"Private" length:"50" readonly:"1"`
@@ -50,18 +58,23 @@ func (cv *invoiceListView) init(fmtnormal string, fmtheader string, fmtselected
 	cv.shortcuts = nil
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Add invoice", "A", 'a', gocui.ModAlt, cv.addInvoice, true, ""})
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Details invoice", "D", 'd', gocui.ModAlt, cv.detailsInvoice, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Pending only", "O", 'o', gocui.ModAlt, cv.togglePending, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Newer", "PgUp", gocui.KeyPgup, gocui.ModNone, func() { cv.page(false) }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Older", "PgDn", gocui.KeyPgdn, gocui.ModNone, func() { cv.page(true) }, false, ""})
 
 	cv.grid.key = "invoices"
-	cv.grid.addColumn("Settled", "GetSettled", boolRow)       //"Settled", 2
-	cv.grid.addColumn("Private", "GetPrivate", boolRow)       //"Private", 2
-	cv.grid.addColumn("Memo", "GetMemo", stringRow)           //"Memo", 0
-	cv.grid.addColumn("Value", "GetValue", intRow)            //"Value", 16
-	cv.grid.addColumn("Creation", "GetCreationDate", dateRow) //"Creation",18
-	cv.grid.addColumn("Settled", "GetSettledDate", dateRow)   //"Settled", 18
-	cv.grid.addColumn("Expiry", "GetExpiry", intRow)          //"Expiry(s)", 10
-	cv.grid.addColumn("Paid", "GetAmtPaidMsat", intRow)       //"Paid mSat", 16
+	cv.grid.addColumn("Settled", "GetSettled", boolRow)         //"Settled", 2
+	cv.grid.addColumn("Private", "GetPrivate", boolRow)         //"Private", 2
+	cv.grid.addColumn("Memo", "GetMemo", stringRow)             //"Memo", 0
+	cv.grid.addAmountColumn("Value", "GetValue", satUnit)       //"Value", 16
+	cv.grid.addColumn("Creation", "GetCreationDate", dateRow)   //"Creation",18
+	cv.grid.addColumn("SettleDate", "GetSettleDate", dateRow)   //"Settled on", 18
+	cv.grid.addColumn("Expiry", "GetExpiry", intRow)            //"Expiry(s)", 10
+	cv.grid.addAmountColumn("Paid", "GetAmtPaidMsat", msatUnit) //"Paid mSat", 16
+	cv.grid.addFiatColumn("ValueFiat", "GetValue", satUnit)
 	cv.grid.initConfig()
 }
 
@@ -127,6 +140,21 @@ func (cv *invoiceListView) addInvoice() {
 	cv.form.initialize(context.gocui)
 }
 
+// page moves the list to the older or to the newer invoices.
+func (cv *invoiceListView) page(older bool) {
+	status.store.background(func() {
+		manageError(status.pageInvoices(&context, older))
+		refreshView()
+	})
+}
+
+func (cv *invoiceListView) togglePending() {
+	status.store.background(func() {
+		manageError(status.togglePendingInvoices(&context))
+		refreshView()
+	})
+}
+
 func (cv *invoiceListView) getSelectedPeer() *lncliPeer {
 	return cv.grid.getSelectedItem().Interface().(*lncliPeer)
 }
@@ -139,6 +167,9 @@ func (cv *invoiceListView) refreshView(g *gocui.Gui) {
 	}
 	v.Clear()
 	x, y := v.Size()
+	invoices := status.state().invoices
+	cv.grid.items = invoices.invoices
+	cv.grid.info = invoices.page.getInfo("Invoices")
 	cv.grid.setRenderSize(x, y)
 	for _, row := range cv.grid.getGridRows() {
 		fmt.Fprintln(v, row)
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+func TestInvoiceListViewRender(t *testing.T) {
+	setupTestContext(t)
+
+	activateView(invoiceListViewt)
+	updateData()
+
+	lines := renderMainView(t)
+
+	assertLine(t, lines, "[Invoices]")
+	assertLine(t, lines, "X", "coffee", "│25,000         │", "│25,000         │")
+	assertLine(t, lines, "donation", "100,000", "86,400")
+}
+
+func TestAddInvoiceForm(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[invoiceListViewt].(*invoiceListView)
+	cv.addInvoice()
+
+	typeInForm(t, cv.form, "Memo", "beer")
+	typeInForm(t, cv.form, "Receipt", "0a0b")
+	typeInForm(t, cv.form, "Amt", "5000")
+	typeInForm(t, cv.form, "Preimage", "0102")
+	typeInForm(t, cv.form, "Expiry", "600")
+
+	cv.form.callback(true)
+
+	inv := fb.lastInvoice
+	if inv == nil {
+		t.Fatal("addinvoice not called")
+	}
+	if inv.Memo != "beer" || inv.Value != 5000 || inv.Expiry != 600 || hex.EncodeToString(inv.RPreimage) != "0102" {
+		t.Errorf("unexpected invoice %+v", inv)
+	}
+	if fb.lastReceipt != "0a0b" {
+		t.Errorf("receipt %q", fb.lastReceipt)
+	}
+}
+
+func TestInvoiceDetailsForm(t *testing.T) {
+	setupTestContext(t)
+
+	activateView(invoiceListViewt)
+	updateTestData(t)
+
+	// Invoices are sorted newest first
+	cv := context.views[invoiceListViewt].(*invoiceListView)
+	cv.grid.moveSelectionDown()
+	cv.detailsInvoice()
+
+	cc := cv.form.toMap.(*invoiceDisplayContainer)
+	if cc.Memo != "coffee" || cc.Settled != "X" || cc.PayReq != "lntb250u1fakecoffee" {
+		t.Errorf("unexpected details %+v", cc)
+	}
+}
+
+func TestAddInvoiceFormValidation(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[invoiceListViewt].(*invoiceListView)
+	cv.addInvoice()
+
+	typeInForm(t, cv.form, "Preimage", "0102")
+	typeInForm(t, cv.form, "FallbackAddr", "bc1notanaddress")
+	cv.form.submit(true)
+
+	if e := getTestEditor(t, cv.form, "Preimage").getError(); e != "32 bytes hex expected" {
+		t.Errorf("preimage error = %q", e)
+	}
+	if e := getTestEditor(t, cv.form, "FallbackAddr").getError(); e != "invalid address" {
+		t.Errorf("fallback address error = %q", e)
+	}
+
+	typeInForm(t, cv.form, "Preimage", "")
+	typeInForm(t, cv.form, "FallbackAddr", "")
+	typeInForm(t, cv.form, "Memo", "beer")
+	typeInForm(t, cv.form, "DescriptionHash", strings.Repeat("ab", 32))
+	cv.form.submit(true)
+
+	if e := getTestEditor(t, cv.form, "DescriptionHash").getError(); e != "memo or description hash" {
+		t.Errorf("description hash error = %q", e)
+	}
+
+	typeInForm(t, cv.form, "Memo", "")
+	cv.form.submit(true)
+
+	if cv.form != nil || fb.lastInvoice == nil {
+		t.Error("valid invoice not added")
+	}
+}
+
+func setTestInvoices(fb *fakeBackend, n int) {
+	fb.invoices.Invoices = nil
+	for i := 1; i <= n; i++ {
+		fb.invoices.Invoices = append(fb.invoices.Invoices, &lnrpc.Invoice{
+			Memo:     fmt.Sprintf("invoice %d", i),
+			Value:    int64(i * 1000),
+			Settled:  i%3 == 0,
+			AddIndex: uint64(i),
+		})
+	}
+}
+
+func TestInvoicePaging(t *testing.T) {
+	fb := setupTestContext(t)
+	setTestInvoices(fb, 25)
+
+	defer func(size uint64) { listPageSize = size }(listPageSize)
+	listPageSize = 10
+
+	activateView(invoiceListViewt)
+	lines := updateTestData(t)
+	assertLine(t, lines, "[Invoices]", "Invoices 16 to 25 of 25")
+	assertLine(t, lines, "invoice 25")
+
+	for _, step := range []struct {
+		older bool
+		info  string
+	}{
+		{true, "Invoices 6 to 15 of 25"},
+		{true, "Invoices 1 to 5 of 25"},
+		{true, "Invoices 1 to 5 of 25"},
+		{false, "Invoices 6 to 15 of 25"},
+		{false, "Invoices 16 to 25 of 25"},
+		{false, "Invoices 16 to 25 of 25"},
+	} {
+		if err := status.pageInvoices(&context, step.older); err != nil {
+			t.Fatal(err)
+		}
+		assertLine(t, renderMainView(t), "[Invoices]", step.info)
+	}
+
+	status.pageInvoices(&context, true)
+	lines = renderMainView(t)
+	assertLine(t, lines, "invoice 6")
+	if _, ok := findLine(lines, "invoice 16"); ok {
+		t.Error("newer invoice on an older page")
+	}
+	if req := fb.lastListInvoices; !req.Reversed || req.IndexOffset != 16 {
+		t.Errorf("page not kept by the update, %+v", req)
+	}
+}
+
+func TestInvoicePendingOnly(t *testing.T) {
+	fb := setupTestContext(t)
+	setTestInvoices(fb, 9)
+
+	activateView(invoiceListViewt)
+	updateTestData(t)
+
+	cv := context.views[invoiceListViewt].(*invoiceListView)
+	cv.togglePending()
+	status.store.wait()
+
+	if !fb.lastListInvoices.PendingOnly {
+		t.Error("pending invoices not requested")
+	}
+	lines := renderMainView(t)
+	assertLine(t, lines, "[Invoices]", "Pending Invoices 1 to 8 of 9")
+	if _, ok := findLine(lines, "invoice 3"); ok {
+		t.Error("settled invoice listed")
+	}
+
+	cv.togglePending()
+	status.store.wait()
+	assertLine(t, renderMainView(t), "invoice 3")
+}
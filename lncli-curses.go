@@ -1,14 +1,10 @@
 package main
 
 import (
-	"sync"
-	"time"
-
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 
 	"github.com/jroimartin/gocui"
-	"github.com/lightningnetwork/lnd/lnrpc"
 )
 
 type lnclicursesContext struct {
@@ -17,9 +13,13 @@ type lnclicursesContext struct {
 	views           map[viewType]viewI
 	globalShortcuts []*keyHandle
 	theme           themeGUI
-	logs            []*logEntry
 	printer         *message.Printer
-	cliMutex        *sync.Mutex
+	backend         nodeBackend
+	scheduler       *refreshScheduler
+	streams         *streamManager
+	unit            amountUnit
+	unitShortcut    *keyHandle
+	price           priceProvider
 }
 
 var context lnclicursesContext
@@ -32,54 +32,50 @@ func manageError(err error) {
 	logError(err.Error())
 }
 
-func setUpdateTicker() {
-	ticker := time.NewTicker(time.Second * time.Duration(getRefreshSec()))
-	go func() {
-		for range ticker.C {
-			updateData()
-		}
-	}()
+// updateData updates the data of the active view, it reads the gui state and
+// is called from the gocui goroutine.
+func updateData() {
+	updateViewData(context.activeMainView)
 }
 
-func updateData() {
-	if getShowHeader() {
-		manageError(status.updateLocalNodeInfo(&context))
-		manageError(status.updateWalletBalance(&context))
-	}
-	switch context.activeMainView {
-	case channelListViewt:
-		manageError(status.updateChannelList(&context))
-	case peerListViewt:
-		manageError(status.updatePeersList(&context))
-	case pendingChannelListViewt:
-		manageError(status.updatePendingChannelList(&context))
-	case invoiceListViewt:
-		manageError(status.updateInvoiceList(&context))
-	case paymentListViewt:
-		manageError(status.updatePaymentList(&context))
-	case walletTransactionsViewt:
-		manageError(status.updateWallletTransactionsList(&context))
-	}
+// updateDataInBackground is updateData not blocking the gocui goroutine.
+func updateDataInBackground() {
+	view := context.activeMainView
+	status.store.background(func() { updateViewData(view) })
+}
+
+func updateViewData(view viewType) {
+	context.scheduler.refresh(view)
+	manageError(status.nodes.save())
 	refreshView()
 }
 
 func main() {
 
-	status.nodes = make(map[string]lnrpc.NodeInfo)
-
 	context.printer = message.NewPrinter(language.English)
 	context.activeMainView = channelListViewt
 	context.views = make(map[viewType]viewI)
-	context.cliMutex = &sync.Mutex{}
 
 	if !initConfig() {
 		panic("Couldn't read configuration")
 	}
 
+	status.store = newStateStore()
+	status.nodes = newNodeCache(getNodeCachePath(), getNodeCacheTTL(), getNodeCacheSize())
+	manageError(status.nodes.load())
+	context.scheduler = newRefreshScheduler()
+	context.price = newPriceProvider()
+
+	backend, err := newNodeBackend()
+	if err != nil {
+		panic(err)
+	}
+	context.backend = backend
+
 	initTheme()
+	context.unit = getAmountUnit()
 	initGrids()
 
-	setUpdateTicker()
 	initViews()
 	switchActiveView(channelListViewt)
 }
@@ -88,7 +84,9 @@ func switchActiveView(view viewType) {
 	unregisterKeyHandlers(context.views[context.activeMainView].getShortCuts())
 	context.activeMainView = view
 	registerKeyHandlers(context.views[view].getShortCuts())
-	go updateData()
+	if context.scheduler.setActiveView(view) {
+		updateDataInBackground()
+	}
 }
 
 func initGrids() {
@@ -98,7 +96,15 @@ func initGrids() {
 	initPaymentListGrid()
 	initInvoiceListGrid()
 	initWalletTransactionListGrid()
+	initUtxoListGrid()
 	initLogListGrid()
+	initForwardingHistoryGrid()
+	initRouteListGrid()
+	initBatchListGrid()
+	initCloseListGrid()
+	initNodeCacheShortcut()
+	initRefreshShortcut()
+	initAmountUnitShortcut()
 }
 
 func initChannelListGrid() {
@@ -131,7 +137,50 @@ func initWalletTransactionListGrid() {
 	context.globalShortcuts = append(context.globalShortcuts, &keyHandle{getConfigGridShortcutHeader("walletTransactions"), "6", '6', gocui.ModAlt, func() { switchActiveView(walletTransactionsViewt) }, true, ""})
 }
 
+func initUtxoListGrid() {
+	context.views[utxoListViewt] = newutxoListView("main", context.theme.normal, context.theme.gridHeader, context.theme.gridSelected)
+	context.globalShortcuts = append(context.globalShortcuts, &keyHandle{getConfigGridShortcutHeader("utxos"), "9", '9', gocui.ModAlt, func() { switchActiveView(utxoListViewt) }, true, ""})
+}
+
+func initForwardingHistoryGrid() {
+	context.views[forwardingHistoryViewt] = newforwardingHistoryView("main", context.theme.normal, context.theme.gridHeader, context.theme.gridSelected)
+	context.globalShortcuts = append(context.globalShortcuts, &keyHandle{getConfigGridShortcutHeader("forwards"), "8", '8', gocui.ModAlt, func() { switchActiveView(forwardingHistoryViewt) }, true, ""})
+}
+
+// initRouteListGrid adds the route view, reached by probing routes from the
+// payment view.
+func initRouteListGrid() {
+	context.views[routeListViewt] = newrouteListView("main", context.theme.normal, context.theme.gridHeader, context.theme.gridSelected)
+}
+
+// initBatchListGrid adds the batch payment view, reached from the wallet
+// transaction view.
+func initBatchListGrid() {
+	context.views[batchListViewt] = newbatchListView("main", context.theme.normal, context.theme.gridHeader, context.theme.gridSelected)
+}
+
+// initCloseListGrid adds the bulk close view, reached by closing several
+// channels from the channel view, or with Alt+0 to follow or retry them.
+func initCloseListGrid() {
+	context.views[closeListViewt] = newcloseListView("main", context.theme.normal, context.theme.gridHeader, context.theme.gridSelected)
+	context.globalShortcuts = append(context.globalShortcuts, &keyHandle{"Bulk close", "0", '0', gocui.ModAlt, func() { switchActiveView(closeListViewt) }, true, ""})
+}
+
 func initLogListGrid() {
 	context.views[logViewt] = newlogListView("main", context.theme.normal, context.theme.gridHeader, context.theme.gridSelected)
 	context.globalShortcuts = append(context.globalShortcuts, &keyHandle{"Logs", "7", '7', gocui.ModAlt, func() { switchActiveView(logViewt) }, true, ""})
 }
+
+func initRefreshShortcut() {
+	context.globalShortcuts = append(context.globalShortcuts, &keyHandle{"Refresh", "U", 'u', gocui.ModAlt, updateDataInBackground, true, ""})
+}
+
+func initNodeCacheShortcut() {
+	context.globalShortcuts = append(context.globalShortcuts, &keyHandle{"Refresh nodes", "I", 'i', gocui.ModAlt, refreshNodeCache, true, ""})
+}
+
+// refreshNodeCache expires the cached node infos, queried again by the update.
+func refreshNodeCache() {
+	status.nodes.invalidate()
+	updateDataInBackground()
+}
@@ -2,30 +2,32 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"os/exec"
-	"regexp"
-	"strconv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/golang/protobuf/jsonpb"
-	expect "github.com/google/goexpect"
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
 )
 
+// lncliStatus queries lnd and publishes the results to its store.
 type lncliStatus struct {
-	localNodeInfo      lnrpc.GetInfoResponse
-	walletBalance      lnrpc.WalletBalanceResponse
-	channels           []*lncliChannel
-	peers              []*lncliPeer
-	pendingchannels    lncliPendingChannelsContainer
-	walletTransactions []*lnrpc.Transaction
-	invoices           lncliInvoicesContainer
-	payments           []*lncliPayment
-	nodes              map[string]lnrpc.NodeInfo
+	store *stateStore
+	nodes *nodeCache
+}
+
+func (s *lncliStatus) state() *lncliState {
+	return s.store.snapshot()
 }
 
 type pendingChannelType int
@@ -81,8 +83,10 @@ type lncliPendingChannelsContainer struct {
 
 type lncliChannel struct {
 	lnrpc.Channel
-	NodeAlias string
-	NodeUpdate int
+	NodeAlias   string
+	NodeUpdate  int
+	BaseFeeMsat int64
+	FeeRatePpm  int64
 }
 
 type lncliPeer struct {
@@ -90,17 +94,232 @@ type lncliPeer struct {
 	Alias string
 }
 
+// listPage is the position in a list paged by lnd, invoices by their add
+// index and payments by their payment index. The page holds the items before
+// start, the most recent ones when start is 0, from the first to the last
+// index. total is the highest index seen.
+type listPage struct {
+	start       uint64
+	first       uint64
+	last        uint64
+	total       uint64
+	pendingOnly bool
+}
+
+var listPageSize uint64 = 100
+
+// setBounds returns the page holding the items from first to last.
+func (p listPage) setBounds(first uint64, last uint64) listPage {
+	p.first, p.last = first, last
+	if last > p.total {
+		p.total = last
+	}
+	return p
+}
+
+// turn returns the page of the older or of the newer items next to p, query
+// returning the number of items and the last index of a page read from
+// offset. ok is false when there is no such page.
+func (p listPage) turn(older bool, query func(offset uint64, reversed bool) (int, uint64, error)) (next listPage, ok bool, err error) {
+	var offset uint64
+	switch {
+	case older && p.first > 1:
+		offset = p.first
+	case !older && p.start > 0:
+		offset = p.last
+	default:
+		return p, false, nil
+	}
+
+	count, last, err := query(offset, older)
+	if err != nil || count == 0 {
+		return p, false, err
+	}
+
+	next = p
+	next.start = last + 1
+	if !older && count < int(listPageSize) {
+		next.start = 0
+	}
+	return next, true, nil
+}
+
+func (p listPage) getInfo(name string) string {
+	info := name
+	if p.pendingOnly {
+		info = "Pending " + info
+	}
+	if p.last == 0 {
+		return info + " none"
+	}
+	return context.printer.Sprintf("%s %d to %d of %d", info, p.first, p.last, p.total)
+}
+
 type lncliInvoicesContainer struct {
-	currentStartIndex int64
-	invoices          []*lncliInvoice
+	page     listPage
+	invoices []*lncliInvoice
 }
 
 type lncliInvoice struct {
 	lnrpc.Invoice
 }
 
+type lncliPaymentsContainer struct {
+	page     listPage
+	payments []*lncliPayment
+}
+
+// lncliPayment is a payment with the aliases of the nodes of its path.
 type lncliPayment struct {
 	lnrpc.Payment
+	PathAliases string
+	HopCount    int
+	aliases     map[string]string
+}
+
+// updatePathAliases resolves the aliases of the path nodes and of the hops of
+// the payment attempts.
+func (c *lncliPayment) updatePathAliases(ctxt *lnclicursesContext, stat *lncliStatus) {
+	c.aliases = make(map[string]string)
+
+	resolve := func(pubkey string) string {
+		alias, ok := c.aliases[pubkey]
+		if !ok {
+			alias = stat.getNodeAlias(ctxt, pubkey)
+			c.aliases[pubkey] = alias
+		}
+		return alias
+	}
+
+	var path []string
+	for _, pubkey := range c.Path {
+		path = append(path, resolve(pubkey))
+	}
+	c.PathAliases = strings.Join(path, " > ")
+	c.HopCount = len(c.Path)
+
+	for _, h := range c.Htlcs {
+		if h.Route != nil {
+			for _, hop := range h.Route.Hops {
+				resolve(hop.PubKey)
+			}
+		}
+	}
+}
+
+// getRoute returns the route of the succeeded attempt, else of the last one.
+func (c *lncliPayment) getRoute() *lnrpc.Route {
+	var route *lnrpc.Route
+	for _, h := range c.Htlcs {
+		if h.Route == nil {
+			continue
+		}
+		if h.Status == lnrpc.HTLCAttempt_SUCCEEDED {
+			return h.Route
+		}
+		route = h.Route
+	}
+	return route
+}
+
+func (c *lncliPayment) getStatus() string {
+	return strings.ToLower(strings.Replace(c.Status.String(), "_", " ", -1))
+}
+
+// getFailureReason returns why the payment failed, empty when it didn't.
+func (c *lncliPayment) getFailureReason() string {
+	if c.FailureReason == lnrpc.PaymentFailureReason_FAILURE_REASON_NONE {
+		return ""
+	}
+	reason := strings.TrimPrefix(c.FailureReason.String(), "FAILURE_REASON_")
+	return strings.ToLower(strings.Replace(reason, "_", " ", -1))
+}
+
+// lncliRoute is a candidate route of a probe, its hops listed with their
+// aliases and fees.
+type lncliRoute struct {
+	lnrpc.Route
+	OutgoingChanID uint64
+	OutgoingChan   string
+	OutgoingAlias  string
+	HopCount       int
+	Path           string
+	TimeLockDelta  int64
+}
+
+// lncliRoutesContainer holds the routes probed to destination for amount
+// sat.
+type lncliRoutesContainer struct {
+	destination string
+	alias       string
+	amount      int64
+	routes      []*lncliRoute
+}
+
+func (c *lncliRoutesContainer) getInfo() string {
+	if len(c.destination) == 0 {
+		return "No probe, Alt+Q to probe routes"
+	}
+	return fmt.Sprintf("%d route(s) to %s for %s", len(c.routes), c.alias, formatAmountUnit(c.amount, satUnit))
+}
+
+// lncliForwardingHistoryContainer holds the forwarding events between
+// startTime and endTime, 0 meaning the configured number of days until now.
+type lncliForwardingHistoryContainer struct {
+	startTime  int64
+	endTime    int64
+	rangeStart int64
+	rangeEnd   int64
+	fetched    lncliForwardsFetch
+	recent     lncliForwardsFetch
+	events     []*lncliForwardingEvent
+	feeDay     uint64
+	feeWeek    uint64
+	feeMonth   uint64
+	feeTotal   uint64
+}
+
+// lncliForwardsFetch holds the forwarding events queried from start until
+// end, offset being the index of the next event to query from start.
+type lncliForwardsFetch struct {
+	start  int64
+	end    int64
+	offset uint32
+	events []*lncliForwardingEvent
+}
+
+func (c *lncliForwardingHistoryContainer) getInfo() string {
+	return fmt.Sprintf("Fees day: %s  week: %s  month: %s  %s to %s: %s",
+		formatForwardingFee(c.feeDay), formatForwardingFee(c.feeWeek), formatForwardingFee(c.feeMonth),
+		time.Unix(c.rangeStart, 0).Format("2006-01-02"), time.Unix(c.rangeEnd, 0).Format("2006-01-02"),
+		formatForwardingFee(c.feeTotal))
+}
+
+// formatForwardingFee formats a fee in the display unit, followed by its
+// fiat value when there's a price.
+func formatForwardingFee(fee uint64) string {
+	s := formatAmountUnit(int64(fee), msatUnit)
+	price := status.state().price
+	if fiat := price.formatFiat(int64(fee), msatUnit); len(fiat) > 0 {
+		s += " (" + fiat + " " + price.currency + ")"
+	}
+	return s
+}
+
+type lncliForwardingEvent struct {
+	lnrpc.ForwardingEvent
+	AliasIn  string
+	AliasOut string
+}
+
+var forwardingHistoryPageSize uint32 = 10000
+
+func (e *lncliForwardingEvent) GetChanIn() string {
+	return shortChannelID(e.ChanIdIn)
+}
+
+func (e *lncliForwardingEvent) GetChanOut() string {
+	return shortChannelID(e.ChanIdOut)
 }
 
 func (c *lncliPendingChannel) updateNodeAlias(ctxt *lnclicursesContext, stat *lncliStatus) error {
@@ -139,594 +358,1826 @@ func (c *lncliPeer) updateNodeAlias(ctxt *lnclicursesContext, stat *lncliStatus)
 	return nil
 }
 
+// getNodeInfo returns the node info from the cache, querying it when missing
+// or expired. The expired entry is still returned if the query fails, a node
+// without entry isn't queried again for a while after failing.
 func (s *lncliStatus) getNodeInfo(ctxt *lnclicursesContext, pubkey string) (lnrpc.NodeInfo, error) {
-	found, ok := s.nodes[pubkey]
+	found, ok, fresh := s.nodes.get(pubkey)
 
-	if ok {
+	if ok && fresh {
 		return found, nil
 	}
 
+	if !ok {
+		if err := s.nodes.getFailure(pubkey); err != nil {
+			return lnrpc.NodeInfo{}, err
+		}
+	}
+
 	nodeinfo, err := s.queryNodeInfo(ctxt, pubkey)
 
 	if err != nil {
+		if ok {
+			return found, nil
+		}
+		s.nodes.setFailure(pubkey, err)
 		return nodeinfo, err
 	}
 
-	s.nodes[pubkey] = nodeinfo
+	s.nodes.set(pubkey, nodeinfo)
 	return nodeinfo, nil
 }
 
-func (ctxt *lnclicursesContext) getlncliArgs() []string {
-	var args []string
-
-	args = nil
-
-	if len(getLndDir()) > 0 {
-		args = append(args, "--lnddir="+getLndDir())
-	}
-	if len(getRPCServer()) > 0 {
-		args = append(args, "--rpcserver="+getRPCServer())
-	}
-	if len(getTLSCertPath()) > 0 {
-		args = append(args, "--tlscertpath="+getTLSCertPath())
-	}
-	if getNoMacaroons() {
-		args = append(args, "--no-macaroons")
-	}
-	if len(getMacaroonPath()) > 0 {
-		args = append(args, "--macaroonpath="+getMacaroonPath())
-	}
-	if getMacaroonTimeOut() > 0 {
-		args = append(args, fmt.Sprintf("--macaroontimeout=%d", getMacaroonTimeOut()))
-	}
-	if len(getMacaroonIP()) > 0 {
-		args = append(args, "--macaroonip="+getMacaroonIP())
-	}
-
-	return args
-}
-
-func (ctxt *lnclicursesContext) execlncliCommand(command string) ([]byte, error) {
-
-	args := ctxt.getlncliArgs()
-	args = append(args, strings.Split(command, " ")...)
-
-	cmd := exec.Command(getLncliExec())
-	cmd.Args = args
-
-	ctxt.cliMutex.Lock()
-	out, err := cmd.Output()
-	ctxt.cliMutex.Unlock()
-
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
 func (s *lncliStatus) updateLocalNodeInfo(ctxt *lnclicursesContext) error {
-	txt, err := ctxt.execlncliCommand("getinfo")
+	info, err := ctxt.backend.getInfo()
 	if err != nil {
 		return err
 	}
-	return jsonpb.Unmarshal(bytes.NewReader(txt), &s.localNodeInfo)
+	s.store.update(func(st *lncliState) { st.localNodeInfo = *info })
+	return nil
 }
 
 func (s *lncliStatus) queryNodeInfo(ctxt *lnclicursesContext, pubkey string) (lnrpc.NodeInfo, error) {
-	txt, err := ctxt.execlncliCommand("getnodeinfo " + pubkey)
-	var nodeinfo lnrpc.NodeInfo
+	nodeinfo, err := ctxt.backend.getNodeInfo(pubkey)
 	if err != nil {
-		return nodeinfo, err
+		return lnrpc.NodeInfo{}, err
 	}
-	err = jsonpb.Unmarshal(bytes.NewReader(txt), &nodeinfo)
-	return nodeinfo, err
+	return *nodeinfo, nil
 }
 
 func (s *lncliStatus) updateWalletBalance(ctxt *lnclicursesContext) error {
-	txt, err := ctxt.execlncliCommand("walletbalance")
+	balance, err := ctxt.backend.walletBalance()
 	if err != nil {
 		return err
 	}
-	return jsonpb.Unmarshal(bytes.NewReader(txt), &s.walletBalance)
+	s.store.update(func(st *lncliState) { st.walletBalance = *balance })
+	return nil
 }
 
 func (s *lncliStatus) walletNewAdress(ctxt *lnclicursesContext, addressType string) (string, error) {
-	txt, err := ctxt.execlncliCommand("newaddress " + addressType)
-	if err != nil {
-		return "", err
-	}
-	var na lnrpc.NewAddressResponse
-	if err = jsonpb.Unmarshal(bytes.NewReader(txt), &na); err != nil {
-		return "", err
-	}
-	return na.Address, nil
+	return ctxt.backend.newAddress(addressType)
 }
 
-func (s *lncliStatus) updateWallletTransactionsList(ctxt *lnclicursesContext) error {
-	txt, err := ctxt.execlncliCommand("listchaintxns")
+// defaultConfTarget is the confirmation target of lnd's on-chain payments
+// when neither it nor a fee rate is set.
+const defaultConfTarget = 6
+
+// onChainEstimate is the fee estimate of an on-chain payment, amount being
+// what's sent, the confirmed balance less the fee for a sweep.
+type onChainEstimate struct {
+	amount  int64
+	fee     int64
+	feeRate int64
+}
+
+// estimateOnChainFee estimates the fee of an on-chain transaction paying the
+// addresses, at satPerByte when set.
+func (s *lncliStatus) estimateOnChainFee(ctxt *lnclicursesContext, addrToAmount map[string]int64, targetConf int32, satPerByte int64) (*onChainEstimate, error) {
+	if targetConf == 0 {
+		targetConf = defaultConfTarget
+	}
+
+	r, err := ctxt.backend.estimateFee(&lnrpc.EstimateFeeRequest{AddrToAmount: addrToAmount, TargetConf: targetConf})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var trans lnrpc.TransactionDetails
-	if err = jsonpb.Unmarshal(bytes.NewReader(txt), &trans); err != nil {
-		return err
+
+	e := &onChainEstimate{fee: r.FeeSat, feeRate: r.FeerateSatPerByte}
+	if satPerByte > 0 && r.FeerateSatPerByte > 0 {
+		e.fee = r.FeeSat * satPerByte / r.FeerateSatPerByte
+		e.feeRate = satPerByte
 	}
+	for _, amt := range addrToAmount {
+		e.amount += amt
+	}
+	return e, nil
+}
 
-	s.walletTransactions = nil
+// sweepOverheadVbytes is the virtual size of a segwit transaction without
+// its inputs and outputs, inputVbytes the one of the inputs by address type.
+const sweepOverheadVbytes = 11
 
-	for _, c := range trans.Transactions {
-		s.walletTransactions = append(s.walletTransactions, c)
-	}
+var inputVbytes = map[string]int64{
+	"p2wkh":  68,
+	"np2wkh": 91,
+	"p2tr":   58,
+}
 
-	ctxt.views[walletTransactionsViewt].getGrid().items = s.walletTransactions
-	return nil
+// outputVbytes returns the virtual size of an output paying addr.
+func outputVbytes(addr string) int64 {
+	a, err := btcutil.DecodeAddress(addr, getChainParams())
+	if err != nil {
+		return 43
+	}
+	switch a.(type) {
+	case *btcutil.AddressPubKeyHash:
+		return 34
+	case *btcutil.AddressScriptHash:
+		return 32
+	case *btcutil.AddressWitnessPubKeyHash:
+		return 31
+	}
+	// p2wsh and p2tr
+	return 43
 }
 
-func (s *lncliStatus) updateInvoiceList(ctxt *lnclicursesContext) error {
+// estimateSendCoins estimates the fee of the on-chain payment. lnd only
+// estimating payments with a change output, the fee of a sweep is the size of
+// its inputs and output at the estimated fee rate.
+func (s *lncliStatus) estimateSendCoins(ctxt *lnclicursesContext, req *lnrpc.SendCoinsRequest) (*onChainEstimate, error) {
+	if !req.SendAll {
+		return s.estimateOnChainFee(ctxt, map[string]int64{req.Addr: req.Amount}, req.TargetConf, req.SatPerByte)
+	}
 
-	txt, err := ctxt.execlncliCommand("listinvoices --reversed --max_invoices 100 --index_offset " + strconv.FormatInt(s.invoices.currentStartIndex, 10))
+	inputs, err := s.sweptUtxos(ctxt, req.Outpoints)
 	if err != nil {
-		return err
-	}
-	var chans lnrpc.ListInvoiceResponse
-	if err = jsonpb.Unmarshal(bytes.NewReader(txt), &chans); err != nil {
-		return err
+		return nil, err
 	}
+	swept := sumUtxos(inputs)
 
-	s.invoices.invoices = nil
-
-	for _, c := range chans.Invoices {
-		nc := lncliInvoice{*c}
-		s.invoices.invoices = append(s.invoices.invoices, &nc)
+	e := &onChainEstimate{feeRate: req.SatPerByte}
+	if e.feeRate == 0 {
+		// the rate only, estimated for any payment
+		r, err := s.estimateOnChainFee(ctxt, map[string]int64{req.Addr: swept / 2}, req.TargetConf, 0)
 		if err != nil {
-			logError(err.Error())
+			return nil, err
 		}
+		e.feeRate = r.feeRate
 	}
 
-	ctxt.views[invoiceListViewt].getGrid().items = s.invoices.invoices
+	vbytes := sweepOverheadVbytes + outputVbytes(req.Addr)
+	for _, u := range inputs {
+		v, ok := inputVbytes[u.AddressType]
+		if !ok {
+			// the largest for an unknown type
+			v = inputVbytes["np2wkh"]
+		}
+		vbytes += v
+	}
 
-	return nil
+	e.fee = vbytes * e.feeRate
+	e.amount = swept - e.fee
+	return e, nil
 }
 
-func (s *lncliStatus) updatePaymentList(ctxt *lnclicursesContext) error {
-
-	txt, err := ctxt.execlncliCommand("listpayments")
-	if err != nil {
-		return err
+// sweptUtxos returns the outputs spent by a sweep, the ones of outpoints or
+// all the confirmed ones.
+func (s *lncliStatus) sweptUtxos(ctxt *lnclicursesContext, outpoints []*lnrpc.OutPoint) ([]*lncliUtxo, error) {
+	if len(outpoints) == 0 {
+		unspent, err := ctxt.backend.listUnspent()
+		if err != nil {
+			return nil, err
+		}
+		var utxos []*lncliUtxo
+		for _, u := range unspent.Utxos {
+			if u.Confirmations > 0 {
+				utxos = append(utxos, &lncliUtxo{AddressType: utxoAddressTypes[u.AddressType], AmountSat: u.AmountSat})
+			}
+		}
+		return utxos, nil
 	}
-	var chans lnrpc.ListPaymentsResponse
-	if err = jsonpb.Unmarshal(bytes.NewReader(txt), &chans); err != nil {
-		return err
+
+	known := make(map[string]*lncliUtxo)
+	for _, u := range s.state().utxos {
+		known[u.Outpoint] = u
 	}
 
-	s.payments = nil
+	var utxos []*lncliUtxo
+	for _, op := range outpoints {
+		u, ok := known[outPointString(op)]
+		if !ok {
+			return nil, fmt.Errorf("unknown output %s", outPointString(op))
+		}
+		utxos = append(utxos, u)
+	}
+	return utxos, nil
+}
 
-	for _, c := range chans.Payments {
-		nc := lncliPayment{*c}
-		s.payments = append(s.payments, &nc)
+// sendCoins estimates the fee of the on-chain payment and sends it once
+// confirmed, in the background.
+func (s *lncliStatus) sendCoins(ctxt *lnclicursesContext, req *lnrpc.SendCoinsRequest) {
+	s.store.background(func() {
+		e, err := s.estimateSendCoins(ctxt, req)
 		if err != nil {
 			logError(err.Error())
+			runInGui(func() { displayMessage("Error: "+err.Error(), nil) })
+			return
 		}
-	}
 
-	ctxt.views[paymentListViewt].getGrid().items = s.payments
+		send := func() {
+			s.store.background(func() {
+				msg := s.sendCoinsNow(ctxt, req)
+				runInGui(func() { displayMessage(msg, nil) })
+			})
+		}
 
-	return nil
+		runInGui(func() {
+			displayOnChainConfirmation("Confirm on-chain payment", newSendCoinsConfirmContainer(req, e), send)
+		})
+	})
 }
 
-func (s *lncliStatus) updateChannelList(ctxt *lnclicursesContext) error {
-
-	txt, err := ctxt.execlncliCommand("listchannels")
+// sendCoinsNow sends the on-chain payment and describes its outcome, the
+// wallet transactions and balance being updated once sent.
+func (s *lncliStatus) sendCoinsNow(ctxt *lnclicursesContext, req *lnrpc.SendCoinsRequest) string {
+	txid, err := ctxt.backend.sendCoins(req)
 	if err != nil {
-		return err
-	}
-	var chans lnrpc.ListChannelsResponse
-	if err = jsonpb.Unmarshal(bytes.NewReader(txt), &chans); err != nil {
-		return err
-	}
-
-	s.channels = nil
-
-	for _, c := range chans.Channels {
-		nc := lncliChannel{*c, "", 0}
-		s.channels = append(s.channels, &nc)
-		go func() {
-			err := nc.updateNodeAlias(ctxt, s)
-			if err != nil {
-				logError(err.Error())
-			}
-			err = nc.updateNodeUpdate(ctxt, s)
-			if err != nil {
-				logError(err.Error())
-			}
-			refreshView()
-		}()
+		logError(err.Error())
+		return "Send failed\n" + err.Error()
 	}
 
-	ctxt.views[channelListViewt].getGrid().items = s.channels
+	ctxt.scheduler.refreshSource("walletTransactions")
+	ctxt.scheduler.refreshSource(headerRefreshKey)
 
-	return nil
+	return "Coins sent\nTxid: " + txid
 }
 
-func (s *lncliStatus) updatePendingChannelList(ctxt *lnclicursesContext) error {
+// lncliBatchPayment is a row of a batch payment file, Error telling why it
+// can't be paid.
+type lncliBatchPayment struct {
+	Row     int
+	Address string
+	Amount  int64
+	Error   string
+}
 
-	txt, err := ctxt.execlncliCommand("pendingchannels")
-	if err != nil {
-		return err
-	}
-	var chans lnrpc.PendingChannelsResponse
-	if err = jsonpb.Unmarshal(bytes.NewReader(txt), &chans); err != nil {
-		return err
-	}
+// lncliBatchContainer holds the on-chain payments loaded from path, sent
+// together in a single transaction.
+type lncliBatchContainer struct {
+	path        string
+	payments    []*lncliBatchPayment
+	total       int64
+	invalid     int
+	targetConf  int32
+	satPerByte  int64
+	label       string
+	estimate    *onChainEstimate
+	estimateErr string
+	txid        string
+	sending     bool
+}
 
-	s.pendingchannels.pendingChannels = nil
-	s.pendingchannels.totalLimbo = chans.GetTotalLimboBalance()
+func (c *lncliBatchContainer) getInfo() string {
+	if len(c.path) == 0 {
+		return "No batch, Alt+L to load a CSV file"
+	}
+
+	info := fmt.Sprintf("%s: %d payment(s) for %s", filepath.Base(c.path), len(c.payments), formatAmountUnit(c.total, satUnit))
+	switch {
+	case c.invalid > 0:
+		info += fmt.Sprintf(", %d invalid", c.invalid)
+	case c.sending:
+		info += ", sending"
+	case len(c.txid) > 0:
+		info += ", sent"
+	case c.estimate != nil:
+		info += ", fee estimate " + formatAmountUnit(c.estimate.fee, satUnit)
+	case len(c.estimateErr) > 0:
+		info += ", fee estimate failed: " + c.estimateErr
+	}
+	return info
+}
 
-	for _, c := range chans.PendingClosingChannels {
-		nc := makeNewPendingChannel(c.GetChannel(), closingChannel)
-		nc.closingTxid = c.GetClosingTxid()
-		s.pendingchannels.pendingChannels = append(s.pendingchannels.pendingChannels, nc)
-		go func() {
-			err := nc.updateNodeAlias(ctxt, s)
-			if err != nil {
-				logError(err.Error())
-			}
-			refreshView()
-		}()
+// checkSendable returns why the batch can't be sent, if so.
+func (c *lncliBatchContainer) checkSendable() error {
+	switch {
+	case len(c.payments) == 0:
+		return errors.New("no batch loaded")
+	case c.invalid > 0:
+		return fmt.Errorf("%d invalid payment(s) in batch", c.invalid)
+	case c.sending:
+		return errors.New("batch being sent")
+	case len(c.txid) > 0:
+		return errors.New("batch already sent")
 	}
+	return nil
+}
 
-	for _, c := range chans.PendingForceClosingChannels {
-		nc := makeNewPendingChannel(c.GetChannel(), forceClosingChannel)
-		nc.closingTxid = c.GetClosingTxid()
-		nc.blocksTilMaturity = c.GetBlocksTilMaturity()
-		nc.limboBalance = c.GetLimboBalance()
-		nc.maturityHeight = c.GetMaturityHeight()
-		nc.pendingHtlcs = c.GetPendingHtlcs()
-		nc.recoveredBalance = c.GetRecoveredBalance()
-		s.pendingchannels.pendingChannels = append(s.pendingchannels.pendingChannels, nc)
-		go func() {
-			err := nc.updateNodeAlias(ctxt, s)
-			if err != nil {
-				logError(err.Error())
-			}
-			refreshView()
-		}()
+func (c *lncliBatchContainer) addrToAmount() map[string]int64 {
+	amounts := make(map[string]int64)
+	for _, p := range c.payments {
+		amounts[p.Address] = p.Amount
 	}
+	return amounts
+}
 
-	for _, c := range chans.PendingOpenChannels {
-		nc := makeNewPendingChannel(c.GetChannel(), openChannel)
-		nc.commitFee = c.GetCommitFee()
-		nc.commitWeight = c.GetCommitWeight()
-		nc.confirmationHeight = c.GetConfirmationHeight()
-		nc.feePerKw = c.GetFeePerKw()
-		s.pendingchannels.pendingChannels = append(s.pendingchannels.pendingChannels, nc)
-		go func() {
-			err := nc.updateNodeAlias(ctxt, s)
-			if err != nil {
-				logError(err.Error())
-			}
-			refreshView()
-		}()
+func (c *lncliBatchContainer) sendManyRequest() *lnrpc.SendManyRequest {
+	return &lnrpc.SendManyRequest{
+		AddrToAmount: c.addrToAmount(),
+		TargetConf:   c.targetConf,
+		SatPerByte:   c.satPerByte,
+		Label:        c.label,
 	}
+}
 
-	for _, c := range chans.WaitingCloseChannels {
-		nc := makeNewPendingChannel(c.GetChannel(), waitingCloseChannel)
-		nc.limboBalance = c.GetLimboBalance()
-		s.pendingchannels.pendingChannels = append(s.pendingchannels.pendingChannels, nc)
-		go func() {
-			err := nc.updateNodeAlias(ctxt, s)
-			if err != nil {
-				logError(err.Error())
-			}
-			refreshView()
-		}()
-	}
+// parseBatchCSV reads the address,amount rows of a batch payment file,
+// amounts in sat unless followed by their unit. A leading header row, with
+// neither a number nor an address, blank lines and # comments are skipped,
+// invalid rows are returned with their error.
+func parseBatchCSV(r io.Reader) ([]*lncliBatchPayment, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var payments []*lncliBatchPayment
+	seen := make(map[string]bool)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row, _ := reader.FieldPos(0)
 
-	ctxt.views[pendingChannelListViewt].getGrid().items = s.pendingchannels.pendingChannels
+		p := &lncliBatchPayment{Row: row}
+		if len(record) > 0 {
+			p.Address = strings.TrimSpace(record[0])
+		}
 
-	return nil
-}
+		if len(record) != 2 {
+			p.Error = "address,amount expected"
+			payments = append(payments, p)
+			continue
+		}
 
-func (s *lncliStatus) closeChannel(ctxt *lnclicursesContext, channel *lncliChannel, force bool) (string, error) {
-	cp := strings.Split(channel.ChannelPoint, ":")
-	//--funding_txid value  the txid of the channel's funding transaction
-	//--output_index value  the output index for the funding output of the funding transaction (default: 0)
-	cm := "closechannel --funding_txid " + cp[0] + " --output_index " + cp[1]
-	if force {
-		cm = cm + " --force"
-	}
-	out, err := ctxt.execlncliCommand(cm)
+		params := getChainParams()
+		amt, amtErr := parseAmount(record[1], satUnit, satUnit)
+		addrErr := validateAddress(p.Address, params)
+
+		switch {
+		case len(payments) == 0 && amtErr != nil && !strings.ContainsAny(record[1], "0123456789") &&
+			addrErr != nil && !hasAddressPrefix(p.Address, params):
+			// header row
+			continue
+		case addrErr != nil:
+			p.Error = addrErr.Error()
+		case amtErr != nil:
+			p.Error = amtErr.Error()
+		case amt <= 0:
+			p.Error = "amount must be positive"
+		case seen[p.Address]:
+			p.Error = "duplicate address"
+		}
 
-	if err != nil {
-		return "", err
+		p.Amount = amt
+		seen[p.Address] = true
+		payments = append(payments, p)
 	}
 
-	cid, err := getAttributeStr(out, "closing_txid")
-
-	if err != nil {
-		return "", err
+	if len(payments) == 0 {
+		return nil, errors.New("no payment in file")
 	}
-
-	return cid.(string), nil
+	return payments, nil
 }
 
-func (s *lncliStatus) connectToPeer(ctxt *lnclicursesContext, pubkey string, host string, port int) (string, error) {
-	cm := fmt.Sprintf("connect %s@%s:%d", pubkey, host, port)
-	o, err := ctxt.execlncliCommand(cm)
-	if err != nil {
-		return "", err
+// loadBatch reads the batch payment file and estimates its fee when all its
+// payments are valid.
+func (s *lncliStatus) loadBatch(ctxt *lnclicursesContext, path string, targetConf int32, satPerByte int64, label string) error {
+	if strings.HasPrefix(path, "~/") {
+		path = filepath.Join(os.Getenv("HOME"), path[2:])
 	}
-	return string(o), nil
-}
 
-func (s *lncliStatus) disconnectPeer(ctxt *lnclicursesContext, peer *lncliPeer) (string, error) {
-	cm := fmt.Sprintf("disconnect %s", peer.PubKey)
-	o, err := ctxt.execlncliCommand(cm)
+	f, err := os.Open(path)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return string(o), nil
-}
-
-func (s *lncliStatus) updatePeersList(ctxt *lnclicursesContext) error {
+	defer f.Close()
 
-	txt, err := ctxt.execlncliCommand("listpeers")
+	payments, err := parseBatchCSV(f)
 	if err != nil {
-		return err
-	}
-	var peers lnrpc.ListPeersResponse
-	if err = jsonpb.Unmarshal(bytes.NewReader(txt), &peers); err != nil {
-		return err
+		return fmt.Errorf("%s: %v", filepath.Base(path), err)
 	}
 
-	s.peers = nil
-
-	for _, p := range peers.Peers {
-		np := lncliPeer{*p, ""}
-		s.peers = append(s.peers, &np)
-		go func() {
-			err := np.updateNodeAlias(ctxt, s)
-			if err != nil {
-				logError(err.Error())
-			}
-			refreshView()
-		}()
+	batch := lncliBatchContainer{path: path, payments: payments, targetConf: targetConf, satPerByte: satPerByte, label: label}
+	for _, p := range payments {
+		batch.total += p.Amount
+		if len(p.Error) > 0 {
+			batch.invalid++
+		}
 	}
 
-	ctxt.views[peerListViewt].getGrid().items = s.peers
+	if batch.invalid == 0 {
+		batch.estimate, err = s.estimateOnChainFee(ctxt, batch.addrToAmount(), targetConf, satPerByte)
+		if err != nil {
+			batch.estimateErr = err.Error()
+		}
+	}
 
+	// the batch being sent is kept until its outcome is known
+	var sending bool
+	s.store.update(func(st *lncliState) {
+		if sending = st.batch.sending; !sending {
+			st.batch = batch
+		}
+	})
+	if sending {
+		return errors.New("batch being sent")
+	}
 	return nil
 }
 
-func (s *lncliStatus) payInvoice(ctxt *lnclicursesContext, payReq string, amt int, feeLimit int, feeLimitPerc int, force bool) (string, error) {
-	var args []string
-	args = nil
+// sendBatch estimates the fee of the loaded batch and sends it once
+// confirmed, in the background.
+func (s *lncliStatus) sendBatch(ctxt *lnclicursesContext) {
+	batch := s.state().batch
+	req := batch.sendManyRequest()
 
-	args = append(args, getLncliExec())
-	args = append(args, ctxt.getlncliArgs()...)
-	args = append(args, "payinvoice")
+	s.store.background(func() {
+		e, err := s.estimateOnChainFee(ctxt, req.AddrToAmount, req.TargetConf, req.SatPerByte)
+		if err != nil {
+			logError(err.Error())
+			runInGui(func() { displayMessage("Error: "+err.Error(), nil) })
+			return
+		}
 
-	if len(payReq) > 0 {
-		args = append(args, "--pay_req")
-		args = append(args, payReq)
-	}
+		send := func() {
+			s.store.background(func() {
+				msg := s.sendBatchNow(ctxt, req)
+				runInGui(func() { displayMessage(msg, nil) })
+			})
+		}
 
-	if amt > 0 {
-		args = append(args, "--amt")
-		args = append(args, strconv.Itoa(amt))
-	}
+		runInGui(func() {
+			displayOnChainConfirmation("Confirm batch payment", newBatchConfirmContainer(&batch, req, e), send)
+		})
+	})
+}
 
-	if feeLimit > 0 {
-		args = append(args, "--fee_limit")
-		args = append(args, strconv.Itoa(feeLimit))
+// sendBatchNow sends the batch in a single transaction and describes its
+// outcome, the batch being marked as sent. The batch is flagged as sending
+// meanwhile, refusing to send it again or to replace it.
+func (s *lncliStatus) sendBatchNow(ctxt *lnclicursesContext, req *lnrpc.SendManyRequest) string {
+	var err error
+	s.store.update(func(st *lncliState) {
+		if err = st.batch.checkSendable(); err == nil {
+			st.batch.sending = true
+		}
+	})
+	if err != nil {
+		return "Batch not sent\n" + err.Error()
+	}
+	refreshView()
+
+	txid, err := ctxt.backend.sendMany(req)
+
+	s.store.update(func(st *lncliState) {
+		st.batch.sending = false
+		if err == nil {
+			st.batch.txid = txid
+		}
+	})
+
+	if err != nil {
+		logError(err.Error())
+		return "Batch send failed\n" + err.Error()
+	}
+
+	ctxt.scheduler.refreshSource("walletTransactions")
+	ctxt.scheduler.refreshSource(headerRefreshKey)
+
+	return "Batch sent\nTxid: " + txid
+}
+
+// utxoLeaseID identifies the outputs leased by lncli-curses.
+var utxoLeaseID = sha256.Sum256([]byte("lncli-curses"))
+
+// lncliUtxo is an unspent wallet output. The leased outputs aren't listed as
+// unspent by lnd, they keep their last known address and confirmations.
+type lncliUtxo struct {
+	Outpoint      string
+	Address       string
+	AddressType   string
+	AmountSat     int64
+	Confirmations int64
+	LeasedUntil   string
+	outpoint      *lnrpc.OutPoint
+	leaseID       []byte
+}
+
+var utxoAddressTypes = map[lnrpc.AddressType]string{
+	lnrpc.AddressType_WITNESS_PUBKEY_HASH:        "p2wkh",
+	lnrpc.AddressType_NESTED_PUBKEY_HASH:         "np2wkh",
+	lnrpc.AddressType_UNUSED_WITNESS_PUBKEY_HASH: "p2wkh",
+	lnrpc.AddressType_UNUSED_NESTED_PUBKEY_HASH:  "np2wkh",
+	lnrpc.AddressType_TAPROOT_PUBKEY:             "p2tr",
+	lnrpc.AddressType_UNUSED_TAPROOT_PUBKEY:      "p2tr",
+}
+
+// utxoOutpoints returns the outpoints of the outputs, as spent by an
+// on-chain payment or a channel funding.
+func utxoOutpoints(utxos []*lncliUtxo) []*lnrpc.OutPoint {
+	var outpoints []*lnrpc.OutPoint
+	for _, u := range utxos {
+		outpoints = append(outpoints, u.outpoint)
+	}
+	return outpoints
+}
+
+func sumUtxos(utxos []*lncliUtxo) int64 {
+	var total int64
+	for _, u := range utxos {
+		total += u.AmountSat
+	}
+	return total
+}
+
+// updateUtxos lists the unspent and leased wallet outputs.
+func (s *lncliStatus) updateUtxos(ctxt *lnclicursesContext) error {
+	unspent, err := ctxt.backend.listUnspent()
+	if err != nil {
+		return err
+	}
+	leases, err := ctxt.backend.listLeases()
+	if err != nil {
+		return err
+	}
+
+	previous := make(map[string]*lncliUtxo)
+	for _, u := range s.state().utxos {
+		previous[u.Outpoint] = u
+	}
+
+	var utxos []*lncliUtxo
+
+	for _, u := range unspent.Utxos {
+		nu := &lncliUtxo{
+			Outpoint:      outPointString(u.Outpoint),
+			Address:       u.Address,
+			AddressType:   utxoAddressTypes[u.AddressType],
+			AmountSat:     u.AmountSat,
+			Confirmations: u.Confirmations,
+			outpoint:      u.Outpoint,
+		}
+		utxos = append(utxos, nu)
 	}
 
-	if feeLimitPerc > 0 {
-		args = append(args, "--fee_limit_percent")
-		args = append(args, strconv.Itoa(feeLimitPerc))
+	for _, l := range leases.LockedUtxos {
+		nu := &lncliUtxo{
+			Outpoint:    outPointString(l.Outpoint),
+			AmountSat:   int64(l.Value),
+			LeasedUntil: time.Unix(int64(l.Expiration), 0).Format("02-01-06 15:04:05"),
+			outpoint:    l.Outpoint,
+			leaseID:     l.Id,
+		}
+		if p, ok := previous[nu.Outpoint]; ok {
+			nu.Address = p.Address
+			nu.AddressType = p.AddressType
+			nu.Confirmations = p.Confirmations
+		}
+		utxos = append(utxos, nu)
 	}
 
-	if force {
-		args = append(args, "--force")
+	s.store.update(func(st *lncliState) { st.utxos = utxos })
+	return nil
+}
+
+// leaseUtxos leases the outputs for seconds, keeping them from being spent
+// by lnd until released or expired.
+func (s *lncliStatus) leaseUtxos(ctxt *lnclicursesContext, utxos []*lncliUtxo, seconds uint64) error {
+	defer ctxt.scheduler.refreshSource("utxos")
+
+	for _, u := range utxos {
+		if len(u.leaseID) > 0 {
+			continue
+		}
+		req := &walletrpc.LeaseOutputRequest{Id: utxoLeaseID[:], Outpoint: u.outpoint, ExpirationSeconds: seconds}
+		if _, err := ctxt.backend.leaseOutput(req); err != nil {
+			return fmt.Errorf("%s: %v", u.Outpoint, err)
+		}
 	}
+	return nil
+}
 
-	ctxt.cliMutex.Lock()
+// releaseUtxos releases the outputs leased by lncli-curses. The outputs
+// leased by lnd or another application are kept, returned in the error.
+func (s *lncliStatus) releaseUtxos(ctxt *lnclicursesContext, utxos []*lncliUtxo) error {
+	defer ctxt.scheduler.refreshSource("utxos")
 
-	ex, _, err := expect.Spawn(strings.Join(args, " "), -1)
+	released := 0
+	var kept []string
+	for _, u := range utxos {
+		if len(u.leaseID) == 0 {
+			continue
+		}
+		if !bytes.Equal(u.leaseID, utxoLeaseID[:]) {
+			kept = append(kept, u.Outpoint)
+			continue
+		}
+		if err := ctxt.backend.releaseOutput(&walletrpc.ReleaseOutputRequest{Id: u.leaseID, Outpoint: u.outpoint}); err != nil {
+			return fmt.Errorf("%s: %v", u.Outpoint, err)
+		}
+		released++
+	}
+	if len(kept) > 0 {
+		return fmt.Errorf("not leased by lncli-curses, kept: %s", strings.Join(kept, ", "))
+	}
+	if released == 0 {
+		return errors.New("no leased output selected")
+	}
+	return nil
+}
 
+func (s *lncliStatus) updateWallletTransactionsList(ctxt *lnclicursesContext) error {
+	trans, err := ctxt.backend.listChainTxns()
 	if err != nil {
-		ctxt.cliMutex.Unlock()
-		return "", err
+		return err
+	}
+
+	var transactions []*lnrpc.Transaction
+
+	for _, c := range trans.Transactions {
+		transactions = append(transactions, c)
 	}
 
-	s1, _, err := ex.Expect(regexp.MustCompile("\\/no\\):"), 10*time.Second)
+	s.store.update(func(st *lncliState) { st.walletTransactions = transactions })
+	return nil
+}
 
+func (s *lncliStatus) updateInvoiceList(ctxt *lnclicursesContext) error {
+
+	page := s.state().invoices.page
+
+	resp, err := ctxt.backend.listInvoices(&lnrpc.ListInvoiceRequest{
+		Reversed:       true,
+		NumMaxInvoices: listPageSize,
+		IndexOffset:    page.start,
+		PendingOnly:    page.pendingOnly,
+	})
 	if err != nil {
-		logError(err.Error())
-		ctxt.cliMutex.Unlock()
-		return "", err
+		return err
 	}
 
-	go displayMessage(s1, func(valid bool) {
-		if !valid {
-			ex.Close()
-			ctxt.cliMutex.Unlock()
-			return
+	var invoices []*lncliInvoice
+
+	for _, c := range resp.Invoices {
+		nc := lncliInvoice{*c}
+		invoices = append(invoices, &nc)
+	}
+
+	s.store.update(func(st *lncliState) {
+		if st.invoices.page == page {
+			st.invoices = lncliInvoicesContainer{page.setBounds(resp.FirstIndexOffset, resp.LastIndexOffset), invoices}
 		}
+	})
 
-		ex.Send("yes\n")
+	return nil
+}
 
-		out, _, err := ex.Expect(regexp.MustCompile("(confirmed)|(})"), 120*time.Second)
+// pageInvoices moves the invoice list to the older or to the newer invoices.
+func (s *lncliStatus) pageInvoices(ctxt *lnclicursesContext, older bool) error {
+	page := s.state().invoices.page
 
+	next, ok, err := page.turn(older, func(offset uint64, reversed bool) (int, uint64, error) {
+		resp, err := ctxt.backend.listInvoices(&lnrpc.ListInvoiceRequest{
+			Reversed:       reversed,
+			NumMaxInvoices: listPageSize,
+			IndexOffset:    offset,
+			PendingOnly:    page.pendingOnly,
+		})
 		if err != nil {
-			ex.Close()
-			ctxt.cliMutex.Unlock()
-			logError(err.Error())
-			return
+			return 0, 0, err
 		}
+		return len(resp.Invoices), resp.LastIndexOffset, nil
+	})
+	if err != nil || !ok {
+		return err
+	}
+
+	s.setInvoicePage(page, next)
+	return s.updateInvoiceList(ctxt)
+}
 
-		re, _ := regexp.Compile("\"payment_error\": \".+\"")
+// togglePendingInvoices switches between all the invoices and the pending
+// ones, back to the most recent.
+func (s *lncliStatus) togglePendingInvoices(ctxt *lnclicursesContext) error {
+	page := s.state().invoices.page
+	s.setInvoicePage(page, listPage{total: page.total, pendingOnly: !page.pendingOnly})
+	return s.updateInvoiceList(ctxt)
+}
 
-		errFound := re.FindString(out)
+func (s *lncliStatus) setInvoicePage(page listPage, next listPage) {
+	s.store.update(func(st *lncliState) {
+		if st.invoices.page == page {
+			st.invoices.page = next
+		}
+	})
+}
 
-		ctxt.cliMutex.Unlock()
-		ex.Close()
+func (s *lncliStatus) updatePaymentList(ctxt *lnclicursesContext) error {
 
-		var finalMsg string
+	page := s.state().payments.page
 
-		if len(errFound) > 0 {
-			finalMsg = strings.Replace(strings.Replace(errFound, "\"", "", -1), "payment_error: ", "", -1)
+	resp, err := ctxt.backend.listPayments(&lnrpc.ListPaymentsRequest{
+		Reversed:    true,
+		MaxPayments: listPageSize,
+		IndexOffset: page.start,
+	})
+	if err != nil {
+		return err
+	}
+
+	var payments []*lncliPayment
+
+	for _, c := range resp.Payments {
+		payments = append(payments, &lncliPayment{Payment: *c, HopCount: len(c.Path)})
+	}
+
+	s.store.update(func(st *lncliState) {
+		if st.payments.page == page {
+			st.payments = lncliPaymentsContainer{page.setBounds(resp.FirstIndexOffset, resp.LastIndexOffset), payments}
+		}
+	})
+
+	if len(payments) == 0 {
+		return nil
+	}
+
+	s.store.background(func() {
+		resolved := make([]*lncliPayment, len(payments))
+		for i, p := range payments {
+			np := *p
+			np.updatePathAliases(ctxt, s)
+			resolved[i] = &np
+		}
+		s.store.update(func(st *lncliState) {
+			if len(st.payments.payments) == len(payments) && st.payments.payments[0] == payments[0] {
+				st.payments.payments = resolved
+			}
+		})
+		refreshView()
+	})
+
+	return nil
+}
+
+// pagePayments moves the payment list to the older or to the newer payments.
+func (s *lncliStatus) pagePayments(ctxt *lnclicursesContext, older bool) error {
+	page := s.state().payments.page
+
+	next, ok, err := page.turn(older, func(offset uint64, reversed bool) (int, uint64, error) {
+		resp, err := ctxt.backend.listPayments(&lnrpc.ListPaymentsRequest{
+			Reversed:    reversed,
+			MaxPayments: listPageSize,
+			IndexOffset: offset,
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		return len(resp.Payments), resp.LastIndexOffset, nil
+	})
+	if err != nil || !ok {
+		return err
+	}
+
+	s.store.update(func(st *lncliState) {
+		if st.payments.page == page {
+			st.payments.page = next
+		}
+	})
+	return s.updatePaymentList(ctxt)
+}
+
+func (s *lncliStatus) getForwardingTimeRange() (int64, int64) {
+	forwards := s.state().forwards
+	end := forwards.endTime
+	if end == 0 {
+		end = time.Now().Unix()
+	}
+	start := forwards.startTime
+	if start == 0 {
+		start = end - int64(getConfigForwardingDays())*24*3600
+	}
+	return start, end
+}
+
+// fetchForwards queries the forwarding events from start to end following
+// the ones of prev, one page after the other. The query restarts from start
+// when prev doesn't reach it, the events before start are dropped.
+func fetchForwards(ctxt *lnclicursesContext, prev lncliForwardsFetch, start int64, end int64) (lncliForwardsFetch, error) {
+	next := prev
+	if prev.start == 0 || start < prev.start || start > prev.end {
+		next = lncliForwardsFetch{start: start}
+	}
+
+	var events []*lncliForwardingEvent
+	for _, e := range next.events {
+		if int64(e.Timestamp) >= start {
+			events = append(events, e)
+		}
+	}
+
+	for {
+		resp, err := ctxt.backend.forwardingHistory(&lnrpc.ForwardingHistoryRequest{
+			StartTime:    uint64(next.start),
+			EndTime:      uint64(end),
+			IndexOffset:  next.offset,
+			NumMaxEvents: forwardingHistoryPageSize,
+		})
+		if err != nil {
+			return prev, err
+		}
+		for _, e := range resp.ForwardingEvents {
+			events = append(events, &lncliForwardingEvent{*e, "", ""})
+		}
+		next.offset += uint32(len(resp.ForwardingEvents))
+		if len(resp.ForwardingEvents) < int(forwardingHistoryPageSize) {
+			break
+		}
+	}
+
+	next.events = events
+	if end > next.end {
+		next.end = end
+	}
+	return next, nil
+}
+
+// forwardsBetween returns the events of fetched between start and end.
+func forwardsBetween(fetched lncliForwardsFetch, start int64, end int64) []*lncliForwardingEvent {
+	var events []*lncliForwardingEvent
+	for _, e := range fetched.events {
+		if int64(e.Timestamp) >= start && int64(e.Timestamp) < end {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// updateForwardingHistory lists the forwarding events of the selected range,
+// querying only the ones following the events already received. The day,
+// week and month fee totals are summed over the last 30 days, queried apart
+// when the range doesn't cover them.
+func (s *lncliStatus) updateForwardingHistory(ctxt *lnclicursesContext) error {
+
+	start, end := s.getForwardingTimeRange()
+	prev := s.state().forwards
+
+	now := time.Now().Unix()
+	untilNow := prev.endTime == 0
+	coversMonth := untilNow && end-start >= 30*24*3600
+
+	var forwards lncliForwardingHistoryContainer
+	var err error
+
+	if !coversMonth {
+		if forwards.recent, err = fetchForwards(ctxt, prev.recent, now-30*24*3600, now+1); err != nil {
+			return err
+		}
+	}
+
+	if forwards.fetched, err = fetchForwards(ctxt, prev.fetched, start, end); err != nil {
+		return err
+	}
+
+	recent := forwards.recent.events
+	if coversMonth {
+		recent = forwards.fetched.events
+	}
+
+	chans, err := ctxt.backend.listChannels()
+	if err != nil {
+		return err
+	}
+
+	peers := make(map[uint64]string)
+	for _, c := range chans.Channels {
+		peers[c.ChanId] = c.RemotePubkey
+	}
+
+	forwards.rangeStart, forwards.rangeEnd = start, end
+	forwards.events = forwardsBetween(forwards.fetched, start, end)
+
+	for _, e := range forwards.events {
+		forwards.feeTotal += e.FeeMsat
+	}
+
+	for _, e := range recent {
+		age := now - int64(e.Timestamp)
+		if age < 30*24*3600 {
+			forwards.feeMonth += e.FeeMsat
+		}
+		if age < 7*24*3600 {
+			forwards.feeWeek += e.FeeMsat
+		}
+		if age < 24*3600 {
+			forwards.feeDay += e.FeeMsat
+		}
+	}
+
+	s.store.update(func(st *lncliState) {
+		next := forwards
+		next.startTime, next.endTime = st.forwards.startTime, st.forwards.endTime
+		st.forwards = next
+	})
+
+	s.store.background(func() {
+		resolved := make(map[*lncliForwardingEvent]*lncliForwardingEvent)
+		for _, e := range forwards.fetched.events {
+			if len(e.AliasIn) == 0 {
+				ne := *e
+				ne.AliasIn = s.getForwardingAlias(ctxt, peers, e.ChanIdIn)
+				ne.AliasOut = s.getForwardingAlias(ctxt, peers, e.ChanIdOut)
+				resolved[e] = &ne
+			}
+		}
+		if len(resolved) == 0 {
+			return
+		}
+		s.store.update(func(st *lncliState) {
+			st.forwards.events = replaceForwards(st.forwards.events, resolved)
+			st.forwards.fetched.events = replaceForwards(st.forwards.fetched.events, resolved)
+		})
+		refreshView()
+	})
+
+	return nil
+}
+
+// replaceForwards returns a copy of events with the ones found in replaced
+// substituted.
+func replaceForwards(events []*lncliForwardingEvent, replaced map[*lncliForwardingEvent]*lncliForwardingEvent) []*lncliForwardingEvent {
+	next := make([]*lncliForwardingEvent, len(events))
+	for i, e := range events {
+		if r, ok := replaced[e]; ok {
+			next[i] = r
 		} else {
-			finalMsg = "Success"
+			next[i] = e
+		}
+	}
+	return next
+}
+
+// getForwardingAlias returns the alias of the channel's peer, the channel id
+// for a closed channel or an unknown node.
+func (s *lncliStatus) getForwardingAlias(ctxt *lnclicursesContext, peers map[uint64]string, chanID uint64) string {
+	if pubkey, ok := peers[chanID]; ok {
+		if ni, err := s.getNodeInfo(ctxt, pubkey); err == nil && len(ni.Node.Alias) > 0 {
+			return ni.Node.Alias
+		}
+	}
+	return shortChannelID(chanID)
+}
+
+func (s *lncliStatus) updateChannelList(ctxt *lnclicursesContext) error {
+
+	chans, err := ctxt.backend.listChannels()
+	if err != nil {
+		return err
+	}
+
+	// The channels are still listed, without their fees, when the fee report
+	// fails.
+	channelFees := make(map[string]*lnrpc.ChannelFeeReport)
+	if fees, err := ctxt.backend.feeReport(); err != nil {
+		logError(err.Error())
+	} else {
+		for _, f := range fees.ChannelFees {
+			channelFees[f.ChannelPoint] = f
+		}
+	}
+
+	var channels []*lncliChannel
+
+	for _, c := range chans.Channels {
+		nc := lncliChannel{Channel: *c}
+		if f, ok := channelFees[c.ChannelPoint]; ok {
+			nc.BaseFeeMsat = f.BaseFeeMsat
+			nc.FeeRatePpm = f.FeePerMil
 		}
+		channels = append(channels, &nc)
+	}
+
+	s.store.update(func(st *lncliState) { st.channels = channels })
+
+	if len(channels) == 0 {
+		return nil
+	}
 
-		displayMessage(finalMsg, func(valid bool) {
-			updateData()
+	s.store.background(func() {
+		resolved := make([]*lncliChannel, len(channels))
+		for i, c := range channels {
+			nc := *c
+			manageError(nc.updateNodeAlias(ctxt, s))
+			manageError(nc.updateNodeUpdate(ctxt, s))
+			resolved[i] = &nc
+		}
+		s.store.update(func(st *lncliState) {
+			if len(st.channels) == len(channels) && st.channels[0] == channels[0] {
+				st.channels = resolved
+			}
 		})
+		refreshView()
 	})
 
-	return "", nil
+	return nil
 }
 
-func (s *lncliStatus) openChannel(ctxt *lnclicursesContext, nk string, cct string, lamnt int, pamnt int, pri bool, blk bool, mcf int, cftgt int, spb int, minht int, remcsv int) (string, error) {
+// getChannelPolicies returns the routing policies of our side and of the
+// remote side of the channel, as announced in the graph. Either can be nil
+// until announced.
+func (s *lncliStatus) getChannelPolicies(ctxt *lnclicursesContext, channel *lncliChannel) (*lnrpc.RoutingPolicy, *lnrpc.RoutingPolicy, error) {
+	edge, err := ctxt.backend.getChanInfo(channel.ChanId)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	var args []string
+	if edge.Node1Pub == channel.RemotePubkey {
+		return edge.GetNode2Policy(), edge.GetNode1Policy(), nil
+	}
+	return edge.GetNode1Policy(), edge.GetNode2Policy(), nil
+}
 
-	if len(nk) > 0 {
-		args = append(args, "--node_key")
-		args = append(args, nk)
+// getChannelPolicy returns the routing policy of our side of the channel, as
+// announced in the graph.
+func (s *lncliStatus) getChannelPolicy(ctxt *lnclicursesContext, channel *lncliChannel) (*lnrpc.RoutingPolicy, error) {
+	policy, _, err := s.getChannelPolicies(ctxt, channel)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(cct) > 0 {
-		args = append(args, "--connect")
-		args = append(args, cct)
+	if policy == nil {
+		return nil, fmt.Errorf("no routing policy for channel %s", channel.ChannelPoint)
 	}
 
-	if lamnt > 0 {
-		args = append(args, "--local_amt")
-		args = append(args, strconv.Itoa(lamnt))
+	return policy, nil
+}
+
+// updateChannelPolicy sets the routing policy of the channel, or of all the
+// channels when channel is nil. A zero max htlc keeps the channels' own, as
+// does a zero min htlc. lnd requires a time lock delta, a zero one updates
+// the channels one after the other with the delta they announced.
+func (s *lncliStatus) updateChannelPolicy(ctxt *lnclicursesContext, channel *lncliChannel, baseFee int, feeRatePpm int, timeLockDelta int, minHtlc int, maxHtlc int) error {
+	req := &lnrpc.PolicyUpdateRequest{
+		BaseFeeMsat:   int64(baseFee),
+		FeeRatePpm:    uint32(feeRatePpm),
+		TimeLockDelta: uint32(timeLockDelta),
+		MaxHtlcMsat:   uint64(maxHtlc),
 	}
 
-	if pamnt > 0 {
-		args = append(args, "--push_amt")
-		args = append(args, strconv.Itoa(pamnt))
+	if minHtlc > 0 {
+		req.MinHtlcMsat = uint64(minHtlc)
+		req.MinHtlcMsatSpecified = true
 	}
 
-	if blk {
-		args = append(args, "--block")
+	if channel == nil && timeLockDelta == 0 {
+		return s.updateChannelPolicies(ctxt, req)
 	}
 
-	if pri {
-		args = append(args, "--private")
+	if channel == nil {
+		req.Scope = &lnrpc.PolicyUpdateRequest_Global{Global: true}
+	} else {
+		cp, err := parseChannelPoint(channel.ChannelPoint)
+		if err != nil {
+			return err
+		}
+		req.Scope = &lnrpc.PolicyUpdateRequest_ChanPoint{ChanPoint: cp}
 	}
 
-	if cftgt > 0 {
-		args = append(args, "--conf_target")
-		args = append(args, strconv.Itoa(cftgt))
+	return ctxt.backend.updateChannelPolicy(req)
+}
+
+// updateChannelPolicies sends the policy update to each channel with the time
+// lock delta of its announced policy.
+func (s *lncliStatus) updateChannelPolicies(ctxt *lnclicursesContext, req *lnrpc.PolicyUpdateRequest) error {
+	var failed []string
+	for _, c := range s.state().channels {
+		policy, err := s.getChannelPolicy(ctxt, c)
+		if err == nil {
+			var cp *lnrpc.ChannelPoint
+			if cp, err = parseChannelPoint(c.ChannelPoint); err == nil {
+				creq := *req
+				creq.TimeLockDelta = policy.TimeLockDelta
+				creq.Scope = &lnrpc.PolicyUpdateRequest_ChanPoint{ChanPoint: cp}
+				err = ctxt.backend.updateChannelPolicy(&creq)
+			}
+		}
+		if err != nil {
+			failed = append(failed, c.ChannelPoint+": "+err.Error())
+		}
 	}
 
-	if spb > 0 {
-		args = append(args, "--sat_per_byte")
-		args = append(args, strconv.Itoa(spb))
+	if len(failed) > 0 {
+		return fmt.Errorf("policy update failed for %s", strings.Join(failed, ", "))
 	}
+	return nil
+}
+
+func (s *lncliStatus) updatePendingChannelList(ctxt *lnclicursesContext) error {
 
-	if minht > 0 {
-		args = append(args, "--min_htlc_msat")
-		args = append(args, strconv.Itoa(minht))
+	chans, err := ctxt.backend.pendingChannels()
+	if err != nil {
+		return err
 	}
 
-	if remcsv > 0 {
-		args = append(args, "--remote_csv_delay")
-		args = append(args, strconv.Itoa(remcsv))
+	var pending lncliPendingChannelsContainer
+	pending.totalLimbo = chans.GetTotalLimboBalance()
+
+	for _, c := range chans.PendingClosingChannels {
+		nc := makeNewPendingChannel(c.GetChannel(), closingChannel)
+		nc.closingTxid = c.GetClosingTxid()
+		pending.pendingChannels = append(pending.pendingChannels, nc)
 	}
 
-	if mcf > 0 {
-		args = append(args, "--min_confs")
-		args = append(args, strconv.Itoa(mcf))
+	for _, c := range chans.PendingForceClosingChannels {
+		nc := makeNewPendingChannel(c.GetChannel(), forceClosingChannel)
+		nc.closingTxid = c.GetClosingTxid()
+		nc.blocksTilMaturity = c.GetBlocksTilMaturity()
+		nc.limboBalance = c.GetLimboBalance()
+		nc.maturityHeight = c.GetMaturityHeight()
+		nc.pendingHtlcs = c.GetPendingHtlcs()
+		nc.recoveredBalance = c.GetRecoveredBalance()
+		pending.pendingChannels = append(pending.pendingChannels, nc)
 	}
 
-	cm := fmt.Sprintf("openchannel %s", strings.Join(args, " "))
-	out, err := ctxt.execlncliCommand(cm)
+	for _, c := range chans.PendingOpenChannels {
+		nc := makeNewPendingChannel(c.GetChannel(), openChannel)
+		nc.commitFee = c.GetCommitFee()
+		nc.commitWeight = c.GetCommitWeight()
+		nc.confirmationHeight = c.GetConfirmationHeight()
+		nc.feePerKw = c.GetFeePerKw()
+		pending.pendingChannels = append(pending.pendingChannels, nc)
+	}
+
+	for _, c := range chans.WaitingCloseChannels {
+		nc := makeNewPendingChannel(c.GetChannel(), waitingCloseChannel)
+		nc.limboBalance = c.GetLimboBalance()
+		pending.pendingChannels = append(pending.pendingChannels, nc)
+	}
+
+	s.store.update(func(st *lncliState) { st.pendingchannels = pending })
+
+	channels := pending.pendingChannels
+
+	if len(channels) == 0 {
+		return nil
+	}
+
+	s.store.background(func() {
+		resolved := make([]*lncliPendingChannel, len(channels))
+		for i, c := range channels {
+			nc := *c
+			manageError(nc.updateNodeAlias(ctxt, s))
+			resolved[i] = &nc
+		}
+		s.store.update(func(st *lncliState) {
+			if len(st.pendingchannels.pendingChannels) == len(channels) && st.pendingchannels.pendingChannels[0] == channels[0] {
+				st.pendingchannels.pendingChannels = resolved
+			}
+		})
+		refreshView()
+	})
+
+	return nil
+}
+
+func (s *lncliStatus) closeChannel(ctxt *lnclicursesContext, channel *lncliChannel, force bool) (string, error) {
+	return ctxt.backend.closeChannel(channel.ChannelPoint, force, 0, 0)
+}
+
+const (
+	bulkClosePending = "pending"
+	bulkCloseClosing = "closing"
+	bulkCloseClosed  = "closed"
+	bulkCloseFailed  = "failed"
+)
+
+// lncliBulkClose is a channel of a bulk close, force closed or closed
+// cooperatively, Status following its close.
+type lncliBulkClose struct {
+	ChannelPoint string
+	NodeAlias    string
+	LocalBalance int64
+	Active       bool
+	Force        bool
+	Status       string
+	ClosingTxid  string
+	Error        string
+}
+
+// Result returns the closing txid of the channel, or the error closing it.
+func (c *lncliBulkClose) Result() string {
+	if len(c.Error) > 0 {
+		return c.Error
+	}
+	return c.ClosingTxid
+}
+
+// isClosable tells if the channel is still to be closed, a failed close
+// being retried.
+func (c *lncliBulkClose) isClosable() bool {
+	return c.Status == bulkClosePending || c.Status == bulkCloseFailed
+}
+
+// getMode describes how the channel is closed.
+func (c *lncliBulkClose) getMode() string {
+	if c.Force {
+		return "force"
+	}
+	return "cooperative"
+}
+
+// lncliBulkCloseContainer holds the channels of a bulk close, closed one
+// after the other with a shared fee target.
+type lncliBulkCloseContainer struct {
+	channels   []*lncliBulkClose
+	targetConf int32
+	satPerByte int64
+	running    bool
+}
+
+func (c *lncliBulkCloseContainer) getInfo() string {
+	if len(c.channels) == 0 {
+		return "No channel, mark channels and Alt+C in the channel view"
+	}
+
+	var local int64
+	var force int
+	count := make(map[string]int)
+	for _, ch := range c.channels {
+		local += ch.LocalBalance
+		if ch.Force {
+			force++
+		}
+		count[ch.Status]++
+	}
+
+	info := fmt.Sprintf("%d channel(s), %s local, %d force", len(c.channels), formatAmountUnit(local, satUnit), force)
+	if count[bulkClosePending] < len(c.channels) {
+		info += fmt.Sprintf(", closed %d/%d", count[bulkCloseClosed], len(c.channels))
+		if count[bulkCloseFailed] > 0 {
+			info += fmt.Sprintf(", %d failed", count[bulkCloseFailed])
+		}
+	}
+	return info
+}
+
+// getPending returns the channels not closed yet.
+func (c *lncliBulkCloseContainer) getPending() []*lncliBulkClose {
+	var pending []*lncliBulkClose
+	for _, ch := range c.channels {
+		if ch.isClosable() {
+			pending = append(pending, ch)
+		}
+	}
+	return pending
+}
+
+// prepareBulkClose replaces the bulk close with the channels, the inactive
+// ones being force closed by default as their peer can't cooperate.
+func (s *lncliStatus) prepareBulkClose(channels []*lncliChannel) error {
+	var closes []*lncliBulkClose
+	for _, c := range channels {
+		closes = append(closes, &lncliBulkClose{
+			ChannelPoint: c.ChannelPoint,
+			NodeAlias:    c.NodeAlias,
+			LocalBalance: c.LocalBalance,
+			Active:       c.Active,
+			Force:        !c.Active,
+			Status:       bulkClosePending,
+		})
+	}
+
+	var running bool
+	s.store.update(func(st *lncliState) {
+		if running = st.bulkClose.running; !running {
+			st.bulkClose = lncliBulkCloseContainer{channels: closes}
+		}
+	})
+	if running {
+		return errors.New("bulk close in progress")
+	}
+	return nil
+}
+
+// updateBulkClose applies fn to a copy of the bulk close channel.
+func (s *lncliStatus) updateBulkClose(channelPoint string, fn func(c *lncliBulkClose)) {
+	s.store.update(func(st *lncliState) {
+		channels := make([]*lncliBulkClose, len(st.bulkClose.channels))
+		for i, c := range st.bulkClose.channels {
+			channels[i] = c
+			if c.ChannelPoint == channelPoint {
+				nc := *c
+				fn(&nc)
+				channels[i] = &nc
+			}
+		}
+		st.bulkClose.channels = channels
+	})
+}
+
+// toggleBulkCloseForce switches the channels not closed yet between a force
+// and a cooperative close, refused while they're being closed.
+func (s *lncliStatus) toggleBulkCloseForce(channelPoints []string) error {
+	toggled := make(map[string]bool)
+	for _, cp := range channelPoints {
+		toggled[cp] = true
+	}
+
+	var running bool
+	s.store.update(func(st *lncliState) {
+		if running = st.bulkClose.running; running {
+			return
+		}
+		channels := make([]*lncliBulkClose, len(st.bulkClose.channels))
+		for i, c := range st.bulkClose.channels {
+			channels[i] = c
+			if toggled[c.ChannelPoint] && c.isClosable() {
+				nc := *c
+				nc.Force = !nc.Force
+				channels[i] = &nc
+			}
+		}
+		st.bulkClose.channels = channels
+	})
+	if running {
+		return errors.New("bulk close in progress")
+	}
+	return nil
+}
 
+// closeChannels closes the pending channels of the bulk close one after the
+// other, the fee target applying to the cooperative closes, lnd picking the
+// fee of the force closes. Each channel is refreshed with its closing txid or
+// error as it's closed.
+func (s *lncliStatus) closeChannels(ctxt *lnclicursesContext, targetConf int32, satPerByte int64) error {
+	var pending []*lncliBulkClose
+	var err error
+	s.store.update(func(st *lncliState) {
+		if st.bulkClose.running {
+			err = errors.New("bulk close in progress")
+			return
+		}
+		pending = st.bulkClose.getPending()
+		if len(pending) == 0 {
+			err = errors.New("no channel to close")
+			return
+		}
+		st.bulkClose.running = true
+		st.bulkClose.targetConf = targetConf
+		st.bulkClose.satPerByte = satPerByte
+	})
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	fid, err := getAttributeStr(out, "funding_txid")
+	defer ctxt.scheduler.refreshSource("pendingChannels")
+	defer ctxt.scheduler.refreshSource("channels")
+	defer s.store.update(func(st *lncliState) { st.bulkClose.running = false })
+
+	for _, c := range pending {
+		s.updateBulkClose(c.ChannelPoint, func(c *lncliBulkClose) {
+			c.Status = bulkCloseClosing
+			c.Error = ""
+		})
+		refreshView()
+
+		conf, rate := targetConf, satPerByte
+		if c.Force {
+			conf, rate = 0, 0
+		}
+		txid, err := ctxt.backend.closeChannel(c.ChannelPoint, c.Force, conf, rate)
+
+		s.updateBulkClose(c.ChannelPoint, func(c *lncliBulkClose) {
+			if err != nil {
+				c.Status = bulkCloseFailed
+				c.Error = err.Error()
+				return
+			}
+			c.Status = bulkCloseClosed
+			c.ClosingTxid = txid
+		})
+		if err != nil {
+			logError(fmt.Sprintf("closing %s: %s", c.ChannelPoint, err.Error()))
+		}
+		refreshView()
+	}
 
+	return nil
+}
+
+func (s *lncliStatus) connectToPeer(ctxt *lnclicursesContext, pubkey string, host string, port int) (string, error) {
+	err := ctxt.backend.connectPeer(pubkey, fmt.Sprintf("%s:%d", host, port))
 	if err != nil {
 		return "", err
 	}
-
-	return fid.(string), nil
+	return "", nil
 }
 
-func getAttributeStr(in []byte, attributeName string) (interface{}, error) {
-	var attrs map[string]interface{}
-	if err := json.Unmarshal(in, &attrs); err != nil {
+func (s *lncliStatus) disconnectPeer(ctxt *lnclicursesContext, peer *lncliPeer) (string, error) {
+	err := ctxt.backend.disconnectPeer(peer.PubKey)
+	if err != nil {
 		return "", err
 	}
-	if val, ok := attrs[attributeName]; ok {
-		return val, nil
+	return "", nil
+}
+
+// disconnectPeers disconnects the peers one after the other, returns the
+// failures.
+func (s *lncliStatus) disconnectPeers(ctxt *lnclicursesContext, peers []*lncliPeer) []string {
+	var failures []string
+	for _, p := range peers {
+		if _, err := s.disconnectPeer(ctxt, p); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", getPeerName(p), err.Error()))
+		}
+	}
+	return failures
+}
+
+// getPeerName returns the alias of the peer, its pub key when unknown.
+func getPeerName(p *lncliPeer) string {
+	if len(p.Alias) > 0 {
+		return p.Alias
 	}
-	return "", errors.New("json attribute not found")
+	return p.PubKey
 }
 
-func (s *lncliStatus) addInvoice(ctxt *lnclicursesContext, amt int, deshash string, expiry int, fallbackaddr string, memo string, preimage string, private bool, receipt string) (string, error) {
+func (s *lncliStatus) updatePeersList(ctxt *lnclicursesContext) error {
+
+	peers, err := ctxt.backend.listPeers()
+	if err != nil {
+		return err
+	}
+
+	var list []*lncliPeer
+
+	for _, p := range peers.Peers {
+		np := lncliPeer{*p, ""}
+		list = append(list, &np)
+	}
+
+	s.store.update(func(st *lncliState) { st.peers = list })
+
+	if len(list) == 0 {
+		return nil
+	}
+
+	s.store.background(func() {
+		resolved := make([]*lncliPeer, len(list))
+		for i, p := range list {
+			np := *p
+			manageError(np.updateNodeAlias(ctxt, s))
+			resolved[i] = &np
+		}
+		s.store.update(func(st *lncliState) {
+			if len(st.peers) == len(list) && st.peers[0] == list[0] {
+				st.peers = resolved
+			}
+		})
+		refreshView()
+	})
+
+	return nil
+}
+
+// lncliPayReq is a decoded payment request, with the aliases of its
+// destination and of the nodes of its route hints.
+type lncliPayReq struct {
+	lnrpc.PayReq
+	DestinationAlias string
+	aliases          map[string]string
+}
+
+func (pr *lncliPayReq) getExpiry() time.Time {
+	return time.Unix(pr.Timestamp+pr.Expiry, 0)
+}
+
+// getWarnings returns what would keep the payment request from being paid
+// for amt sat.
+func (pr *lncliPayReq) getWarnings(amt int64) []string {
+	var warnings []string
+	if time.Now().After(pr.getExpiry()) {
+		warnings = append(warnings, "expired")
+	}
+	if pr.NumSatoshis == 0 && pr.NumMsat == 0 && amt == 0 {
+		warnings = append(warnings, "no amount, one must be given")
+	}
+	return warnings
+}
+
+// getRouteHints formats the route hints one per line, as their hops.
+func (pr *lncliPayReq) getRouteHints() string {
+	var hints []string
+	for _, rh := range pr.RouteHints {
+		var hops []string
+		for _, h := range rh.HopHints {
+			hops = append(hops, context.printer.Sprintf("%s %s base %d mSat, rate %d ppm, time lock delta %d",
+				pr.aliases[h.NodeId], shortChannelID(h.ChanId), h.FeeBaseMsat, h.FeeProportionalMillionths, h.CltvExpiryDelta))
+		}
+		hints = append(hints, strings.Join(hops, " > "))
+	}
+	return strings.Join(hints, "\n")
+}
+
+const (
+	// keysendRecordType is the custom record carrying the preimage of the
+	// payments sent without invoice.
+	keysendRecordType = 5482373484
+	// messageRecordType is the custom record carrying a text message.
+	messageRecordType = 34349334
+	keysendCltvDelta  = 40
+)
+
+// paymentInFlight is a payment sent and not completed yet.
+type paymentInFlight struct {
+	destination string
+	amount      int64
+	started     time.Time
+}
+
+// getNodeAlias returns the alias of the node, or its pubkey when unknown.
+func (s *lncliStatus) getNodeAlias(ctxt *lnclicursesContext, pubkey string) string {
+	if ni, err := s.getNodeInfo(ctxt, pubkey); err == nil && ni.Node != nil && len(ni.Node.Alias) > 0 {
+		return ni.Node.Alias
+	}
+	return pubkey
+}
+
+func (s *lncliStatus) decodePayReq(ctxt *lnclicursesContext, payReq string) (*lncliPayReq, error) {
+	pr, err := ctxt.backend.decodePayReq(payReq)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make(map[string]string)
+	for _, rh := range pr.RouteHints {
+		for _, h := range rh.HopHints {
+			aliases[h.NodeId] = s.getNodeAlias(ctxt, h.NodeId)
+		}
+	}
+
+	return &lncliPayReq{*pr, s.getNodeAlias(ctxt, pr.Destination), aliases}, nil
+}
+
+// payInvoice decodes payReq and has the payment confirmed, unless forced,
+// before sending it in the background. The outcome is displayed once the
+// payment completes.
+func (s *lncliStatus) payInvoice(ctxt *lnclicursesContext, payReq string, amt int, feeLimit int, feeLimitPerc int, force bool) (string, error) {
+
+	req := &lnrpc.SendRequest{PaymentRequest: payReq, Amt: int64(amt)}
+
+	if feeLimit > 0 {
+		req.FeeLimit = &lnrpc.FeeLimit{Limit: &lnrpc.FeeLimit_Fixed{Fixed: int64(feeLimit)}}
+	} else if feeLimitPerc > 0 {
+		req.FeeLimit = &lnrpc.FeeLimit{Limit: &lnrpc.FeeLimit_Percent{Percent: int64(feeLimitPerc)}}
+	}
+
+	s.store.background(func() {
+		pr, err := s.decodePayReq(ctxt, payReq)
+		if err != nil {
+			logError(err.Error())
+			runInGui(func() { displayMessage("Error: "+err.Error(), nil) })
+			return
+		}
+
+		amount := pr.NumSatoshis
+		if amount == 0 {
+			amount = req.Amt
+		}
+
+		send := func() {
+			s.store.background(func() { s.sendAndDisplayPayment(ctxt, req, pr.DestinationAlias, amount) })
+		}
+
+		if force {
+			send()
+			return
+		}
+
+		runInGui(func() { displayPaymentConfirmation(newPayConfirmContainer(pr, req), send) })
+	})
+
+	return "", nil
+}
+
+// keysend sends amt sat to the node without invoice, the payment preimage
+// being generated and sent along with the optional message. outgoingChanID,
+// when not 0, is the channel the payment must leave through.
+func (s *lncliStatus) keysend(ctxt *lnclicursesContext, pubkey string, amt int, feeLimit int, feeLimitPerc int, message string, outgoingChanID uint64) error {
+	dest, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return err
+	}
+
+	preimage := make([]byte, 32)
+	if _, err = rand.Read(preimage); err != nil {
+		return err
+	}
+	hash := sha256.Sum256(preimage)
 
-	var args []string
+	req := &lnrpc.SendRequest{
+		Dest:              dest,
+		Amt:               int64(amt),
+		PaymentHash:       hash[:],
+		FinalCltvDelta:    keysendCltvDelta,
+		OutgoingChanId:    outgoingChanID,
+		DestCustomRecords: map[uint64][]byte{keysendRecordType: preimage},
+	}
 
-	if len(memo) > 0 {
-		args = append(args, "--memo")
-		args = append(args, memo)
+	if len(message) > 0 {
+		req.DestCustomRecords[messageRecordType] = []byte(message)
 	}
 
-	if len(receipt) > 0 {
-		args = append(args, "--receipt")
-		args = append(args, receipt)
+	if feeLimit > 0 {
+		req.FeeLimit = &lnrpc.FeeLimit{Limit: &lnrpc.FeeLimit_Fixed{Fixed: int64(feeLimit)}}
+	} else if feeLimitPerc > 0 {
+		req.FeeLimit = &lnrpc.FeeLimit{Limit: &lnrpc.FeeLimit_Percent{Percent: int64(feeLimitPerc)}}
 	}
 
-	if len(preimage) > 0 {
-		args = append(args, "--preimage")
-		args = append(args, preimage)
+	s.store.background(func() {
+		s.sendAndDisplayPayment(ctxt, req, s.getNodeAlias(ctxt, pubkey), int64(amt))
+	})
+
+	return nil
+}
+
+// sendAndDisplayPayment sends the payment and displays its outcome.
+func (s *lncliStatus) sendAndDisplayPayment(ctxt *lnclicursesContext, req *lnrpc.SendRequest, destination string, amount int64) {
+	msg := s.sendPayment(ctxt, req, destination, amount)
+	runInGui(func() {
+		displayMessage(msg, func(valid bool) {
+			updateDataInBackground()
+		})
+	})
+}
+
+// sendPayment sends the payment, listed as in flight in the state until it
+// completes, and returns the message describing its outcome. The payments
+// are updated once it succeeded.
+func (s *lncliStatus) sendPayment(ctxt *lnclicursesContext, req *lnrpc.SendRequest, destination string, amount int64) string {
+	p := &paymentInFlight{destination, amount, time.Now()}
+	s.store.update(func(st *lncliState) { st.sending = append(append([]*paymentInFlight(nil), st.sending...), p) })
+	refreshView()
+
+	resp, err := ctxt.backend.payInvoice(req)
+
+	s.store.update(func(st *lncliState) {
+		var sending []*paymentInFlight
+		for _, c := range st.sending {
+			if c != p {
+				sending = append(sending, c)
+			}
+		}
+		st.sending = sending
+	})
+
+	switch {
+	case err != nil:
+		logError(err.Error())
+		return "Payment failed\n" + err.Error()
+	case len(resp.PaymentError) > 0:
+		return "Payment failed\n" + resp.PaymentError
 	}
 
-	if amt > 0 {
-		args = append(args, "--amt")
-		args = append(args, strconv.Itoa(amt))
+	msg := "Payment sent\nAmount: " + formatAmountUnit(amount, satUnit) + "\n"
+	if route := resp.PaymentRoute; route != nil {
+		var hops []string
+		for _, h := range route.Hops {
+			hops = append(hops, s.getNodeAlias(ctxt, h.PubKey))
+		}
+		msg += fmt.Sprintf("Fee: %s\nRoute: %s\n", formatAmountUnit(route.TotalFeesMsat, msatUnit), strings.Join(hops, " > "))
 	}
 
-	if len(deshash) > 0 {
-		args = append(args, "--description_hash")
-		args = append(args, deshash)
+	ctxt.scheduler.refreshSource("payments")
+
+	return msg + "Preimage: " + hex.EncodeToString(resp.PaymentPreimage)
+}
+
+// maxProbedChannels bounds the routes queried by a probe.
+var maxProbedChannels = 5
+
+// probeRoutes queries a route to the node for amt sat through the active
+// channels with enough local balance, one per peer and the ones with the most
+// first, through any channel when none has. The routes found are published as
+// the candidates, the last error returned when there are none.
+func (s *lncliStatus) probeRoutes(ctxt *lnclicursesContext, pubkey string, amt int64) error {
+	// the time lock deltas are counted from the current block
+	info, err := ctxt.backend.getInfo()
+	if err != nil {
+		return err
 	}
 
-	if len(fallbackaddr) > 0 {
-		args = append(args, "--fallback_addr")
-		args = append(args, fallbackaddr)
+	var candidates []*lncliChannel
+	for _, c := range s.state().channels {
+		if c.Active && c.LocalBalance >= amt {
+			candidates = append(candidates, c)
+		}
 	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].LocalBalance > candidates[j].LocalBalance })
 
-	if expiry > 0 {
-		args = append(args, "--expiry")
-		args = append(args, strconv.Itoa(expiry))
+	var outgoing []uint64
+	peers := make(map[string]bool)
+	for _, c := range candidates {
+		if len(outgoing) < maxProbedChannels && !peers[c.RemotePubkey] {
+			peers[c.RemotePubkey] = true
+			outgoing = append(outgoing, c.ChanId)
+		}
 	}
+	if len(outgoing) == 0 {
+		outgoing = append(outgoing, 0)
+	}
+
+	var routes []*lncliRoute
 
-	if private {
-		args = append(args, "--private")
+	for _, chanID := range outgoing {
+		var resp *lnrpc.QueryRoutesResponse
+		resp, err = ctxt.backend.queryRoutes(&lnrpc.QueryRoutesRequest{PubKey: pubkey, Amt: amt, OutgoingChanId: chanID})
+		if err != nil {
+			continue
+		}
+		for _, r := range resp.Routes {
+			routes = append(routes, s.newRoute(ctxt, r, info.BlockHeight))
+		}
 	}
 
-	cm := fmt.Sprintf("addinvoice %s", strings.Join(args, " "))
-	out, err := ctxt.execlncliCommand(cm)
+	if len(routes) == 0 {
+		return err
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].TotalFeesMsat < routes[j].TotalFeesMsat })
+
+	alias := s.getNodeAlias(ctxt, pubkey)
+	s.store.update(func(st *lncliState) {
+		st.routes = lncliRoutesContainer{pubkey, alias, amt, routes}
+	})
+
+	return nil
+}
+
+// newRoute resolves the aliases of the route hops, their fees listed in mSat.
+func (s *lncliStatus) newRoute(ctxt *lnclicursesContext, r *lnrpc.Route, blockHeight uint32) *lncliRoute {
+	nr := &lncliRoute{Route: *r, HopCount: len(r.Hops)}
+
+	if r.TotalTimeLock > blockHeight {
+		nr.TimeLockDelta = int64(r.TotalTimeLock - blockHeight)
+	}
+
+	var hops []string
+	for i, h := range r.Hops {
+		alias := s.getNodeAlias(ctxt, h.PubKey)
+		if i == 0 {
+			nr.OutgoingChanID = h.ChanId
+			nr.OutgoingChan = shortChannelID(h.ChanId)
+			nr.OutgoingAlias = alias
+		}
+		hops = append(hops, context.printer.Sprintf("%s (%d)", alias, h.FeeMsat))
+	}
+	nr.Path = strings.Join(hops, " > ")
+
+	return nr
+}
+
+func (s *lncliStatus) openChannel(ctxt *lnclicursesContext, nk string, cct string, lamnt int, pamnt int, pri bool, blk bool, mcf int, cftgt int, spb int, minht int, remcsv int, outpoints []*lnrpc.OutPoint) (string, error) {
 
+	nodeKey, err := hex.DecodeString(nk)
 	if err != nil {
 		return "", err
 	}
 
-	preq, err := getAttributeStr(out, "pay_req")
+	req := &lnrpc.OpenChannelRequest{
+		NodePubkey:         nodeKey,
+		LocalFundingAmount: int64(lamnt),
+		PushSat:            int64(pamnt),
+		TargetConf:         int32(cftgt),
+		SatPerByte:         int64(spb),
+		Private:            pri,
+		MinHtlcMsat:        int64(minht),
+		RemoteCsvDelay:     uint32(remcsv),
+		MinConfs:           int32(mcf),
+		Outpoints:          outpoints,
+	}
+
+	return ctxt.backend.openChannel(req, cct, blk)
+}
+
+func (s *lncliStatus) addInvoice(ctxt *lnclicursesContext, amt int, deshash string, expiry int, fallbackaddr string, memo string, preimage string, private bool, receipt string) (string, error) {
+
+	invoice := &lnrpc.Invoice{
+		Memo:         memo,
+		Value:        int64(amt),
+		FallbackAddr: fallbackaddr,
+		Expiry:       int64(expiry),
+		Private:      private,
+	}
+
+	var err error
+
+	if invoice.RPreimage, err = hex.DecodeString(preimage); err != nil {
+		return "", err
+	}
+
+	if invoice.DescriptionHash, err = hex.DecodeString(deshash); err != nil {
+		return "", err
+	}
+
+	resp, err := ctxt.backend.addInvoice(invoice, receipt)
 
 	if err != nil {
 		return "", err
 	}
 
-	return preq.(string), nil
+	return resp.PaymentRequest, nil
 }
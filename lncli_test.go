@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+func TestUpdateDataHeader(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	updateData()
+
+	if status.state().localNodeInfo.Alias != "fakenode" {
+		t.Errorf("local alias = %q", status.state().localNodeInfo.Alias)
+	}
+	if status.state().walletBalance.TotalBalance != 1500000 {
+		t.Errorf("total balance = %d", status.state().walletBalance.TotalBalance)
+	}
+
+	lines := renderView(t, "nodeinfo", refreshNodeInfoView)
+	assertLine(t, lines, "Alias", "fakenode")
+	assertLine(t, lines, "Chains", "bitcoin testnet synced")
+
+	lines = renderView(t, "balance", refreshWalletBalanceView)
+	assertLine(t, lines, "Confirmed", "1,000,000")
+}
+
+func TestUpdateDataOnlyFetchesActiveView(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	activateView(peerListViewt)
+	updateData()
+
+	if fb.callCount("listpeers") != 1 {
+		t.Errorf("listpeers called %d times", fb.callCount("listpeers"))
+	}
+	if fb.callCount("listchannels") != 0 {
+		t.Errorf("listchannels called %d times", fb.callCount("listchannels"))
+	}
+}
+
+func TestUpdateChannelList(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	if err := status.updateChannelList(&context); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(status.state().channels) != 2 {
+		t.Fatalf("got %d channels", len(status.state().channels))
+	}
+
+	waitFor(t, "channel alias", func() bool {
+		c := status.state().channels[0]
+		return c.NodeAlias == "acinq" && c.NodeUpdate == 1546300000
+	})
+}
+
+func TestUpdatePendingChannelList(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	if err := status.updatePendingChannelList(&context); err != nil {
+		t.Fatal(err)
+	}
+
+	if status.state().pendingchannels.totalLimbo != 250000 {
+		t.Errorf("total limbo = %d", status.state().pendingchannels.totalLimbo)
+	}
+
+	types := ""
+	for _, c := range status.state().pendingchannels.pendingChannels {
+		types += c.GetType()
+	}
+	if types != "CFOW" {
+		t.Errorf("pending channel types = %q", types)
+	}
+}
+
+func TestUpdateInvoiceListRequest(t *testing.T) {
+	fb := setupTestContext(t)
+
+	if err := status.updateInvoiceList(&context); err != nil {
+		t.Fatal(err)
+	}
+
+	req := fb.lastListInvoices
+	if !req.Reversed || req.NumMaxInvoices != 100 || req.IndexOffset != 0 {
+		t.Errorf("unexpected listinvoices request %+v", req)
+	}
+	if len(status.state().invoices.invoices) != 2 {
+		t.Errorf("got %d invoices", len(status.state().invoices.invoices))
+	}
+}
+
+func TestGetNodeInfoCachesResult(t *testing.T) {
+	fb := setupTestContext(t)
+
+	for i := 0; i < 3; i++ {
+		ni, err := status.getNodeInfo(&context, testPubkey(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ni.Node.Alias != "bitrefill" {
+			t.Errorf("alias = %q", ni.Node.Alias)
+		}
+	}
+
+	if fb.callCount("getnodeinfo") != 1 {
+		t.Errorf("getnodeinfo called %d times", fb.callCount("getnodeinfo"))
+	}
+}
+
+func TestUpdateDataLogsBackendErrors(t *testing.T) {
+	fb := setupTestContext(t)
+	fb.setError(errors.New("unable to connect to lnd"))
+
+	updateData()
+
+	if len(status.state().logs) == 0 {
+		t.Fatal("no error logged")
+	}
+	if status.state().logs[0].Message != "unable to connect to lnd" {
+		t.Errorf("logged %q", status.state().logs[0].Message)
+	}
+}
+
+func TestUpdateChannelPolicyFeeRate(t *testing.T) {
+	fb := setupTestContext(t)
+
+	for _, ppm := range []int{0, 1, 3, 7, 29, 250, 4999, 1000000} {
+		if err := status.updateChannelPolicy(&context, nil, 0, ppm, 40, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+		if got := fb.lastPolicyUpdate.FeeRatePpm; got != uint32(ppm) {
+			t.Errorf("fee rate %d ppm sent as %d ppm", ppm, got)
+		}
+	}
+}
+
+func TestPolicyUpdateError(t *testing.T) {
+	if err := policyUpdateError(&lnrpc.PolicyUpdateResponse{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	resp := &lnrpc.PolicyUpdateResponse{FailedUpdates: []*lnrpc.FailedUpdate{
+		{Outpoint: &lnrpc.OutPoint{TxidStr: strings.Repeat("a1", 32), OutputIndex: 1}, UpdateError: "channel not found"},
+	}}
+	want := "policy update failed for " + strings.Repeat("a1", 32) + ":1 (channel not found)"
+	if err := policyUpdateError(resp); err == nil || err.Error() != want {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestPaymentResponse(t *testing.T) {
+	resp, err := paymentResponse(&lnrpc.Payment{Status: lnrpc.Payment_FAILED, FailureReason: lnrpc.PaymentFailureReason_FAILURE_REASON_NO_ROUTE})
+	if err != nil || resp.PaymentError != "FAILURE_REASON_NO_ROUTE" {
+		t.Errorf("failed payment %v, error %v", resp, err)
+	}
+
+	resp, err = paymentResponse(&lnrpc.Payment{Status: lnrpc.Payment_IN_FLIGHT})
+	if err != nil || resp.PaymentError != "payment in_flight" {
+		t.Errorf("payment in flight %v, error %v", resp, err)
+	}
+
+	route := &lnrpc.Route{TotalFeesMsat: 1000}
+	resp, err = paymentResponse(&lnrpc.Payment{
+		Status:          lnrpc.Payment_SUCCEEDED,
+		PaymentHash:     strings.Repeat("ab", 32),
+		PaymentPreimage: strings.Repeat("cd", 32),
+		Htlcs: []*lnrpc.HTLCAttempt{
+			{Status: lnrpc.HTLCAttempt_FAILED, Route: &lnrpc.Route{}},
+			{Status: lnrpc.HTLCAttempt_SUCCEEDED, Route: route},
+		},
+	})
+	if err != nil || len(resp.PaymentError) > 0 || resp.PaymentRoute != route || hex.EncodeToString(resp.PaymentPreimage) != strings.Repeat("cd", 32) {
+		t.Errorf("succeeded payment %v, error %v", resp, err)
+	}
+}
+
+func TestGlobalShortcutsDontShadowViews(t *testing.T) {
+	setupTestContext(t)
+
+	type binding struct {
+		key interface{}
+		mod gocui.Modifier
+	}
+	global := make(map[binding]string)
+	for _, kh := range context.globalShortcuts {
+		global[binding{kh.key, kh.mod}] = kh.header
+	}
+
+	for vt, v := range context.views {
+		for _, kh := range v.getShortCuts() {
+			if header, ok := global[binding{kh.key, kh.mod}]; ok {
+				t.Errorf("view %d %q bound to the key of %q", vt, kh.header, header)
+			}
+		}
+	}
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+)
+
+// lncliBackend talks to lnd by executing the lncli binary and parsing its
+// JSON output.
+type lncliBackend struct {
+	cliMutex *sync.Mutex
+}
+
+func newlncliBackend() *lncliBackend {
+	b := new(lncliBackend)
+	b.cliMutex = &sync.Mutex{}
+	return b
+}
+
+func (b *lncliBackend) getlncliArgs() []string {
+	var args []string
+
+	args = nil
+
+	if len(getLndDir()) > 0 {
+		args = append(args, "--lnddir="+getLndDir())
+	}
+	if len(getRPCServer()) > 0 {
+		args = append(args, "--rpcserver="+getRPCServer())
+	}
+	if len(getTLSCertPath()) > 0 {
+		args = append(args, "--tlscertpath="+getTLSCertPath())
+	}
+	if getNoMacaroons() {
+		args = append(args, "--no-macaroons")
+	}
+	if len(getMacaroonPath()) > 0 {
+		args = append(args, "--macaroonpath="+getMacaroonPath())
+	}
+	if getMacaroonTimeOut() > 0 {
+		args = append(args, fmt.Sprintf("--macaroontimeout=%d", getMacaroonTimeOut()))
+	}
+	if len(getMacaroonIP()) > 0 {
+		args = append(args, "--macaroonip="+getMacaroonIP())
+	}
+
+	return args
+}
+
+func (b *lncliBackend) execlncliCommand(command ...string) ([]byte, error) {
+	b.cliMutex.Lock()
+	defer b.cliMutex.Unlock()
+	return b.execlncliUnlocked(command...)
+}
+
+// execlncliUnlocked executes lncli without holding cliMutex, for the commands
+// lasting as long as a payment, which would hold the refreshes back.
+func (b *lncliBackend) execlncliUnlocked(command ...string) ([]byte, error) {
+
+	args := []string{getLncliExec()}
+	args = append(args, b.getlncliArgs()...)
+	args = append(args, command...)
+
+	cmd := exec.Command(getLncliExec())
+	cmd.Args = args
+
+	// the output is returned along with the exit error, for the commands
+	// printing their outcome when failing
+	return cmd.Output()
+}
+
+func (b *lncliBackend) execlncliUnmarshal(pb proto.Message, command ...string) error {
+	txt, err := b.execlncliCommand(command...)
+	if err != nil {
+		return err
+	}
+	return jsonpb.Unmarshal(bytes.NewReader(txt), pb)
+}
+
+func (b *lncliBackend) getInfo() (*lnrpc.GetInfoResponse, error) {
+	var r lnrpc.GetInfoResponse
+	if err := b.execlncliUnmarshal(&r, "getinfo"); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) walletBalance() (*lnrpc.WalletBalanceResponse, error) {
+	var r lnrpc.WalletBalanceResponse
+	if err := b.execlncliUnmarshal(&r, "walletbalance"); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) listChannels() (*lnrpc.ListChannelsResponse, error) {
+	var r lnrpc.ListChannelsResponse
+	if err := b.execlncliUnmarshal(&r, "listchannels"); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) pendingChannels() (*lnrpc.PendingChannelsResponse, error) {
+	var r lnrpc.PendingChannelsResponse
+	if err := b.execlncliUnmarshal(&r, "pendingchannels"); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) listPeers() (*lnrpc.ListPeersResponse, error) {
+	var r lnrpc.ListPeersResponse
+	if err := b.execlncliUnmarshal(&r, "listpeers"); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) listInvoices(req *lnrpc.ListInvoiceRequest) (*lnrpc.ListInvoiceResponse, error) {
+	args := []string{"listinvoices",
+		"--max_invoices", strconv.FormatUint(req.NumMaxInvoices, 10),
+		"--index_offset", strconv.FormatUint(req.IndexOffset, 10)}
+
+	if req.Reversed {
+		args = append(args, "--reversed")
+	}
+
+	if req.PendingOnly {
+		args = append(args, "--pending_only")
+	}
+
+	var r lnrpc.ListInvoiceResponse
+	if err := b.execlncliUnmarshal(&r, args...); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) listPayments(req *lnrpc.ListPaymentsRequest) (*lnrpc.ListPaymentsResponse, error) {
+	args := []string{"listpayments",
+		"--max_payments", strconv.FormatUint(req.MaxPayments, 10),
+		"--index_offset", strconv.FormatUint(req.IndexOffset, 10)}
+
+	if !req.Reversed {
+		args = append(args, "--paginate_forwards")
+	}
+
+	var r lnrpc.ListPaymentsResponse
+	if err := b.execlncliUnmarshal(&r, args...); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) listChainTxns() (*lnrpc.TransactionDetails, error) {
+	var r lnrpc.TransactionDetails
+	if err := b.execlncliUnmarshal(&r, "listchaintxns"); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) forwardingHistory(req *lnrpc.ForwardingHistoryRequest) (*lnrpc.ForwardingHistoryResponse, error) {
+	var r lnrpc.ForwardingHistoryResponse
+	err := b.execlncliUnmarshal(&r, "fwdinghistory",
+		"--start_time", strconv.FormatUint(req.StartTime, 10),
+		"--end_time", strconv.FormatUint(req.EndTime, 10),
+		"--index_offset", strconv.FormatUint(uint64(req.IndexOffset), 10),
+		"--max_events", strconv.FormatUint(uint64(req.NumMaxEvents), 10))
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) getChanInfo(chanID uint64) (*lnrpc.ChannelEdge, error) {
+	var r lnrpc.ChannelEdge
+	if err := b.execlncliUnmarshal(&r, "getchaninfo", strconv.FormatUint(chanID, 10)); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) feeReport() (*lnrpc.FeeReportResponse, error) {
+	var r lnrpc.FeeReportResponse
+	if err := b.execlncliUnmarshal(&r, "feereport"); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) updateChannelPolicy(req *lnrpc.PolicyUpdateRequest) error {
+	args := []string{"updatechanpolicy",
+		"--base_fee_msat", strconv.FormatInt(req.BaseFeeMsat, 10),
+		"--fee_rate_ppm", strconv.FormatUint(uint64(req.FeeRatePpm), 10),
+		"--time_lock_delta", strconv.FormatUint(uint64(req.TimeLockDelta), 10)}
+
+	if req.MinHtlcMsatSpecified {
+		args = append(args, "--min_htlc_msat", strconv.FormatUint(req.MinHtlcMsat, 10))
+	}
+	if req.MaxHtlcMsat > 0 {
+		args = append(args, "--max_htlc_msat", strconv.FormatUint(req.MaxHtlcMsat, 10))
+	}
+	if cp := req.GetChanPoint(); cp != nil {
+		args = append(args, "--chan_point", fmt.Sprintf("%s:%d", channelPointTxid(cp), cp.OutputIndex))
+	}
+
+	var r lnrpc.PolicyUpdateResponse
+	if err := b.execlncliUnmarshal(&r, args...); err != nil {
+		return err
+	}
+	return policyUpdateError(&r)
+}
+
+func (b *lncliBackend) getNodeInfo(pubkey string) (*lnrpc.NodeInfo, error) {
+	var r lnrpc.NodeInfo
+	if err := b.execlncliUnmarshal(&r, "getnodeinfo", pubkey); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) openChannel(req *lnrpc.OpenChannelRequest, connect string, block bool) (string, error) {
+
+	args := []string{"openchannel"}
+
+	if len(req.NodePubkey) > 0 {
+		args = append(args, "--node_key", hex.EncodeToString(req.NodePubkey))
+	}
+
+	if len(connect) > 0 {
+		args = append(args, "--connect", connect)
+	}
+
+	if req.LocalFundingAmount > 0 {
+		args = append(args, "--local_amt", strconv.FormatInt(req.LocalFundingAmount, 10))
+	}
+
+	if req.PushSat > 0 {
+		args = append(args, "--push_amt", strconv.FormatInt(req.PushSat, 10))
+	}
+
+	if block {
+		args = append(args, "--block")
+	}
+
+	if req.Private {
+		args = append(args, "--private")
+	}
+
+	if req.TargetConf > 0 {
+		args = append(args, "--conf_target", strconv.Itoa(int(req.TargetConf)))
+	}
+
+	if req.SatPerByte > 0 {
+		args = append(args, "--sat_per_byte", strconv.FormatInt(req.SatPerByte, 10))
+	}
+
+	if req.MinHtlcMsat > 0 {
+		args = append(args, "--min_htlc_msat", strconv.FormatInt(req.MinHtlcMsat, 10))
+	}
+
+	if req.RemoteCsvDelay > 0 {
+		args = append(args, "--remote_csv_delay", strconv.Itoa(int(req.RemoteCsvDelay)))
+	}
+
+	if req.MinConfs > 0 {
+		args = append(args, "--min_confs", strconv.Itoa(int(req.MinConfs)))
+	}
+
+	for _, op := range req.Outpoints {
+		args = append(args, "--utxo", outPointString(op))
+	}
+
+	out, err := b.execlncliCommand(args...)
+
+	if err != nil {
+		return "", err
+	}
+
+	fid, err := getAttributeStr(out, "funding_txid")
+
+	if err != nil {
+		return "", err
+	}
+
+	return fid.(string), nil
+}
+
+func (b *lncliBackend) closeChannel(channelPoint string, force bool, targetConf int32, satPerByte int64) (string, error) {
+	txid, index, err := splitChannelPoint(channelPoint)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"closechannel", "--funding_txid", txid, "--output_index", strconv.Itoa(int(index))}
+	if force {
+		args = append(args, "--force")
+	}
+	if targetConf > 0 {
+		args = append(args, "--conf_target", strconv.Itoa(int(targetConf)))
+	}
+	if satPerByte > 0 {
+		args = append(args, "--sat_per_byte", strconv.FormatInt(satPerByte, 10))
+	}
+
+	out, err := b.execlncliCommand(args...)
+
+	if err != nil {
+		return "", err
+	}
+
+	cid, err := getAttributeStr(out, "closing_txid")
+
+	if err != nil {
+		return "", err
+	}
+
+	return cid.(string), nil
+}
+
+func (b *lncliBackend) connectPeer(pubkey string, host string) error {
+	_, err := b.execlncliCommand("connect", pubkey+"@"+host)
+	return err
+}
+
+func (b *lncliBackend) disconnectPeer(pubkey string) error {
+	_, err := b.execlncliCommand("disconnect", pubkey)
+	return err
+}
+
+func (b *lncliBackend) addInvoice(invoice *lnrpc.Invoice, receipt string) (*lnrpc.AddInvoiceResponse, error) {
+
+	args := []string{"addinvoice"}
+
+	if len(invoice.Memo) > 0 {
+		args = append(args, "--memo", invoice.Memo)
+	}
+
+	if len(receipt) > 0 {
+		args = append(args, "--receipt", receipt)
+	}
+
+	if len(invoice.RPreimage) > 0 {
+		args = append(args, "--preimage", hex.EncodeToString(invoice.RPreimage))
+	}
+
+	if invoice.Value > 0 {
+		args = append(args, "--amt", strconv.FormatInt(invoice.Value, 10))
+	}
+
+	if len(invoice.DescriptionHash) > 0 {
+		args = append(args, "--description_hash", hex.EncodeToString(invoice.DescriptionHash))
+	}
+
+	if len(invoice.FallbackAddr) > 0 {
+		args = append(args, "--fallback_addr", invoice.FallbackAddr)
+	}
+
+	if invoice.Expiry > 0 {
+		args = append(args, "--expiry", strconv.FormatInt(invoice.Expiry, 10))
+	}
+
+	if invoice.Private {
+		args = append(args, "--private")
+	}
+
+	out, err := b.execlncliCommand(args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	preq, err := getAttributeStr(out, "pay_req")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.AddInvoiceResponse{PaymentRequest: preq.(string)}, nil
+}
+
+func (b *lncliBackend) payInvoice(req *lnrpc.SendRequest) (*lnrpc.SendResponse, error) {
+
+	args := []string{"payinvoice", "--force"}
+
+	if len(req.PaymentRequest) > 0 {
+		args = append(args, "--pay_req", req.PaymentRequest)
+	}
+
+	// payments without invoice, the custom records as id=hex pairs
+	if len(req.Dest) > 0 {
+		args = []string{"sendpayment", "--force",
+			"--dest", hex.EncodeToString(req.Dest),
+			"--payment_hash", hex.EncodeToString(req.PaymentHash),
+			"--final_cltv_delta", strconv.Itoa(int(req.FinalCltvDelta))}
+
+		if req.OutgoingChanId > 0 {
+			args = append(args, "--outgoing_chan_id", strconv.FormatUint(req.OutgoingChanId, 10))
+		}
+
+		var ids []uint64
+		for id := range req.DestCustomRecords {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		var records []string
+		for _, id := range ids {
+			records = append(records, fmt.Sprintf("%d=%s", id, hex.EncodeToString(req.DestCustomRecords[id])))
+		}
+		if len(records) > 0 {
+			args = append(args, "--data", strings.Join(records, ","))
+		}
+	}
+
+	if req.Amt > 0 {
+		args = append(args, "--amt", strconv.FormatInt(req.Amt, 10))
+	}
+
+	if fl := req.FeeLimit.GetFixed(); fl > 0 {
+		args = append(args, "--fee_limit", strconv.FormatInt(fl, 10))
+	}
+
+	if fl := req.FeeLimit.GetPercent(); fl > 0 {
+		args = append(args, "--fee_limit_percent", strconv.FormatInt(fl, 10))
+	}
+
+	args = append(args, "--json")
+
+	// lncli prints the payment updates and exits with an error when the
+	// payment fails, the last update holding its outcome.
+	out, err := b.execlncliUnlocked(args...)
+
+	payment, perr := lastPaymentUpdate(out)
+	if perr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, perr
+	}
+
+	return paymentResponse(payment)
+}
+
+// lastPaymentUpdate returns the last of the payment updates printed by lncli.
+func lastPaymentUpdate(out []byte) (*lnrpc.Payment, error) {
+	var last *lnrpc.Payment
+
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		p := new(lnrpc.Payment)
+		if err := jsonpb.UnmarshalNext(dec, p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		last = p
+	}
+
+	if last == nil {
+		return nil, errors.New("no payment update")
+	}
+	return last, nil
+}
+
+func (b *lncliBackend) decodePayReq(payReq string) (*lnrpc.PayReq, error) {
+	var r lnrpc.PayReq
+	if err := b.execlncliUnmarshal(&r, "decodepayreq", payReq); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) queryRoutes(req *lnrpc.QueryRoutesRequest) (*lnrpc.QueryRoutesResponse, error) {
+	args := []string{"queryroutes", "--dest", req.PubKey, "--amt", strconv.FormatInt(req.Amt, 10)}
+
+	if req.OutgoingChanId > 0 {
+		args = append(args, "--outgoing_chanid", strconv.FormatUint(req.OutgoingChanId, 10))
+	}
+
+	var r lnrpc.QueryRoutesResponse
+	if err := b.execlncliUnmarshal(&r, args...); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) newAddress(addressType string) (string, error) {
+	var na lnrpc.NewAddressResponse
+	if err := b.execlncliUnmarshal(&na, "newaddress", addressType); err != nil {
+		return "", err
+	}
+	return na.Address, nil
+}
+
+func (b *lncliBackend) estimateFee(req *lnrpc.EstimateFeeRequest) (*lnrpc.EstimateFeeResponse, error) {
+	amounts, err := json.Marshal(req.AddrToAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"estimatefee"}
+
+	if req.TargetConf > 0 {
+		args = append(args, "--conf_target", strconv.Itoa(int(req.TargetConf)))
+	}
+
+	var r lnrpc.EstimateFeeResponse
+	if err := b.execlncliUnmarshal(&r, append(args, string(amounts))...); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) sendCoins(req *lnrpc.SendCoinsRequest) (string, error) {
+	args := []string{"sendcoins", "--addr", req.Addr}
+
+	if req.SendAll {
+		args = append(args, "--sweepall")
+	} else {
+		args = append(args, "--amt", strconv.FormatInt(req.Amount, 10))
+	}
+
+	if req.TargetConf > 0 {
+		args = append(args, "--conf_target", strconv.Itoa(int(req.TargetConf)))
+	}
+
+	if req.SatPerByte > 0 {
+		args = append(args, "--sat_per_byte", strconv.FormatInt(req.SatPerByte, 10))
+	}
+
+	if len(req.Label) > 0 {
+		args = append(args, "--label", req.Label)
+	}
+
+	for _, op := range req.Outpoints {
+		args = append(args, "--utxo", outPointString(op))
+	}
+
+	var r lnrpc.SendCoinsResponse
+	if err := b.execlncliUnmarshal(&r, args...); err != nil {
+		return "", err
+	}
+	return r.Txid, nil
+}
+
+func (b *lncliBackend) sendMany(req *lnrpc.SendManyRequest) (string, error) {
+	amounts, err := json.Marshal(req.AddrToAmount)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"sendmany"}
+
+	if req.TargetConf > 0 {
+		args = append(args, "--conf_target", strconv.Itoa(int(req.TargetConf)))
+	}
+
+	if req.SatPerByte > 0 {
+		args = append(args, "--sat_per_byte", strconv.FormatInt(req.SatPerByte, 10))
+	}
+
+	if len(req.Label) > 0 {
+		args = append(args, "--label", req.Label)
+	}
+
+	var r lnrpc.SendManyResponse
+	if err := b.execlncliUnmarshal(&r, append(args, string(amounts))...); err != nil {
+		return "", err
+	}
+	return r.Txid, nil
+}
+
+func (b *lncliBackend) listUnspent() (*lnrpc.ListUnspentResponse, error) {
+	var r lnrpc.ListUnspentResponse
+	if err := b.execlncliUnmarshal(&r, "listunspent", "--min_confs", "0", "--max_confs", strconv.Itoa(math.MaxInt32)); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) listLeases() (*walletrpc.ListLeasesResponse, error) {
+	var r walletrpc.ListLeasesResponse
+	if err := b.execlncliUnmarshal(&r, "wallet", "listleases"); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *lncliBackend) leaseOutput(req *walletrpc.LeaseOutputRequest) (uint64, error) {
+	var r walletrpc.LeaseOutputResponse
+	err := b.execlncliUnmarshal(&r, "wallet", "leaseoutput",
+		"--outpoint", outPointString(req.Outpoint),
+		"--lockid", hex.EncodeToString(req.Id),
+		"--expiry", strconv.FormatUint(req.ExpirationSeconds, 10))
+	if err != nil {
+		return 0, err
+	}
+	return r.Expiration, nil
+}
+
+func (b *lncliBackend) releaseOutput(req *walletrpc.ReleaseOutputRequest) error {
+	_, err := b.execlncliCommand("wallet", "releaseoutput", "--outpoint", outPointString(req.Outpoint), "--lockid", hex.EncodeToString(req.Id))
+	return err
+}
+
+func getAttributeStr(in []byte, attributeName string) (interface{}, error) {
+	var attrs map[string]interface{}
+	if err := json.Unmarshal(in, &attrs); err != nil {
+		return "", err
+	}
+	if val, ok := attrs[attributeName]; ok {
+		return val, nil
+	}
+	return "", errors.New("json attribute not found")
+}
@@ -47,6 +47,7 @@ func (cv *logListView) init(fmtnormal string, fmtheader string, fmtselected stri
 	cv.shortcuts = nil
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
 
 	cv.grid.header = "[Logs]"
 
@@ -69,7 +70,7 @@ func (cv *logListView) refreshView(g *gocui.Gui) {
 
 	x, y := v.Size()
 
-	cv.grid.items = context.logs
+	cv.grid.items = status.state().logs
 	cv.grid.setRenderSize(x, y)
 
 	for _, row := range cv.grid.getGridRows() {
@@ -94,7 +95,8 @@ func (cv *logListView) getPhysicalView() string {
 }
 
 func writelog(lvl logLevel, e string) {
-	context.logs = append(context.logs, &logEntry{time.Now(), lvl, e})
+	entry := &logEntry{time.Now(), lvl, e}
+	status.store.update(func(st *lncliState) { st.logs = append(st.logs, entry) })
 }
 
 func logError(e string) {
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// nodeCacheFailureTTL is how long a failed query is remembered.
+const nodeCacheFailureTTL = 10 * time.Minute
+
+// nodeCache keeps the node infos queried from lnd, persisted to a file so
+// aliases don't have to be queried again at start up. Entries older than ttl
+// are queried again, the oldest ones are dropped above maxSize entries. The
+// failed queries are only kept in memory.
+type nodeCache struct {
+	mutex    sync.Mutex
+	path     string
+	ttl      time.Duration
+	maxSize  int
+	entries  map[string]*nodeCacheEntry
+	failures map[string]*nodeCacheFailure
+	dirty    bool
+}
+
+type nodeCacheFailure struct {
+	err    error
+	failed time.Time
+}
+
+type nodeCacheEntry struct {
+	Info    lnrpc.NodeInfo `json:"info"`
+	Fetched int64          `json:"fetched"`
+}
+
+// newNodeCache returns an empty cache, persisted to path unless empty.
+func newNodeCache(path string, ttl time.Duration, maxSize int) *nodeCache {
+	c := new(nodeCache)
+	c.path = path
+	c.ttl = ttl
+	c.maxSize = maxSize
+	c.entries = make(map[string]*nodeCacheEntry)
+	c.failures = make(map[string]*nodeCacheFailure)
+	return c
+}
+
+// get returns the cached node info and whether it's still fresh.
+func (c *nodeCache) get(pubkey string) (lnrpc.NodeInfo, bool, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[pubkey]
+	if !ok {
+		return lnrpc.NodeInfo{}, false, false
+	}
+	fresh := time.Since(time.Unix(e.Fetched, 0)) < c.ttl
+	return e.Info, true, fresh
+}
+
+func (c *nodeCache) set(pubkey string, info lnrpc.NodeInfo) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.failures, pubkey)
+	c.entries[pubkey] = &nodeCacheEntry{info, time.Now().Unix()}
+	c.dirty = true
+	c.evict()
+}
+
+// getFailure returns the error of the last query of the node if it failed
+// less than nodeCacheFailureTTL ago.
+func (c *nodeCache) getFailure(pubkey string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	f, ok := c.failures[pubkey]
+	if !ok {
+		return nil
+	}
+	if time.Since(f.failed) >= nodeCacheFailureTTL {
+		delete(c.failures, pubkey)
+		return nil
+	}
+	return f.err
+}
+
+func (c *nodeCache) setFailure(pubkey string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.failures[pubkey] = &nodeCacheFailure{err, time.Now()}
+}
+
+// evict drops the oldest entries once above maxSize, down to 90% of maxSize
+// for the next ones not to sort the entries again.
+func (c *nodeCache) evict() {
+	if c.maxSize <= 0 || len(c.entries) <= c.maxSize {
+		return
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for k := range c.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return c.entries[keys[i]].Fetched < c.entries[keys[j]].Fetched })
+
+	keep := c.maxSize - c.maxSize/10
+	for _, k := range keys[:len(keys)-keep] {
+		delete(c.entries, k)
+	}
+}
+
+// invalidate expires all the entries, they are kept until queried again, and
+// forgets the failed queries.
+func (c *nodeCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, e := range c.entries {
+		e.Fetched = 0
+	}
+	c.failures = make(map[string]*nodeCacheFailure)
+	c.dirty = true
+}
+
+func (c *nodeCache) size() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.entries)
+}
+
+func (c *nodeCache) load() error {
+	if len(c.path) == 0 {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]*nodeCacheEntry)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = entries
+	c.evict()
+	return nil
+}
+
+// save writes the cache to its file if modified since last saved.
+func (c *nodeCache) save() error {
+	if len(c.path) == 0 {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNodeCacheExpiry(t *testing.T) {
+	fb := setupTestContext(t)
+	status.nodes = newNodeCache("", time.Hour, 10)
+
+	if ni, err := status.getNodeInfo(&context, testPubkey(1)); err != nil || ni.Node.Alias != "acinq" {
+		t.Fatalf("getNodeInfo = %v, %v", ni.Node, err)
+	}
+	status.getNodeInfo(&context, testPubkey(1))
+	if n := fb.callCount("getnodeinfo"); n != 1 {
+		t.Errorf("getnodeinfo called %d times for a cached node", n)
+	}
+
+	fb.nodes[testPubkey(1)].Node.Alias = "acinq2"
+	status.nodes.entries[testPubkey(1)].Fetched = time.Now().Add(-2 * time.Hour).Unix()
+
+	if ni, _ := status.getNodeInfo(&context, testPubkey(1)); ni.Node.Alias != "acinq2" {
+		t.Errorf("expired entry not queried again, alias %s", ni.Node.Alias)
+	}
+
+	status.nodes.invalidate()
+	fb.err = errors.New("lnd unavailable")
+
+	if ni, err := status.getNodeInfo(&context, testPubkey(1)); err != nil || ni.Node.Alias != "acinq2" {
+		t.Errorf("expired entry not returned on error, %v", err)
+	}
+	if _, err := status.getNodeInfo(&context, testPubkey(2)); err == nil {
+		t.Error("missing node without error")
+	}
+}
+
+func TestNodeCacheEviction(t *testing.T) {
+	fb := setupTestContext(t)
+	c := newNodeCache("", time.Hour, 10)
+
+	for i := 1; i <= 10; i++ {
+		c.set(testPubkey(byte(i)), *fb.nodes[testPubkey(1)])
+		c.entries[testPubkey(byte(i))].Fetched = int64(1000 + i)
+	}
+	if c.size() != 10 {
+		t.Fatalf("cache size %d", c.size())
+	}
+
+	c.set(testPubkey(11), *fb.nodes[testPubkey(1)])
+
+	if c.size() != 9 {
+		t.Fatalf("cache size %d, want evicted down to 90%%", c.size())
+	}
+	for _, k := range []byte{1, 2} {
+		if _, ok, _ := c.get(testPubkey(k)); ok {
+			t.Errorf("oldest entry %d not evicted", k)
+		}
+	}
+	if _, ok, _ := c.get(testPubkey(11)); !ok {
+		t.Error("new entry evicted")
+	}
+}
+
+func TestNodeCachePersistence(t *testing.T) {
+	fb := setupTestContext(t)
+	path := filepath.Join(t.TempDir(), ".lncli-curses", "nodecache.json")
+
+	c := newNodeCache(path, time.Hour, 10)
+	c.set(testPubkey(1), *fb.nodes[testPubkey(1)])
+	c.set(testPubkey(2), *fb.nodes[testPubkey(2)])
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := newNodeCache(path, time.Hour, 1)
+	if err := loaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.size() != 1 {
+		t.Errorf("loaded %d entries above the cache size", loaded.size())
+	}
+
+	loaded = newNodeCache(path, time.Hour, 10)
+	if err := loaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	ni, ok, fresh := loaded.get(testPubkey(2))
+	if !ok || !fresh || ni.Node.Alias != "bitrefill" || ni.Node.Addresses[0].Addr != "bitrefill.example.com:9735" {
+		t.Errorf("loaded entry %v, %v, %v", ni.Node, ok, fresh)
+	}
+
+	if err := newNodeCache(filepath.Join(t.TempDir(), "none.json"), time.Hour, 10).load(); err != nil {
+		t.Errorf("missing cache file, %v", err)
+	}
+}
+
+func TestRefreshNodeCache(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	fb.nodes[testPubkey(2)].Node.Alias = "bitrefill2"
+	refreshNodeCache()
+
+	waitFor(t, "refreshed alias", func() bool {
+		ni, _, fresh := status.nodes.get(testPubkey(2))
+		return fresh && ni.Node.Alias == "bitrefill2"
+	})
+}
+
+func TestNodeCacheFailures(t *testing.T) {
+	fb := setupTestContext(t)
+
+	for i := 0; i < 2; i++ {
+		if alias := status.getNodeAlias(&context, testPubkey(9)); alias != testPubkey(9) {
+			t.Errorf("alias = %q", alias)
+		}
+	}
+	if n := fb.callCount("getnodeinfo"); n != 1 {
+		t.Errorf("failed node queried %d times", n)
+	}
+
+	status.nodes.failures[testPubkey(9)].failed = time.Now().Add(-nodeCacheFailureTTL)
+	status.getNodeAlias(&context, testPubkey(9))
+	if n := fb.callCount("getnodeinfo"); n != 2 {
+		t.Errorf("failed node not queried again, %d queries", n)
+	}
+
+	status.nodes.invalidate()
+	status.getNodeAlias(&context, testPubkey(9))
+	if n := fb.callCount("getnodeinfo"); n != 3 {
+		t.Errorf("failed node not queried after a refresh, %d queries", n)
+	}
+}
@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/jroimartin/gocui"
+	"github.com/lightningnetwork/lnd/lnrpc"
 )
 
 type paymentListView struct {
@@ -15,13 +18,213 @@ type paymentListView struct {
 // func (s *lncliStatus) payInvoice(ctxt *lnclicursesContext, payReq string, amt int, feeLimit int, feeLimitPerc int, force bool) (string, error) {
 
 type payInvoiceContainer struct {
-	PayReq       string `displayname:"Pay req." length:"70" lines:"3"`
-	Amount       int    `displayname:"Amount" length:"16"`
-	FeeLimit     int    `displayname:"Fee limit" length:"5"`
-	FeeLimitPerc int    `displayname:"Fee limit perc" length:"3"`
+	PayReq       string `displayname:"Pay req." length:"70" lines:"6" validate:"required,bolt11"`
+	Decoded      string `displayname:"Decoded" length:"70" lines:"3" readonly:"1"`
+	Amount       int    `displayname:"Amount" length:"16" unit:"sat" validate:"min=0"`
+	FeeLimit     int    `displayname:"Fee limit" length:"12" unit:"sat" validate:"min=0"`
+	FeeLimitPerc int    `displayname:"Fee limit perc" length:"3" validate:"min=0,max=100"`
 	Force        bool   `displayname:"Force"`
 }
 
+func (c *payInvoiceContainer) validate() map[string]string {
+	errs := make(map[string]string)
+	if c.FeeLimit > 0 && c.FeeLimitPerc > 0 {
+		errs["FeeLimitPerc"] = "fee limit or percentage"
+	}
+	return errs
+}
+
+type payConfirmContainer struct {
+	Destination string `displayname:"Destination" length:"66" readonly:"1"`
+	Amount      string `displayname:"Amount" length:"66" readonly:"1"`
+	Description string `displayname:"Description" length:"66" lines:"3" readonly:"1"`
+	Expiry      string `displayname:"Expires" length:"66" readonly:"1"`
+	FeeLimit    string `displayname:"Fee limit" length:"66" readonly:"1"`
+	Warning     string `displayname:"Warning" length:"66" readonly:"1"`
+}
+
+type keysendContainer struct {
+	PubKey       string `displayname:"Pub key" length:"40" lines:"2" validate:"required,pubkey"`
+	Amount       int    `displayname:"Amount" length:"16" unit:"sat" validate:"required,min=1"`
+	FeeLimit     int    `displayname:"Fee limit" length:"12" unit:"sat" validate:"min=0"`
+	FeeLimitPerc int    `displayname:"Fee limit perc" length:"3" validate:"min=0,max=100"`
+	Message      string `displayname:"Message" length:"40" lines:"3"`
+	OutgoingChan string `displayname:"Outgoing chan" length:"16" validate:"chanid"`
+}
+
+func (c *keysendContainer) validate() map[string]string {
+	errs := make(map[string]string)
+	if c.FeeLimit > 0 && c.FeeLimitPerc > 0 {
+		errs["FeeLimitPerc"] = "fee limit or percentage"
+	}
+	return errs
+}
+
+type paymentDetailsContainer struct {
+	PaymentHash    string `displayname:"Payment hash" length:"70" readonly:"1"`
+	Status         string `displayname:"Status" length:"70" readonly:"1"`
+	FailureReason  string `displayname:"Failure reason" length:"70" readonly:"1"`
+	Created        string `displayname:"Created" length:"70" readonly:"1"`
+	Value          string `displayname:"Value" length:"70" readonly:"1"`
+	Fee            string `displayname:"Fee" length:"70" readonly:"1"`
+	Preimage       string `displayname:"Preimage" length:"70" readonly:"1"`
+	PaymentRequest string `displayname:"Payment request" length:"70" lines:"2" readonly:"1"`
+	Hops           string `displayname:"Hops" length:"70" lines:"8" readonly:"1"`
+}
+
+func newPaymentDetailsContainer(p *lncliPayment) *paymentDetailsContainer {
+	cc := new(paymentDetailsContainer)
+	cc.PaymentHash = p.PaymentHash
+	cc.Status = p.getStatus()
+	cc.FailureReason = p.getFailureReason()
+	cc.Created = time.Unix(p.CreationDate, 0).Format("02-01-06 15:04:05")
+	cc.Value = formatAmountUnit(p.ValueMsat, msatUnit)
+	cc.Fee = formatAmountUnit(p.FeeMsat, msatUnit)
+	if p.FeeMsat == 0 {
+		cc.Fee = formatAmountUnit(p.Fee, satUnit)
+	}
+	cc.Preimage = p.PaymentPreimage
+	cc.PaymentRequest = p.PaymentRequest
+	cc.Hops = formatPaymentHops(p)
+	return cc
+}
+
+// formatPaymentHops lists the hops of the payment route, with the fee each
+// node charged and the amount it forwarded. Only the path nodes are known for
+// the payments without attempts.
+func formatPaymentHops(p *lncliPayment) string {
+	var hops []string
+
+	route := p.getRoute()
+	if route == nil {
+		for i, pubkey := range p.Path {
+			hops = append(hops, fmt.Sprintf("%d. %s\n%s", i+1, p.aliases[pubkey], pubkey))
+		}
+		return strings.Join(hops, "\n")
+	}
+
+	for i, h := range route.Hops {
+		hops = append(hops, context.printer.Sprintf("%d. %s, chan %s, fee %d mSat, forwards %d mSat\n%s",
+			i+1, p.aliases[h.PubKey], shortChannelID(h.ChanId), h.FeeMsat, h.AmtToForwardMsat, h.PubKey))
+	}
+	return strings.Join(hops, "\n")
+}
+
+type decodePayReqContainer struct {
+	PayReq string `displayname:"Pay req." length:"70" lines:"6" validate:"required,bolt11"`
+}
+
+type payReqDisplayContainer struct {
+	Destination     string `displayname:"Destination" length:"70" readonly:"1"`
+	Alias           string `displayname:"Alias" length:"70" readonly:"1"`
+	Amount          string `displayname:"Amount (sat)" length:"70" readonly:"1"`
+	Description     string `displayname:"Description" length:"70" lines:"3" readonly:"1"`
+	DescriptionHash string `displayname:"Description hash" length:"70" readonly:"1"`
+	PaymentHash     string `displayname:"Payment hash" length:"70" readonly:"1"`
+	Timestamp       string `displayname:"Created" length:"70" readonly:"1"`
+	Expiry          string `displayname:"Expires" length:"70" readonly:"1"`
+	CltvExpiry      string `displayname:"CLTV" length:"70" readonly:"1"`
+	FallbackAddr    string `displayname:"Fallback address" length:"70" readonly:"1"`
+	RouteHints      string `displayname:"Route hints" length:"70" lines:"4" readonly:"1"`
+	Warning         string `displayname:"Warning" length:"70" readonly:"1"`
+}
+
+func newPayReqDisplayContainer(pr *lncliPayReq) *payReqDisplayContainer {
+	cc := new(payReqDisplayContainer)
+	cc.Destination = pr.Destination
+	cc.Alias = pr.DestinationAlias
+	cc.Amount = formatAmountUnit(pr.NumSatoshis, satUnit)
+	if pr.NumMsat%1000 != 0 {
+		cc.Amount = formatAmountUnit(pr.NumMsat, msatUnit)
+	}
+	cc.Description = pr.Description
+	cc.DescriptionHash = pr.DescriptionHash
+	cc.PaymentHash = pr.PaymentHash
+	cc.Timestamp = time.Unix(pr.Timestamp, 0).Format("02-01-06 15:04:05")
+	cc.Expiry = pr.getExpiry().Format("02-01-06 15:04:05")
+	cc.CltvExpiry = context.printer.Sprintf("%d blocks", pr.CltvExpiry)
+	cc.FallbackAddr = pr.FallbackAddr
+	cc.RouteHints = pr.getRouteHints()
+	cc.Warning = strings.Join(pr.getWarnings(0), ", ")
+	return cc
+}
+
+// getPayReqSummary describes the payment request decoded in the pay invoice
+// form, for amt sat.
+func getPayReqSummary(pr *lncliPayReq, amt int64) string {
+	amount := pr.NumSatoshis
+	if amount == 0 {
+		amount = amt
+	}
+	summary := fmt.Sprintf("%s, %s, expires %s\n%s", pr.DestinationAlias, formatAmountUnit(amount, satUnit),
+		pr.getExpiry().Format("02-01-06 15:04:05"), pr.Description)
+	if warnings := pr.getWarnings(amt); len(warnings) > 0 {
+		summary += "\nWarning: " + strings.Join(warnings, ", ")
+	}
+	return summary
+}
+
+func newPayConfirmContainer(pr *lncliPayReq, req *lnrpc.SendRequest) *payConfirmContainer {
+	cc := new(payConfirmContainer)
+
+	cc.Destination = pr.DestinationAlias
+	if cc.Destination != pr.Destination {
+		cc.Destination += " " + pr.Destination
+	}
+
+	amount := pr.NumSatoshis
+	if amount == 0 {
+		amount = req.Amt
+	}
+	cc.Amount = formatAmountUnit(amount, satUnit)
+
+	cc.Description = pr.Description
+
+	expiry := time.Unix(pr.Timestamp+pr.Expiry, 0)
+	cc.Expiry = expiry.Format("02-01-06 15:04:05")
+	if time.Now().After(expiry) {
+		cc.Expiry += " expired"
+	}
+	cc.Warning = strings.Join(pr.getWarnings(req.Amt), ", ")
+
+	switch {
+	case req.FeeLimit.GetFixed() > 0:
+		cc.FeeLimit = formatAmountUnit(req.FeeLimit.GetFixed(), satUnit)
+	case req.FeeLimit.GetPercent() > 0:
+		cc.FeeLimit = fmt.Sprintf("%d%%", req.FeeLimit.GetPercent())
+	default:
+		cc.FeeLimit = "lnd default"
+	}
+
+	return cc
+}
+
+// displayPaymentConfirmation shows the payment about to be sent, calling
+// confirmed when validated.
+func displayPaymentConfirmation(cc *payConfirmContainer, confirmed func()) {
+
+	form := newFormEdit("payConfirm", "Confirm payment", cc)
+
+	form.callback = func(valid bool) {
+		form.close(context.gocui)
+		form = nil
+		if valid {
+			confirmed()
+		}
+	}
+
+	form.switchActiveEditor(-1, context.gocui)
+	form.initialize(context.gocui)
+}
+
+// getSendingInfo describes the payments in flight, for the grid header.
+func getSendingInfo(sending []*paymentInFlight) string {
+	if len(sending) == 0 {
+		return ""
+	}
+	return context.printer.Sprintf("  Sending %d payment(s) for %ds", len(sending), int(time.Since(sending[0].started).Seconds()))
+}
+
 // type disconnectPeer struct {
 // 	NodeAlias string `displayname:"Node alias" length:"32" readonly:"1"`
 // 	PubKey    string `displayname:"Pub key" length:"32" readonly:"1" lines:"3"`
@@ -40,22 +243,49 @@ func (cv *paymentListView) init(fmtnormal string, fmtheader string, fmtselected
 	cv.shortcuts = nil
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Pay Invoice", "P", 'p', gocui.ModAlt, cv.payInvoice, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Decode pay req", "E", 'e', gocui.ModAlt, cv.decodePayReq, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Send to node", "K", 'k', gocui.ModAlt, cv.sendToNode, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Probe route", "Q", 'q', gocui.ModAlt, cv.probeRoute, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Details", "D", 'd', gocui.ModAlt, cv.details, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Newer", "PgUp", gocui.KeyPgup, gocui.ModNone, func() { cv.page(false) }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Older", "PgDn", gocui.KeyPgdn, gocui.ModNone, func() { cv.page(true) }, false, ""})
 
 	cv.grid.key = "payments"
 	cv.grid.addColumn("Creation", "CreationDate", dateRow)      //"Creation",18
 	cv.grid.addColumn("Hash", "PaymentHash", stringRow)         //"Hash", 0
-	cv.grid.addColumn("Value", "ValueMsat", intRow)             //"Value mSat",16
-	cv.grid.addColumn("Fee", "Fee", intRow)                     //"Fee",16
+	cv.grid.addAmountColumn("Value", "ValueMsat", msatUnit)     //"Value",16
+	cv.grid.addAmountColumn("Fee", "Fee", satUnit)              //"Fee",16
 	cv.grid.addColumn("Preimage", "PaymentPreimage", stringRow) //"Preimage",6
-	cv.grid.addColumn("Path", "Path", sliceRow)                 //"Path",0
+	cv.grid.addColumn("Path", "PathAliases", stringRow)         //"Path",0
+	cv.grid.addColumn("PathKeys", "Path", sliceRow)             //"Path keys",0
+	cv.grid.addColumn("Hops", "HopCount", intRow)               //"Hops",5
+	cv.grid.addFiatColumn("ValueFiat", "ValueMsat", msatUnit)
 	cv.grid.initConfig()
 }
 
 func (cv *paymentListView) payInvoice() {
 	cc := new(payInvoiceContainer)
 
-	cv.form = newFormEdit("payInvoiceVal", "Pay invoice", cc)
+	form := newFormEdit("payInvoiceVal", "Pay invoice", cc)
+	cv.form = form
+
+	// the payment request is decoded once entered
+	cv.form.changed = func(name string) {
+		if name != "PayReq" {
+			return
+		}
+		form.getValue()
+		if validateBolt11(cc.PayReq) != nil {
+			return
+		}
+		payReq, amt := cc.PayReq, int64(cc.Amount)
+		status.store.background(func() {
+			pr, err := status.decodePayReq(&context, payReq)
+			runInGui(func() { cv.setDecoded(form, pr, amt, err) })
+		})
+	}
 
 	cv.form.callback = func(valid bool) {
 		cv.form.getValue()
@@ -73,6 +303,136 @@ func (cv *paymentListView) payInvoice() {
 	cv.form.initialize(context.gocui)
 }
 
+func (cv *paymentListView) sendToNode() {
+	cv.keysend("")
+}
+
+// keysend opens the form sending a payment without invoice to the node,
+// prefilled with its pubkey when given.
+func (cv *paymentListView) keysend(pubkey string) {
+	cc := new(keysendContainer)
+	cc.PubKey = pubkey
+	cv.openKeysend(cc)
+}
+
+func (cv *paymentListView) openKeysend(cc *keysendContainer) {
+	cv.form = newFormEdit("keysendVal", "Send to node", cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.getValue()
+		cv.form.close(context.gocui)
+		cv.form = nil
+		if valid {
+			outgoing, _ := parseShortChannelID(cc.OutgoingChan)
+			err := status.keysend(&context, cc.PubKey, cc.Amount, cc.FeeLimit, cc.FeeLimitPerc, cc.Message, outgoing)
+			if err != nil {
+				logError(err.Error())
+				displayMessage("Error : "+err.Error(), nil)
+			}
+		}
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
+func (cv *paymentListView) getSelectedPayment() *lncliPayment {
+	return cv.grid.getSelectedItem().Interface().(*lncliPayment)
+}
+
+func (cv *paymentListView) details() {
+	if len(status.state().payments.payments) == 0 {
+		return
+	}
+	p := cv.getSelectedPayment()
+	if p == nil {
+		return
+	}
+
+	cv.form = newFormEdit("paymentDetailsVal", "Payment details", newPaymentDetailsContainer(p))
+
+	cv.form.callback = func(valid bool) {
+		cv.form.close(context.gocui)
+		cv.form = nil
+	}
+
+	cv.form.initialize(context.gocui)
+	cv.form.switchActiveEditor(-1, context.gocui)
+}
+
+// probeRoute switches to the route view to probe the routes to a node.
+func (cv *paymentListView) probeRoute() {
+	switchActiveView(routeListViewt)
+	context.views[routeListViewt].(*routeListView).probe()
+}
+
+// keysendTo switches to the payment view to send a payment to the node.
+func keysendTo(pubkey string) {
+	switchActiveView(paymentListViewt)
+	context.views[paymentListViewt].(*paymentListView).keysend(pubkey)
+}
+
+// setDecoded shows the payment request decoded in the pay invoice form,
+// unless closed since.
+func (cv *paymentListView) setDecoded(form *formEdit, pr *lncliPayReq, amt int64, err error) {
+	if cv.form != form {
+		return
+	}
+	if err != nil {
+		form.setValue("Decoded", "Error: "+err.Error())
+	} else {
+		form.setValue("Decoded", getPayReqSummary(pr, amt))
+	}
+	form.layout(context.gocui)
+}
+
+func (cv *paymentListView) decodePayReq() {
+	cc := new(decodePayReqContainer)
+
+	cv.form = newFormEdit("decodePayReqVal", "Decode pay req", cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.getValue()
+		cv.form.close(context.gocui)
+		cv.form = nil
+		if !valid {
+			return
+		}
+		status.store.background(func() {
+			pr, err := status.decodePayReq(&context, cc.PayReq)
+			runInGui(func() {
+				if err != nil {
+					logError(err.Error())
+					displayMessage("Error: "+err.Error(), nil)
+					return
+				}
+				cv.displayPayReq(pr)
+			})
+		})
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
+func (cv *paymentListView) displayPayReq(pr *lncliPayReq) {
+	cv.form = newFormEdit("payReqDetailsVal", "Payment request", newPayReqDisplayContainer(pr))
+
+	cv.form.callback = func(valid bool) {
+		cv.form.close(context.gocui)
+		cv.form = nil
+	}
+
+	cv.form.initialize(context.gocui)
+	cv.form.switchActiveEditor(-1, context.gocui)
+}
+
+// page moves the list to the older or to the newer payments.
+func (cv *paymentListView) page(older bool) {
+	status.store.background(func() {
+		manageError(status.pagePayments(&context, older))
+		refreshView()
+	})
+}
+
 // func (cv *paymentListView) getSelectedPeer() *lncliPeer {
 // 	return cv.grid.getSelectedItem().Interface().(*lncliPeer)
 // }
@@ -84,6 +444,9 @@ func (cv *paymentListView) refreshView(g *gocui.Gui) {
 	}
 	v.Clear()
 	x, y := v.Size()
+	st := status.state()
+	cv.grid.items = st.payments.payments
+	cv.grid.info = st.payments.page.getInfo("Payments") + getSendingInfo(st.sending)
 	cv.grid.setRenderSize(x, y)
 	for _, row := range cv.grid.getGridRows() {
 		fmt.Fprintln(v, row)
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+func TestPaymentListViewRender(t *testing.T) {
+	setupTestContext(t)
+
+	activateView(paymentListViewt)
+	updateData()
+
+	lines := renderMainView(t)
+
+	assertLine(t, lines, "[Payments]")
+	assertLine(t, lines, "0101010101", "│10,000         │")
+}
+
+func TestPayInvoiceForm(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[paymentListViewt].(*paymentListView)
+	cv.payInvoice()
+
+	typeInForm(t, cv.form, "PayReq", "lntb1fakeinvoice")
+	typeInForm(t, cv.form, "FeeLimit", "10")
+	toggleInForm(t, cv.form, "Force")
+
+	cv.form.callback(true)
+
+	waitFor(t, "payment", func() bool { return fb.callCount("payinvoice") == 1 })
+	if fb.callCount("decodepayreq") != 1 {
+		t.Error("payment request not decoded")
+	}
+
+	fb.mutex.Lock()
+	defer fb.mutex.Unlock()
+	if fb.lastPayment.PaymentRequest != "lntb1fakeinvoice" || fb.lastPayment.FeeLimit.GetFixed() != 10 {
+		t.Errorf("unexpected payment %+v", fb.lastPayment)
+	}
+}
+
+func TestPayInvoiceFormValidation(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[paymentListViewt].(*paymentListView)
+	cv.payInvoice()
+
+	typeInForm(t, cv.form, "PayReq", "lntb1fakeinvoice")
+	cv.form.submit(true)
+
+	if e := getTestEditor(t, cv.form, "PayReq").getError(); e != "invalid payment request" {
+		t.Errorf("pay req error = %q", e)
+	}
+
+	typeInForm(t, cv.form, "PayReq", testBolt11)
+	typeInForm(t, cv.form, "FeeLimit", "10")
+	typeInForm(t, cv.form, "FeeLimitPerc", "2")
+	cv.form.submit(true)
+
+	if e := getTestEditor(t, cv.form, "FeeLimitPerc").getError(); e != "fee limit or percentage" {
+		t.Errorf("fee limit perc error = %q", e)
+	}
+	if e := getTestEditor(t, cv.form, "PayReq").getError(); e != "" {
+		t.Errorf("pay req error not cleared, %q", e)
+	}
+
+	cv.form.submit(false)
+
+	if cv.form != nil || fb.callCount("payinvoice") != 0 {
+		t.Error("cancel not closing the form without paying")
+	}
+}
+
+func TestPaymentPaging(t *testing.T) {
+	fb := setupTestContext(t)
+	fb.payments.Payments = nil
+	for i := 1; i <= 12; i++ {
+		fb.payments.Payments = append(fb.payments.Payments, &lnrpc.Payment{PaymentHash: fmt.Sprintf("hash%02d", i), ValueMsat: int64(i), PaymentIndex: uint64(i)})
+	}
+
+	defer func(size uint64) { listPageSize = size }(listPageSize)
+	listPageSize = 5
+
+	activateView(paymentListViewt)
+	lines := updateTestData(t)
+	assertLine(t, lines, "[Payments]", "Payments 8 to 12 of 12")
+	if req := fb.lastListPayments; !req.Reversed || req.MaxPayments != 5 {
+		t.Errorf("unexpected listpayments request %+v", req)
+	}
+
+	cv := context.views[paymentListViewt].(*paymentListView)
+	cv.page(true)
+	status.store.wait()
+	lines = renderMainView(t)
+	assertLine(t, lines, "[Payments]", "Payments 3 to 7 of 12")
+	assertLine(t, lines, "hash03")
+
+	cv.page(false)
+	status.store.wait()
+	assertLine(t, renderMainView(t), "[Payments]", "Payments 8 to 12 of 12")
+}
+
+func TestPayConfirmation(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	pr, err := status.decodePayReq(&context, "lntb250u1fakecoffee")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &lnrpc.SendRequest{FeeLimit: &lnrpc.FeeLimit{Limit: &lnrpc.FeeLimit_Percent{Percent: 2}}}
+	cc := newPayConfirmContainer(pr, req)
+	if cc.Destination != "yalls "+testPubkey(3) || cc.Amount != "25,000 sat" || cc.Description != "coffee" || cc.FeeLimit != "2%" {
+		t.Errorf("unexpected confirmation %+v", cc)
+	}
+	if strings.Contains(cc.Expiry, "expired") || cc.Warning != "" {
+		t.Errorf("valid invoice expired, %s %s", cc.Expiry, cc.Warning)
+	}
+
+	pr.NumSatoshis = 0
+	pr.Timestamp = time.Now().Unix() - 7200
+	cc = newPayConfirmContainer(pr, &lnrpc.SendRequest{Amt: 1000})
+	if cc.Amount != "1,000 sat" || !strings.HasSuffix(cc.Expiry, "expired") || cc.FeeLimit != "lnd default" || cc.Warning != "expired" {
+		t.Errorf("unexpected confirmation %+v", cc)
+	}
+}
+
+func TestDecodePayReq(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	fb.payReq.RouteHints = []*lnrpc.RouteHint{{HopHints: []*lnrpc.HopHint{
+		{NodeId: testPubkey(2), ChanId: 1, FeeBaseMsat: 1000, FeeProportionalMillionths: 10, CltvExpiryDelta: 40},
+		{NodeId: testPubkey(9), ChanId: 2, FeeBaseMsat: 0, FeeProportionalMillionths: 1, CltvExpiryDelta: 144},
+	}}}
+
+	pr, err := status.decodePayReq(&context, testBolt11)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cc := newPayReqDisplayContainer(pr)
+	if cc.Destination != testPubkey(3) || cc.Alias != "yalls" || cc.Amount != "25,000 sat" || cc.Description != "coffee" || cc.CltvExpiry != "40 blocks" {
+		t.Errorf("unexpected payment request %+v", cc)
+	}
+	want := "bitrefill 0x0x1 base 1,000 mSat, rate 10 ppm, time lock delta 40 > " +
+		testPubkey(9) + " 0x0x2 base 0 mSat, rate 1 ppm, time lock delta 144"
+	if cc.RouteHints != want {
+		t.Errorf("route hints = %q, want %q", cc.RouteHints, want)
+	}
+	if cc.Warning != "" {
+		t.Errorf("unexpected warning %q", cc.Warning)
+	}
+
+	pr.NumSatoshis = 0
+	pr.Timestamp = time.Now().Unix() - 7200
+	if cc = newPayReqDisplayContainer(pr); cc.Warning != "expired, no amount, one must be given" {
+		t.Errorf("warning = %q", cc.Warning)
+	}
+}
+
+func TestPayInvoiceFormDecoded(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	cv := context.views[paymentListViewt].(*paymentListView)
+	cv.payInvoice()
+	form := cv.form
+
+	typeInForm(t, form, "PayReq", testBolt11)
+	form.switchActiveEditor(1, context.gocui)
+	waitFor(t, "decoding", func() bool { return fb.callCount("decodepayreq") == 1 })
+
+	pr, err := status.decodePayReq(&context, testBolt11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv.setDecoded(form, pr, 0, nil)
+
+	decoded := getTestEditor(t, form, "Decoded").getValue().(string)
+	if !strings.HasPrefix(decoded, "yalls, 25,000 sat, expires ") || !strings.HasSuffix(decoded, "\ncoffee") {
+		t.Errorf("decoded = %q", decoded)
+	}
+
+	pr.NumSatoshis = 0
+	cv.setDecoded(form, pr, 0, nil)
+	if decoded = getTestEditor(t, form, "Decoded").getValue().(string); !strings.HasSuffix(decoded, "Warning: no amount, one must be given") {
+		t.Errorf("decoded = %q", decoded)
+	}
+
+	cv.setDecoded(form, nil, 0, errors.New("invoice not for current active network"))
+	if decoded = getTestEditor(t, form, "Decoded").getValue().(string); decoded != "Error: invoice not for current active network" {
+		t.Errorf("decoded = %q", decoded)
+	}
+
+	form.callback(false)
+	cv.setDecoded(form, pr, 0, nil)
+	if cv.form != nil {
+		t.Error("closed form updated")
+	}
+}
+
+func TestSendPayment(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	fb.paymentDone = make(chan struct{})
+	fb.paymentRoute = &lnrpc.Route{
+		TotalFeesMsat: 1002,
+		Hops:          []*lnrpc.Hop{{PubKey: testPubkey(1)}, {PubKey: testPubkey(3)}},
+	}
+
+	pr, _ := status.decodePayReq(&context, "lntb250u1fakecoffee")
+
+	var msg string
+	status.store.background(func() { msg = status.sendPayment(&context, &lnrpc.SendRequest{}, pr.DestinationAlias, pr.NumSatoshis) })
+
+	waitFor(t, "payment in flight", func() bool { return len(status.state().sending) == 1 })
+
+	activateView(paymentListViewt)
+	assertLine(t, renderMainView(t), "[Payments]", "Sending 1 payment(s)")
+
+	if err := status.updatePaymentList(&context); err != nil {
+		t.Error("payment list not updated while the payment is in flight")
+	}
+
+	polls := fb.callCount("listpayments")
+	close(fb.paymentDone)
+	status.store.wait()
+
+	if len(status.state().sending) != 0 {
+		t.Error("completed payment still in flight")
+	}
+	if fb.callCount("listpayments") != polls+1 {
+		t.Error("payments not updated after the payment")
+	}
+	for _, s := range []string{"Payment sent", "Amount: 25,000 sat", "Fee: 1.002 sat", "Route: acinq > yalls", "Preimage: 010203"} {
+		if !strings.Contains(msg, s) {
+			t.Errorf("%q missing from %q", s, msg)
+		}
+	}
+}
+
+func TestSendPaymentFailure(t *testing.T) {
+	fb := setupTestContext(t)
+	fb.paymentError = "unable to find a path to destination"
+	if msg := status.sendPayment(&context, &lnrpc.SendRequest{}, "yalls", 1000); msg != "Payment failed\nunable to find a path to destination" {
+		t.Errorf("unexpected message %q", msg)
+	}
+
+	fb.err = errors.New("lnd unavailable")
+	if msg := status.sendPayment(&context, &lnrpc.SendRequest{}, "yalls", 1000); msg != "Payment failed\nlnd unavailable" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestKeysend(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	if err := status.keysend(&context, testPubkey(3), 1000, 0, 1, "thanks", 0); err != nil {
+		t.Fatal(err)
+	}
+	status.store.wait()
+
+	fb.mutex.Lock()
+	req := fb.lastPayment
+	fb.mutex.Unlock()
+
+	if hex.EncodeToString(req.Dest) != testPubkey(3) || req.Amt != 1000 || req.FinalCltvDelta != 40 || req.FeeLimit.GetPercent() != 1 {
+		t.Errorf("unexpected payment %+v", req)
+	}
+	if hash := sha256.Sum256(req.DestCustomRecords[keysendRecordType]); !bytes.Equal(hash[:], req.PaymentHash) {
+		t.Error("payment hash not matching the preimage record")
+	}
+	if m := string(req.DestCustomRecords[messageRecordType]); m != "thanks" {
+		t.Errorf("message record = %q", m)
+	}
+	if fb.callCount("listpayments") != 1 {
+		t.Error("payments not updated after the payment")
+	}
+
+	if err := status.keysend(&context, testPubkey(3), 1000, 0, 0, "", 0); err != nil {
+		t.Fatal(err)
+	}
+	status.store.wait()
+
+	fb.mutex.Lock()
+	defer fb.mutex.Unlock()
+	if len(fb.lastPayment.DestCustomRecords) != 1 || fb.lastPayment.FeeLimit != nil {
+		t.Errorf("unexpected payment %+v", fb.lastPayment)
+	}
+}
+
+func TestKeysendFromPeer(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	activateView(peerListViewt)
+	updateTestData(t)
+
+	context.views[peerListViewt].(*peerListView).sendToNode()
+
+	if context.activeMainView != paymentListViewt {
+		t.Fatal("payment view not activated")
+	}
+	cv := context.views[paymentListViewt].(*paymentListView)
+	if pk := getTestEditor(t, cv.form, "PubKey").getValue(); pk != status.state().peers[0].PubKey {
+		t.Errorf("pub key = %v", pk)
+	}
+
+	cv.form.submit(true)
+	if e := getTestEditor(t, cv.form, "Amount").getError(); e != "required" {
+		t.Errorf("amount error = %q", e)
+	}
+
+	typeInForm(t, cv.form, "Amount", "500")
+	typeInForm(t, cv.form, "FeeLimit", "10")
+	typeInForm(t, cv.form, "FeeLimitPerc", "2")
+	cv.form.submit(true)
+	if e := getTestEditor(t, cv.form, "FeeLimitPerc").getError(); e != "fee limit or percentage" {
+		t.Errorf("fee limit perc error = %q", e)
+	}
+
+	typeInForm(t, cv.form, "FeeLimitPerc", "0")
+	cv.form.submit(true)
+	status.store.wait()
+
+	if cv.form != nil || fb.callCount("payinvoice") != 1 {
+		t.Error("keysend not sent")
+	}
+}
+
+func TestPaymentPathAliases(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	activateView(paymentListViewt)
+	updateData()
+	status.store.wait()
+	lines := renderMainView(t)
+
+	assertLine(t, lines, "Hops", "Path")
+	assertLine(t, lines, "0101010101", "│2   │acinq > yalls")
+	if _, ok := findLine(lines, testPubkey(1)[:20]); ok {
+		t.Error("path pubkeys displayed")
+	}
+}
+
+func TestPaymentDetails(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	p := fb.payments.Payments[0]
+	p.Status = lnrpc.Payment_SUCCEEDED
+	p.FeeMsat = 1002
+	p.Htlcs = []*lnrpc.HTLCAttempt{
+		{Status: lnrpc.HTLCAttempt_FAILED, Route: &lnrpc.Route{Hops: []*lnrpc.Hop{{PubKey: testPubkey(2)}, {PubKey: testPubkey(3)}}}},
+		{Status: lnrpc.HTLCAttempt_SUCCEEDED, Route: &lnrpc.Route{Hops: []*lnrpc.Hop{
+			{ChanId: 659767854661451776, PubKey: testPubkey(1), FeeMsat: 1002, AmtToForwardMsat: 10000000},
+			{ChanId: 1, PubKey: testPubkey(3), AmtToForwardMsat: 10000000},
+		}}},
+	}
+
+	activateView(paymentListViewt)
+	updateData()
+	status.store.wait()
+	renderMainView(t)
+
+	cv := context.views[paymentListViewt].(*paymentListView)
+	cv.details()
+	if cv.form == nil {
+		t.Fatal("details not displayed")
+	}
+
+	for name, want := range map[string]string{
+		"Status":        "succeeded",
+		"FailureReason": "",
+		"Value":         "10,000 sat",
+		"Fee":           "1.002 sat",
+		"Hops": "1. acinq, chan " + shortChannelID(659767854661451776) + ", fee 1,002 mSat, forwards 10,000,000 mSat\n" + testPubkey(1) + "\n" +
+			"2. yalls, chan 0x0x1, fee 0 mSat, forwards 10,000,000 mSat\n" + testPubkey(3),
+	} {
+		if v := getTestEditor(t, cv.form, name).getValue(); v != want {
+			t.Errorf("%s = %q, want %q", name, v, want)
+		}
+	}
+
+	failed := &lncliPayment{Payment: lnrpc.Payment{Status: lnrpc.Payment_FAILED, FailureReason: lnrpc.PaymentFailureReason_FAILURE_REASON_NO_ROUTE, Path: []string{testPubkey(3)}}}
+	failed.updatePathAliases(&context, &status)
+	cc := newPaymentDetailsContainer(failed)
+	if cc.Status != "failed" || cc.FailureReason != "no route" || cc.Hops != "1. yalls\n"+testPubkey(3) {
+		t.Errorf("unexpected failed payment details %+v", cc)
+	}
+}
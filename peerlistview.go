@@ -3,6 +3,10 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/jroimartin/gocui"
 )
@@ -13,9 +17,17 @@ type peerListView struct {
 }
 
 type connectPeer struct {
-	PubKey string `displayname:"Pub key" length:"40" lines:"2"`
-	Host   string `displayname:"Host" length:"16"`
-	Port   int    `displayname:"Port" length:"5"`
+	PubKey string `displayname:"Pub key" length:"40" lines:"2" validate:"required,pubkey"`
+	Host   string `displayname:"Host" length:"16" validate:"required"`
+	Port   int    `displayname:"Port" length:"5" validate:"required,min=1,max=65535"`
+}
+
+func (c *connectPeer) validate() map[string]string {
+	errs := make(map[string]string)
+	if err := validateHostPort(net.JoinHostPort(c.Host, strconv.Itoa(c.Port))); err != nil {
+		errs["Host"] = err.Error()
+	}
+	return errs
 }
 
 type disconnectPeer struct {
@@ -23,6 +35,10 @@ type disconnectPeer struct {
 	PubKey    string `displayname:"Pub key" length:"32" readonly:"1" lines:"3"`
 }
 
+type disconnectPeers struct {
+	Peers string `displayname:"Peers" length:"40" readonly:"1" lines:"8"`
+}
+
 func newpeerListView(physicalView string, fmtnormal string, fmtheader string, fmtselected string) *peerListView {
 	cv := new(peerListView)
 
@@ -40,21 +56,25 @@ func (cv *peerListView) init(fmtnormal string, fmtheader string, fmtselected str
 	cv.grid.fmtHeader = fmtheader
 	cv.grid.fmtSelected = fmtselected
 
+	cv.grid.setMarkable("PubKey")
+
 	cv.shortcuts = nil
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Connect", "C", 'c', gocui.ModAlt, cv.connect, true, ""})
-	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Disconnect", "D", 'd', gocui.ModAlt, cv.disconnect, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Disconnect", "D", 'd', gocui.ModAlt, cv.grid.selectionAction(cv.disconnect), true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Send to node", "K", 'k', gocui.ModAlt, cv.sendToNode, true, ""})
 
 	cv.grid.key = "peers"
-	cv.grid.addColumn("Alias", "Alias", stringRow)      // "Alias", 0
-	cv.grid.addColumn("Address", "Address", stringRow)  //"Address", 22
-	cv.grid.addColumn("BytesSent", "BytesSent", intRow) //"Bytes sent",13
-	cv.grid.addColumn("BytesRec", "BytesRecv", intRow)  //"Bytes rec.",13
-	cv.grid.addColumn("SatSent", "SatSent", intRow)     //"Sat sent", 12
-	cv.grid.addColumn("SatRec", "SatRecv", intRow)      //"Sat rec.", 12
-	cv.grid.addColumn("Inbound", "Inbound", boolRow)    //"Inbound",2
-	cv.grid.addColumn("Ping", "PingTime", intRow)       //"Ping",6
+	cv.grid.addColumn("Alias", "Alias", stringRow)         // "Alias", 0
+	cv.grid.addColumn("Address", "Address", stringRow)     //"Address", 22
+	cv.grid.addColumn("BytesSent", "BytesSent", intRow)    //"Bytes sent",13
+	cv.grid.addColumn("BytesRec", "BytesRecv", intRow)     //"Bytes rec.",13
+	cv.grid.addAmountColumn("SatSent", "SatSent", satUnit) //"Sat sent", 12
+	cv.grid.addAmountColumn("SatRec", "SatRecv", satUnit)  //"Sat rec.", 12
+	cv.grid.addColumn("Inbound", "Inbound", boolRow)       //"Inbound",2
+	cv.grid.addColumn("Ping", "PingTime", intRow)          //"Ping",6
 	cv.grid.initConfig()
 }
 
@@ -83,9 +103,22 @@ func (cv *peerListView) getSelectedPeer() *lncliPeer {
 	return cv.grid.getSelectedItem().Interface().(*lncliPeer)
 }
 
-func (cv *peerListView) disconnect() {
+func (cv *peerListView) sendToNode() {
+	if c := cv.getSelectedPeer(); c != nil {
+		keysendTo(c.PubKey)
+	}
+}
+
+// disconnect disconnects the marked peers, or the selected one, once
+// confirmed.
+func (cv *peerListView) disconnect(items []reflect.Value) {
 
-	c := cv.getSelectedPeer()
+	if len(items) > 1 {
+		cv.disconnectMany(items)
+		return
+	}
+
+	c := items[0].Interface().(*lncliPeer)
 
 	cc := new(disconnectPeer)
 
@@ -107,6 +140,41 @@ func (cv *peerListView) disconnect() {
 	cv.form.initialize(context.gocui)
 }
 
+func (cv *peerListView) disconnectMany(items []reflect.Value) {
+
+	var peers []*lncliPeer
+	var names []string
+	for _, item := range items {
+		p := item.Interface().(*lncliPeer)
+		peers = append(peers, p)
+		names = append(names, getPeerName(p))
+	}
+
+	cc := new(disconnectPeers)
+	cc.Peers = strings.Join(names, "\n")
+
+	cv.form = newFormEdit("disconnectPeersForm", fmt.Sprintf("Disconnect %d peers", len(peers)), cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.close(context.gocui)
+		cv.form = nil
+		cc = nil
+		if valid {
+			cv.grid.clearMarks()
+			view := context.activeMainView
+			status.store.background(func() {
+				failures := status.disconnectPeers(&context, peers)
+				updateViewData(view)
+				if len(failures) > 0 {
+					runInGui(func() { displayMessage("Error: "+strings.Join(failures, "\n"), nil) })
+				}
+			})
+		}
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
 func (cv *peerListView) refreshView(g *gocui.Gui) {
 	v, err := g.View(cv.mappedToPhysicalView)
 	if err != nil {
@@ -117,6 +185,7 @@ func (cv *peerListView) refreshView(g *gocui.Gui) {
 
 	x, y := v.Size()
 
+	cv.grid.items = status.state().peers
 	cv.grid.setRenderSize(x, y)
 
 	for _, row := range cv.grid.getGridRows() {
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPeerListViewRender(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	activateView(peerListViewt)
+	updateData()
+
+	waitFor(t, "peer aliases", func() bool {
+		return len(status.state().peers) == 2 && status.state().peers[1].Alias == "bitrefill"
+	})
+
+	lines := renderMainView(t)
+
+	assertLine(t, lines, "[Peers]")
+	assertLine(t, lines, "acinq", "203.0.113.1:9735", "1,024", "2,048")
+	assertLine(t, lines, "bitrefill", "203.0.113.2:9735")
+}
+
+func TestConnectPeerForm(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[peerListViewt].(*peerListView)
+	cv.connect()
+
+	typeInForm(t, cv.form, "PubKey", testPubkey(3))
+	typeInForm(t, cv.form, "Host", "203.0.113.3")
+
+	cv.form.callback(true)
+
+	if fb.lastConnect != testPubkey(3)+"@203.0.113.3:9735" {
+		t.Errorf("connect %q", fb.lastConnect)
+	}
+}
+
+func TestDisconnectPeerForm(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	activateView(peerListViewt)
+	updateTestData(t)
+
+	cv := context.views[peerListViewt].(*peerListView)
+	cv.grid.selectionAction(cv.disconnect)()
+	if cv.form.title != " Disconnect peer " {
+		t.Errorf("form %q, want the single peer form", cv.form.title)
+	}
+	cv.form.callback(true)
+
+	if fb.lastDisconnect != testPubkey(1) {
+		t.Errorf("disconnect %q", fb.lastDisconnect)
+	}
+}
+
+func TestDisconnectPeers(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	activateView(peerListViewt)
+	updateData()
+	waitFor(t, "peer aliases", func() bool {
+		return len(status.state().peers) == 2 && status.state().peers[1].Alias == "bitrefill"
+	})
+
+	peers := status.state().peers
+	cv := context.views[peerListViewt].(*peerListView)
+	renderMainView(t)
+	cv.grid.toggleMarkAll()
+	assertLine(t, renderMainView(t), "[Peers]", "Marked: 2")
+
+	cv.grid.selectionAction(cv.disconnect)()
+	if cv.form == nil || cv.form.title != " Disconnect 2 peers " {
+		t.Fatal("bulk disconnect form not opened")
+	}
+	if v := getTestEditor(t, cv.form, "Peers").getValue(); v != "acinq\nbitrefill" {
+		t.Errorf("peers %q", v)
+	}
+	cv.form.callback(true)
+	status.store.wait()
+
+	if fb.callCount("disconnect") != 2 {
+		t.Errorf("%d peers disconnected", fb.callCount("disconnect"))
+	}
+	if len(cv.grid.marked) != 0 {
+		t.Error("peers still marked after disconnecting")
+	}
+
+	fb.setError(errors.New("peer not found"))
+	failures := status.disconnectPeers(&context, peers)
+	if len(failures) != 2 || failures[0] != "acinq: peer not found" {
+		t.Errorf("failures %q", failures)
+	}
+}
+
+func TestConnectPeerFormValidation(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[peerListViewt].(*peerListView)
+	cv.connect()
+
+	typeInForm(t, cv.form, "PubKey", testPubkey(3))
+	typeInForm(t, cv.form, "Port", "0")
+	cv.form.submit(true)
+
+	if e := getTestEditor(t, cv.form, "Host").getError(); e != "required" {
+		t.Errorf("host error = %q", e)
+	}
+	if e := getTestEditor(t, cv.form, "Port").getError(); e != "required" {
+		t.Errorf("port error = %q", e)
+	}
+	if cv.form.selectedEditorIndex != 1 {
+		t.Errorf("focus on editor %d", cv.form.selectedEditorIndex)
+	}
+
+	typeInForm(t, cv.form, "Host", "203.0.113.3")
+	typeInForm(t, cv.form, "Port", "9736")
+	cv.form.submit(true)
+
+	if fb.lastConnect != testPubkey(3)+"@203.0.113.3:9736" {
+		t.Errorf("connect %q", fb.lastConnect)
+	}
+}
@@ -75,14 +75,15 @@ func (cv *pendingchannelListView) init(fmtnormal string, fmtheader string, fmtse
 	cv.shortcuts = nil
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Details channel", "D", 'd', gocui.ModAlt, cv.detailsChannel, true, ""})
 
 	cv.grid.key = "pendingChannels"
-	cv.grid.addColumn("Type", "GetType", stringRow)         //"Type",2
-	cv.grid.addColumn("Node", "NodeAlias", stringRow)       //"Node", 0
-	cv.grid.addColumn("Capacity", "GetCapacity", intRow)    //"Capacity", 10
-	cv.grid.addColumn("Local", "GetLocalBalance", intRow)   //"Local",10
-	cv.grid.addColumn("Remote", "GetRemoteBalance", intRow) //"Remote",10
+	cv.grid.addColumn("Type", "GetType", stringRow)                //"Type",2
+	cv.grid.addColumn("Node", "NodeAlias", stringRow)              //"Node", 0
+	cv.grid.addAmountColumn("Capacity", "GetCapacity", satUnit)    //"Capacity", 10
+	cv.grid.addAmountColumn("Local", "GetLocalBalance", satUnit)   //"Local",10
+	cv.grid.addAmountColumn("Remote", "GetRemoteBalance", satUnit) //"Remote",10
 	cv.grid.initConfig()
 }
 
@@ -214,6 +215,7 @@ func (cv *pendingchannelListView) refreshView(g *gocui.Gui) {
 
 	x, y := v.Size()
 
+	cv.grid.items = status.state().pendingchannels.pendingChannels
 	cv.grid.setRenderSize(x, y)
 
 	for _, row := range cv.grid.getGridRows() {
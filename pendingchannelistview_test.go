@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestPendingChannelListViewRender(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	activateView(pendingChannelListViewt)
+	updateData()
+
+	waitFor(t, "pending channel aliases", func() bool {
+		for _, c := range status.state().pendingchannels.pendingChannels {
+			if len(c.NodeAlias) == 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	lines := renderMainView(t)
+
+	assertLine(t, lines, "[Pending channels]")
+	assertLine(t, lines, "C", "acinq", "100,000", "50,000")
+	assertLine(t, lines, "F", "bitrefill", "400,000")
+	assertLine(t, lines, "O", "yalls", "300,000")
+}
+
+func TestPendingChannelDetailsForm(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	activateView(pendingChannelListViewt)
+	updateTestData(t)
+
+	cv := context.views[pendingChannelListViewt].(*pendingchannelListView)
+	cv.grid.moveSelectionDown()
+	cv.detailsChannel()
+
+	cc := cv.form.toMap.(*pendingForceClosingChannelDisplayContainer)
+	if cc.LimboBalance != "250,000" || cc.BlocksTilMaturity != "120" {
+		t.Errorf("unexpected details %+v", cc)
+	}
+
+	cv.form.callback(false)
+	if cv.form != nil {
+		t.Error("form still open")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// priceProvider gives the price of a bitcoin in a fiat currency.
+type priceProvider interface {
+	getPrice(currency string) (float64, error)
+}
+
+// staticPriceProvider is a fixed rate, set in config.json.
+type staticPriceProvider struct {
+	rate float64
+}
+
+func (p *staticPriceProvider) getPrice(currency string) (float64, error) {
+	if p.rate <= 0 {
+		return 0, errors.New("no fiat rate set")
+	}
+	return p.rate, nil
+}
+
+// filePriceProvider reads the price from a local file, kept up to date by
+// another program.
+type filePriceProvider struct {
+	path string
+}
+
+func (p *filePriceProvider) getPrice(currency string) (float64, error) {
+	data, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return 0, err
+	}
+	return parsePrice(data, currency)
+}
+
+// httpPriceProvider queries the price from an HTTP endpoint, {currency} in
+// its url being replaced by the currency.
+type httpPriceProvider struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPPriceProvider(url string) *httpPriceProvider {
+	return &httpPriceProvider{url, &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *httpPriceProvider) getPrice(currency string) (float64, error) {
+	resp, err := p.client.Get(strings.Replace(p.url, "{currency}", currency, -1))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("price query failed, %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	return parsePrice(data, currency)
+}
+
+// parsePrice reads a price given as a number, or as a JSON object with the
+// price under the currency key, possibly nested in single entry objects, e.g.
+// {"EUR": 25000.5} or {"bitcoin": {"eur": 25000.5}}.
+func parsePrice(data []byte, currency string) (float64, error) {
+	if price, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64); err == nil {
+		return checkPrice(price)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return 0, errors.New("invalid price data")
+	}
+
+	for {
+		switch t := v.(type) {
+		case float64:
+			return checkPrice(t)
+		case string:
+			price, err := strconv.ParseFloat(t, 64)
+			if err != nil {
+				return 0, errors.New("invalid price data")
+			}
+			return checkPrice(price)
+		case map[string]interface{}:
+			next, found := findPriceEntry(t, currency)
+			if !found {
+				return 0, fmt.Errorf("no %s price", currency)
+			}
+			v = next
+		default:
+			return 0, errors.New("invalid price data")
+		}
+	}
+}
+
+// findPriceEntry returns the value under the currency key, else the object
+// nested in a single entry object. A single value under another currency
+// isn't taken.
+func findPriceEntry(m map[string]interface{}, currency string) (interface{}, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, currency) {
+			return v, true
+		}
+	}
+	if len(m) == 1 {
+		for _, v := range m {
+			if nested, ok := v.(map[string]interface{}); ok {
+				return nested, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func checkPrice(price float64) (float64, error) {
+	if !(price > 0) || math.IsInf(price, 0) {
+		return 0, errors.New("invalid price")
+	}
+	return price, nil
+}
+
+// newPriceProvider returns the price provider of the config.json fiat
+// source, nil when no fiat currency is set.
+func newPriceProvider() priceProvider {
+	if len(getFiatCurrency()) == 0 {
+		return nil
+	}
+
+	switch source := getConfigFiatSource(); source {
+	case "", "static":
+		return &staticPriceProvider{getConfigFiatRate()}
+	case "file":
+		return &filePriceProvider{getConfigFiatPath()}
+	case "http":
+		return newHTTPPriceProvider(getConfigFiatURL())
+	default:
+		logError(fmt.Sprintf("Unknown fiat source '%s'", source))
+		return nil
+	}
+}
+
+// lncliPrice is the last price of a bitcoin in the fiat currency.
+type lncliPrice struct {
+	currency string
+	rate     float64
+}
+
+// toFiat converts the amount of native unit to fiat, false when there's no
+// price.
+func (p lncliPrice) toFiat(amount int64, native amountUnit) (float64, bool) {
+	if !(p.rate > 0) || math.IsInf(p.rate, 0) {
+		return 0, false
+	}
+	btc := new(big.Rat).SetFrac64(amount*amountUnitScales[native], amountUnitScales[btcUnit])
+	f, _ := btc.Mul(btc, new(big.Rat).SetFloat64(p.rate)).Float64()
+	return f, true
+}
+
+// formatFiat formats the amount of native unit in fiat, empty when there's
+// no price.
+func (p lncliPrice) formatFiat(amount int64, native amountUnit) string {
+	f, ok := p.toFiat(amount, native)
+	if !ok {
+		return ""
+	}
+	return context.printer.Sprintf("%.2f", f)
+}
+
+func (s *lncliStatus) updatePrice(ctxt *lnclicursesContext) error {
+	if ctxt.price == nil {
+		return nil
+	}
+	currency := getFiatCurrency()
+	rate, err := ctxt.price.getPrice(currency)
+	if err != nil {
+		return err
+	}
+	s.store.update(func(st *lncliState) { st.price = lncliPrice{currency, rate} })
+	return nil
+}
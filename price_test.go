@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		data string
+		want float64
+	}{
+		{"25000.5\n", 25000.5},
+		{`{"EUR": 25000.5, "USD": 27000}`, 25000.5},
+		{`{"eur": "25000.5"}`, 25000.5},
+		{`{"bitcoin": {"eur": 25000.5}}`, 25000.5},
+	}
+
+	for _, tt := range tests {
+		if price, err := parsePrice([]byte(tt.data), "EUR"); err != nil || price != tt.want {
+			t.Errorf("%q = %v, %v", tt.data, price, err)
+		}
+	}
+
+	for _, data := range []string{"", "-1", "abc", `{"USD": 27000, "GBP": 21000}`, `{"EUR": true}`, `["25000"]`, `{"USD": 27000}`, `{"bitcoin": {"usd": 27000}}`, `"NaN"`, `{"EUR": "Infinity"}`} {
+		if _, err := parsePrice([]byte(data), "EUR"); err == nil {
+			t.Errorf("%q: no error", data)
+		}
+	}
+}
+
+func TestPriceProviders(t *testing.T) {
+	if _, err := (&staticPriceProvider{}).getPrice("EUR"); err == nil {
+		t.Error("no error without static rate")
+	}
+
+	path := filepath.Join(t.TempDir(), "price.json")
+	if err := ioutil.WriteFile(path, []byte(`{"EUR": 20000}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if price, err := (&filePriceProvider{path}).getPrice("EUR"); err != nil || price != 20000 {
+		t.Errorf("file price = %v, %v", price, err)
+	}
+	if _, err := (&filePriceProvider{path + ".missing"}).getPrice("EUR"); err == nil {
+		t.Error("no error for a missing price file")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("vs") != "EUR" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"bitcoin": {"eur": 21000.25}}`)
+	}))
+	defer server.Close()
+
+	if price, err := newHTTPPriceProvider(server.URL + "/price?vs={currency}").getPrice("EUR"); err != nil || price != 21000.25 {
+		t.Errorf("http price = %v, %v", price, err)
+	}
+	if _, err := newHTTPPriceProvider(server.URL + "/price?vs={currency}").getPrice("USD"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("http error = %v", err)
+	}
+}
+
+func TestNewPriceProvider(t *testing.T) {
+	setupTestContext(t)
+
+	if newPriceProvider() != nil {
+		t.Error("price provider without fiat currency")
+	}
+
+	viper.Set("fiat.currency", "eur")
+	viper.Set("fiat.rate", 20000)
+	if p, ok := newPriceProvider().(*staticPriceProvider); !ok || p.rate != 20000 {
+		t.Errorf("static provider %+v", p)
+	}
+
+	viper.Set("fiat.source", "http")
+	viper.Set("fiat.url", "http://localhost/price")
+	if p, ok := newPriceProvider().(*httpPriceProvider); !ok || p.url != "http://localhost/price" {
+		t.Errorf("http provider %+v", p)
+	}
+
+	viper.Set("fiat.source", "ticker")
+	if newPriceProvider() != nil {
+		t.Error("price provider for an unknown source")
+	}
+}
+
+func TestToFiatInvalidRate(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if _, ok := (lncliPrice{"EUR", rate}).toFiat(100000, satUnit); ok {
+			t.Errorf("%v rate converted", rate)
+		}
+	}
+}
+
+func TestFiatDisplay(t *testing.T) {
+	setupTestContext(t)
+	updateData()
+
+	cv := context.views[channelListViewt].(*channelListView)
+	cv.grid.addDisplayColumn("LocalFiat", "Local EUR", 12)
+
+	lines := renderView(t, "balance", refreshWalletBalanceView)
+	if len(lines) != 3 {
+		t.Errorf("fiat displayed without price %q", lines)
+	}
+	if l := assertLine(t, renderMainView(t), "1,500,000"); !strings.HasSuffix(l, "│           │") {
+		t.Errorf("fiat column without price %q", l)
+	}
+
+	viper.Set("fiat.currency", "EUR")
+	context.price = &staticPriceProvider{20000}
+	if err := status.updatePrice(&context); err != nil {
+		t.Fatal(err)
+	}
+
+	lines = renderView(t, "balance", refreshWalletBalanceView)
+	assertLine(t, lines, "Total", "300.00", "EUR")
+	assertLine(t, lines, "BTC price", "20,000.00", "EUR")
+
+	assertLine(t, renderMainView(t), "1,500,000", "│300.00     │")
+
+	if err := cv.grid.setFilter("LocalFiat>=300"); err != nil {
+		t.Fatal(err)
+	}
+	if rows := gridRowNames(cv.grid); len(rows) != 1 {
+		t.Errorf("fiat filter rows %q", rows)
+	}
+	if err := cv.grid.setFilter("LocalFiat>300 EUR"); err == nil {
+		t.Error("no error for an invalid fiat amount")
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jroimartin/gocui"
+)
+
+type routeListView struct {
+	viewBase
+	form *formEdit
+}
+
+type probeRouteContainer struct {
+	PubKey string `displayname:"Destination" length:"40" lines:"2" validate:"required,pubkey"`
+	Amount int    `displayname:"Amount" length:"16" unit:"sat" validate:"required,min=1"`
+}
+
+func newrouteListView(physicalView string, fmtnormal string, fmtheader string, fmtselected string) *routeListView {
+	cv := new(routeListView)
+
+	cv.grid = makeNewDataGrid()
+	cv.mappedToPhysicalView = physicalView
+
+	cv.init(fmtnormal, fmtheader, fmtselected)
+
+	return cv
+}
+
+func (cv *routeListView) init(fmtnormal string, fmtheader string, fmtselected string) {
+
+	cv.grid.fmtForeground = fmtnormal
+	cv.grid.fmtHeader = fmtheader
+	cv.grid.fmtSelected = fmtselected
+
+	cv.shortcuts = nil
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Probe route", "Q", 'q', gocui.ModAlt, cv.probe, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Send through", "K", 'k', gocui.ModAlt, cv.sendThrough, true, ""})
+
+	cv.grid.header = "[Routes]"
+
+	cv.grid.addColumn("OutChan", "OutgoingChan", stringRow)
+	cv.grid.addColumn("OutAlias", "OutgoingAlias", stringRow)
+	cv.grid.addColumn("Hops", "HopCount", intRow)
+	cv.grid.addColumn("Route", "Path", stringRow)
+	cv.grid.addAmountColumn("Fee", "TotalFees", satUnit)
+	cv.grid.addAmountColumn("FeeMsat", "TotalFeesMsat", msatUnit)
+	cv.grid.addColumn("TimeLock", "TimeLockDelta", intRow)
+	cv.grid.addAmountColumn("Amount", "TotalAmt", satUnit)
+
+	cv.grid.addDisplayColumn("OutChan", "Out chan", 16)
+	cv.grid.addDisplayColumn("OutAlias", "Out", 16)
+	cv.grid.addDisplayColumn("Hops", "Hops", 5)
+	cv.grid.addDisplayColumn("Route", "Route (fee mSat)", 0)
+	cv.grid.addDisplayColumn("Fee", "Fee", 9)
+	cv.grid.addDisplayColumn("FeeMsat", "Exact fee", 12)
+	cv.grid.addDisplayColumn("TimeLock", "Time lock", 10)
+	cv.grid.addDisplayColumn("Amount", "Amount", 13)
+	cv.grid.setSortColumn("FeeMsat", false)
+}
+
+// probe opens the form querying the routes to a node, prefilled with the
+// last probe.
+func (cv *routeListView) probe() {
+	routes := status.state().routes
+
+	cc := new(probeRouteContainer)
+	cc.PubKey = routes.destination
+	cc.Amount = int(routes.amount)
+
+	cv.form = newFormEdit("probeRouteVal", "Probe route", cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.getValue()
+		cv.form.close(context.gocui)
+		cv.form = nil
+		if valid {
+			probeRoutes(cc.PubKey, int64(cc.Amount))
+		}
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
+// probeRoutes queries the routes in the background, displayed in the route
+// view once found.
+func probeRoutes(pubkey string, amt int64) {
+	status.store.background(func() {
+		if err := status.probeRoutes(&context, pubkey, amt); err != nil {
+			logError(err.Error())
+			runInGui(func() { displayMessage("Error: "+err.Error(), nil) })
+			return
+		}
+		refreshView()
+	})
+}
+
+func (cv *routeListView) getSelectedRoute() *lncliRoute {
+	return cv.grid.getSelectedItem().Interface().(*lncliRoute)
+}
+
+// sendThrough opens the keysend form for the probed destination and amount,
+// through the outgoing channel of the selected route.
+func (cv *routeListView) sendThrough() {
+	routes := status.state().routes
+	if len(routes.routes) == 0 {
+		return
+	}
+	r := cv.getSelectedRoute()
+	if r == nil {
+		return
+	}
+
+	cc := new(keysendContainer)
+	cc.PubKey = routes.destination
+	cc.Amount = int(routes.amount)
+	cc.FeeLimit = int((r.TotalFeesMsat + 999) / 1000)
+	cc.OutgoingChan = r.OutgoingChan
+
+	switchActiveView(paymentListViewt)
+	context.views[paymentListViewt].(*paymentListView).openKeysend(cc)
+}
+
+func (cv *routeListView) refreshView(g *gocui.Gui) {
+	v, err := g.View(cv.mappedToPhysicalView)
+	if err != nil {
+		log.Panicln(err.Error())
+		return
+	}
+	v.Clear()
+
+	x, y := v.Size()
+
+	routes := status.state().routes
+	cv.grid.items = routes.routes
+	cv.grid.info = routes.getInfo()
+	cv.grid.setRenderSize(x, y)
+
+	for _, row := range cv.grid.getGridRows() {
+		fmt.Fprintln(v, row)
+	}
+
+	if cv.form != nil {
+		cv.form.layout(g)
+	}
+}
+
+func (cv *routeListView) getShortCuts() []*keyHandle {
+	return cv.shortcuts
+}
+
+func (cv *routeListView) getGrid() *dataGrid {
+	return cv.grid
+}
+
+func (cv *routeListView) getPhysicalView() string {
+	return cv.mappedToPhysicalView
+}
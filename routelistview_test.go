@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// loadTestRoutes enables the second channel and probes the routes to yalls
+// for 25,000 sat.
+func loadTestRoutes(t *testing.T, fb *fakeBackend) {
+	t.Helper()
+
+	fb.channels.Channels[1].Active = true
+	fb.channels.Channels[1].LocalBalance = 300000
+
+	activateView(channelListViewt)
+	updateTestData(t)
+
+	if err := status.probeRoutes(&context, testPubkey(3), 25000); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProbeRoutes(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	loadTestRoutes(t, fb)
+
+	if n := fb.callCount("queryroutes"); n != 2 {
+		t.Errorf("%d routes queried, want one per channel", n)
+	}
+
+	routes := status.state().routes.routes
+	if len(routes) != 2 {
+		t.Fatalf("%d routes", len(routes))
+	}
+	r := routes[0]
+	if r.OutgoingAlias != "acinq" || r.OutgoingChan != shortChannelID(659767854661451776) || r.HopCount != 2 || r.TimeLockDelta != 184 {
+		t.Errorf("unexpected route %+v", r)
+	}
+	if r.Path != "acinq (1,776) > yalls (0)" {
+		t.Errorf("path = %q", r.Path)
+	}
+	if routes[1].OutgoingAlias != "bitrefill" || routes[1].TotalFeesMsat != 1777 {
+		t.Errorf("unexpected second route %+v", routes[1])
+	}
+
+	activateView(routeListViewt)
+	lines := renderMainView(t)
+	assertLine(t, lines, "[Routes]", "2 route(s) to yalls for 25,000 sat")
+	assertLine(t, lines, "acinq (1,776) > yalls (0)", "1,776")
+	assertLine(t, lines, "bitrefill (1,777) > yalls (0)", "25,001")
+}
+
+func TestProbeRoutesWithoutChannel(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	activateView(routeListViewt)
+	assertLine(t, renderMainView(t), "[Routes]", "No probe")
+
+	if err := status.probeRoutes(&context, testPubkey(3), 25000); err != nil {
+		t.Fatal(err)
+	}
+	if n := fb.callCount("queryroutes"); n != 1 || len(status.state().routes.routes) != 1 {
+		t.Errorf("%d queries, %d routes, want one through any channel", n, len(status.state().routes.routes))
+	}
+
+	fb.err = errors.New("unable to find a path to destination")
+	if err := status.probeRoutes(&context, testPubkey(2), 1000); err == nil {
+		t.Error("probe error not returned")
+	}
+	if status.state().routes.destination != testPubkey(3) {
+		t.Error("routes replaced by a failed probe")
+	}
+}
+
+func TestProbeRoutesBounded(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	// two channels to each of 6 peers, the second with more local balance
+	fb.channels.Channels = nil
+	for i := 0; i < 12; i++ {
+		fb.channels.Channels = append(fb.channels.Channels, &lnrpc.Channel{
+			ChanId:       uint64(1000 + i),
+			ChannelPoint: fmt.Sprintf("%s:%d", strings.Repeat("e5", 32), i),
+			RemotePubkey: testPubkey(byte(10 + i/2)),
+			Active:       true,
+			LocalBalance: int64(100000 + i*1000),
+		})
+	}
+	if err := status.updateChannelList(&context); err != nil {
+		t.Fatal(err)
+	}
+	// the block height isn't known from the header
+	status.store.update(func(st *lncliState) { st.localNodeInfo = lnrpc.GetInfoResponse{} })
+
+	if err := status.probeRoutes(&context, testPubkey(3), 25000); err != nil {
+		t.Fatal(err)
+	}
+	if n := fb.callCount("queryroutes"); n != maxProbedChannels {
+		t.Errorf("%d routes queried, want %d", n, maxProbedChannels)
+	}
+	for _, r := range status.state().routes.routes {
+		if r.OutgoingChanID%2 == 0 || r.OutgoingChanID < 1003 {
+			t.Errorf("route through %d, not the peers' channels with the most balance", r.OutgoingChanID)
+		}
+		if r.TimeLockDelta != 184 {
+			t.Errorf("time lock delta = %d", r.TimeLockDelta)
+		}
+	}
+	status.store.wait()
+}
+
+func TestProbeRouteSendThrough(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	loadTestRoutes(t, fb)
+
+	activateView(routeListViewt)
+	renderMainView(t)
+
+	cv := context.views[routeListViewt].(*routeListView)
+	cv.grid.moveSelectionDown()
+	cv.sendThrough()
+
+	if context.activeMainView != paymentListViewt {
+		t.Fatal("payment view not activated")
+	}
+	pv := context.views[paymentListViewt].(*paymentListView)
+	for name, want := range map[string]interface{}{"PubKey": testPubkey(3), "Amount": 25000, "FeeLimit": 2, "OutgoingChan": shortChannelID(659767854661451777)} {
+		if v := getTestEditor(t, pv.form, name).getValue(); v != want {
+			t.Errorf("%s = %v, want %v", name, v, want)
+		}
+	}
+
+	pv.form.submit(true)
+	status.store.wait()
+
+	fb.mutex.Lock()
+	defer fb.mutex.Unlock()
+	if fb.lastPayment == nil || fb.lastPayment.OutgoingChanId != 659767854661451777 {
+		t.Errorf("payment not sent through the selected channel, %+v", fb.lastPayment)
+	}
+}
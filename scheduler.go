@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	headerRefreshKey  = "header"
+	maxRefreshBackoff = 10 * time.Minute
+)
+
+// refreshSource is a set of data updated together, every interval while
+// its view is displayed and every interval * inactiveFactor otherwise, or
+// while a stream keeps it up to date. A source is updated once at a time, a
+// refresh asked meanwhile runs again after the update in flight. The node
+// cache is saved after the update of the sources querying it.
+type refreshSource struct {
+	key       string
+	view      viewType
+	update    func() error
+	interval  time.Duration
+	next      time.Time
+	updated   time.Time
+	failures  int
+	streaming bool
+	running   bool
+	again     bool
+	nodes     bool
+}
+
+// refreshScheduler updates the refresh sources when due, backing off when the
+// backend errors.
+type refreshScheduler struct {
+	mutex          sync.Mutex
+	sources        []*refreshSource
+	header         *refreshSource
+	activeView     viewType
+	inactiveFactor int
+}
+
+func newRefreshScheduler() *refreshScheduler {
+	s := new(refreshScheduler)
+	s.activeView = channelListViewt
+	s.inactiveFactor = getConfigInactiveRefreshFactor()
+
+	s.header = s.addSource(headerRefreshKey, -1, func() error {
+		if !getShowHeader() {
+			return nil
+		}
+		if err := status.updateLocalNodeInfo(&context); err != nil {
+			return err
+		}
+		return status.updateWalletBalance(&context)
+	})
+	s.addSource("channels", channelListViewt, func() error { return status.updateChannelList(&context) })
+	s.addSource("peers", peerListViewt, func() error { return status.updatePeersList(&context) })
+	s.addSource("pendingChannels", pendingChannelListViewt, func() error { return status.updatePendingChannelList(&context) })
+	s.addSource("invoices", invoiceListViewt, func() error { return status.updateInvoiceList(&context) })
+	s.addSource("payments", paymentListViewt, func() error { return status.updatePaymentList(&context) })
+	s.addSource("walletTransactions", walletTransactionsViewt, func() error { return status.updateWallletTransactionsList(&context) })
+	s.addSource("utxos", utxoListViewt, func() error { return status.updateUtxos(&context) })
+	s.addSource("forwards", forwardingHistoryViewt, func() error { return status.updateForwardingHistory(&context) })
+	s.addSource("price", -1, func() error { return status.updatePrice(&context) })
+
+	for _, key := range []string{"channels", "peers", "pendingChannels", "payments", "forwards"} {
+		s.getSourceByKey(key).nodes = true
+	}
+
+	return s
+}
+
+func (s *refreshScheduler) addSource(key string, view viewType, update func() error) *refreshSource {
+	src := &refreshSource{
+		key:      key,
+		view:     view,
+		update:   update,
+		interval: time.Duration(getConfigRefreshSec(key)) * time.Second,
+	}
+	s.sources = append(s.sources, src)
+	return src
+}
+
+func (s *refreshScheduler) getSource(view viewType) *refreshSource {
+	for _, src := range s.sources {
+		if src.view == view {
+			return src
+		}
+	}
+	return nil
+}
+
+func (s *refreshScheduler) getSourceByKey(key string) *refreshSource {
+	for _, src := range s.sources {
+		if src.key == key {
+			return src
+		}
+	}
+	return nil
+}
+
+// start runs the due sources every second, redrawing the views for their
+// last updated times.
+func (s *refreshScheduler) start() {
+	s.stagger(time.Now())
+	ticker := time.NewTicker(time.Second)
+	go func() {
+		for now := range ticker.C {
+			s.tick(now)
+			refreshView()
+		}
+	}()
+}
+
+// tick starts the update of the sources due at now each in the background,
+// a slow source not holding back the others. The sources still updating are
+// left alone.
+func (s *refreshScheduler) tick(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, src := range s.sources {
+		if src.running || now.Before(src.next) {
+			continue
+		}
+		src.running = true
+		src := src
+		status.store.background(func() { s.updates(src) })
+	}
+}
+
+// stagger spreads the first updates of the sources not displayed over their
+// interval, the header and the active view's source are updated first.
+func (s *refreshScheduler) stagger(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, src := range s.sources {
+		if src == s.header || src.view == s.activeView || src.interval <= 0 {
+			continue
+		}
+		src.next = now.Add(time.Duration(rand.Int63n(int64(src.interval))))
+	}
+}
+
+func (s *refreshScheduler) run(src *refreshSource) {
+	s.mutex.Lock()
+	if src.running {
+		src.again = true
+		s.mutex.Unlock()
+		return
+	}
+	src.running = true
+	s.mutex.Unlock()
+
+	s.updates(src)
+}
+
+// updates updates the running source until no refresh is asked meanwhile.
+func (s *refreshScheduler) updates(src *refreshSource) {
+	for {
+		err := src.update()
+		manageError(err)
+
+		s.mutex.Lock()
+		s.schedule(src, err)
+		again := src.again
+		src.again = false
+		src.running = again
+		s.mutex.Unlock()
+
+		if !again {
+			break
+		}
+	}
+
+	if src.nodes {
+		manageError(status.nodes.save())
+	}
+}
+
+// schedule sets the next update of the source, backing off on errors.
+func (s *refreshScheduler) schedule(src *refreshSource, err error) {
+	now := time.Now()
+	delay := src.interval
+	if src.streaming || (src.view >= 0 && src.view != s.activeView) {
+		delay *= time.Duration(s.inactiveFactor)
+	}
+
+	if err != nil {
+		src.failures++
+		for i := 0; i < src.failures && delay < maxRefreshBackoff; i++ {
+			delay *= 2
+		}
+		if delay > maxRefreshBackoff {
+			delay = maxRefreshBackoff
+		}
+	} else {
+		src.failures = 0
+		src.updated = now
+	}
+
+	// up to 10% of jitter, keeping the sources from all hitting lnd at once
+	if jitter := int64(delay / 10); jitter > 0 {
+		delay += time.Duration(rand.Int63n(jitter))
+	}
+	src.next = now.Add(delay)
+}
+
+// refresh updates the header and the data of view now.
+func (s *refreshScheduler) refresh(view viewType) {
+	s.run(s.header)
+	if src := s.getSource(view); src != nil {
+		s.run(src)
+	}
+}
+
+// refreshSource updates the source named key now.
+func (s *refreshScheduler) refreshSource(key string) {
+	if src := s.getSourceByKey(key); src != nil {
+		s.run(src)
+	}
+}
+
+// setStreaming marks the sources named keys as kept up to date by a stream,
+// which are then only polled for what the stream doesn't carry. Polling
+// resumes at the sources' intervals when the stream drops.
+func (s *refreshScheduler) setStreaming(keys []string, streaming bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, key := range keys {
+		src := s.getSourceByKey(key)
+		if src == nil {
+			continue
+		}
+		src.streaming = streaming
+		if next := src.updated.Add(src.interval); !streaming && src.failures == 0 && src.next.After(next) {
+			src.next = next
+		}
+	}
+}
+
+// setActiveView brings the next update of the view displayed back to its
+// interval, returning whether its data is older than that interval. The
+// sources backing off or streaming are left alone.
+func (s *refreshScheduler) setActiveView(view viewType) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.activeView = view
+
+	src := s.getSource(view)
+	if src == nil || src.failures > 0 || src.streaming {
+		return false
+	}
+	if next := src.updated.Add(src.interval); src.next.After(next) {
+		src.next = next
+	}
+	return time.Since(src.updated) > src.interval
+}
+
+// getStatus returns when the data of view was last updated.
+func (s *refreshScheduler) getStatus(view viewType) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	src := s.getSource(view)
+	if src == nil || src.updated.IsZero() {
+		return ""
+	}
+
+	msg := fmt.Sprintf("Updated %ds ago", int(time.Since(src.updated).Seconds()))
+	if src.failures > 0 {
+		msg += fmt.Sprintf(", %d failed", src.failures)
+	}
+	if src.streaming {
+		msg += ", streaming"
+	}
+	return msg
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestRefreshSchedulerConfig(t *testing.T) {
+	setupTestContext(t)
+
+	for key, want := range map[string]time.Duration{"header": 10 * time.Second, "channels": 30 * time.Second, "walletTransactions": 120 * time.Second} {
+		for _, src := range context.scheduler.sources {
+			if src.key == key && src.interval != want {
+				t.Errorf("%s interval = %v, want %v", key, src.interval, want)
+			}
+		}
+	}
+
+	viper.Set("refresh.peers", 0)
+	if sec := getConfigRefreshSec("peers"); sec != 60 {
+		t.Errorf("default interval = %d, want RefreshSec", sec)
+	}
+}
+
+func TestRefreshSchedulerTick(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	s := context.scheduler
+
+	s.tick(time.Now())
+	status.store.wait()
+	for _, call := range []string{"getinfo", "feereport", "listpeers", "pendingchannels", "listinvoices", "listpayments", "listchaintxns", "fwdinghistory"} {
+		if n := fb.callCount(call); n != 1 {
+			t.Errorf("%s called %d times on the first tick", call, n)
+		}
+	}
+
+	s.tick(time.Now().Add(15 * time.Second))
+	status.store.wait()
+	if fb.callCount("getinfo") != 2 || fb.callCount("feereport") != 1 {
+		t.Error("header not alone updated after 15s")
+	}
+
+	s.tick(time.Now().Add(40 * time.Second))
+	status.store.wait()
+	if fb.callCount("feereport") != 2 || fb.callCount("listpeers") != 1 {
+		t.Error("active view not updated before the inactive ones")
+	}
+
+	s.tick(time.Now().Add(140 * time.Second))
+	status.store.wait()
+	if fb.callCount("listpeers") != 2 {
+		t.Error("inactive view not prefetched")
+	}
+
+	status.store.wait()
+}
+
+func TestRefreshSchedulerBackoff(t *testing.T) {
+	fb := setupTestContext(t)
+	s := context.scheduler
+	src := s.getSource(channelListViewt)
+
+	fb.err = errors.New("unable to connect to lnd")
+
+	for i, want := range []time.Duration{60 * time.Second, 120 * time.Second, 240 * time.Second} {
+		s.run(src)
+		delay := time.Until(src.next)
+		if delay < want-time.Second || delay > want+want/10 {
+			t.Errorf("delay after %d failures = %v, want %v", i+1, delay, want)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		s.run(src)
+	}
+	if delay := time.Until(src.next); delay > maxRefreshBackoff+maxRefreshBackoff/10 {
+		t.Errorf("delay %v above the max backoff", delay)
+	}
+
+	fb.err = nil
+	s.run(src)
+	if src.failures != 0 || time.Until(src.next) > 33*time.Second {
+		t.Errorf("backoff not reset, %d failures", src.failures)
+	}
+}
+
+func TestRefreshSchedulerActiveView(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	s := context.scheduler
+
+	s.tick(time.Now())
+	status.store.wait()
+
+	if s.setActiveView(peerListViewt) {
+		t.Error("fresh data reported stale")
+	}
+	peers := s.getSource(peerListViewt)
+	if time.Until(peers.next) > 31*time.Second {
+		t.Errorf("next update in %v, not rescheduled at the active interval", time.Until(peers.next))
+	}
+
+	peers.updated = time.Now().Add(-time.Minute)
+	if !s.setActiveView(peerListViewt) {
+		t.Error("stale data not reported")
+	}
+}
+
+func TestRefreshSchedulerInFlight(t *testing.T) {
+	setupTestContext(t)
+	s := context.scheduler
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	src := s.addSource("slow", -1, func() error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.run(src)
+		close(done)
+	}()
+	<-started
+
+	// both refreshes asked during the update run it once more
+	s.refreshSource("slow")
+	s.refreshSource("slow")
+	close(release)
+	<-done
+
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("source updated %d times", n)
+	}
+	if src.running {
+		t.Error("source still running")
+	}
+}
+
+func TestRefreshSchedulerTickConcurrent(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	s := context.scheduler
+
+	release := make(chan struct{})
+	var calls int32
+	s.addSource("slow", -1, func() error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	})
+
+	s.tick(time.Now())
+	waitFor(t, "other sources updated", func() bool { return fb.callCount("feereport") == 1 && fb.callCount("listpeers") == 1 })
+
+	// the source still updating isn't started again
+	s.tick(time.Now().Add(time.Hour))
+	close(release)
+	status.store.wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("slow source updated %d times", n)
+	}
+}
+
+func TestRefreshSchedulerStagger(t *testing.T) {
+	setupTestContext(t)
+	s := context.scheduler
+
+	now := time.Now()
+	s.stagger(now)
+
+	for _, src := range s.sources {
+		first := src == s.header || src.view == s.activeView
+		if first && src.next.After(now) {
+			t.Errorf("%s first update delayed", src.key)
+		}
+		if !first && src.next.After(now.Add(src.interval)) {
+			t.Errorf("%s first update in %v, above its interval", src.key, src.next.Sub(now))
+		}
+	}
+}
+
+func TestRefreshStatusHeader(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+
+	lines := renderMainView(t)
+	if _, ok := findLine(lines, "Updated"); ok {
+		t.Error("update time displayed before any update")
+	}
+
+	lines = updateTestData(t)
+	assertLine(t, lines, "[Channels]", "Updated 0s ago")
+
+	fb.err = errors.New("unable to connect to lnd")
+	updateData()
+	fb.err = nil
+
+	assertLine(t, renderMainView(t), "[Channels]", "Updated 0s ago, 1 failed")
+	status.store.wait()
+}
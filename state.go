@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// lncliState is a snapshot of the data displayed by the views. Published
+// snapshots are never modified, an update applies to a copy and replaces the
+// slices and items it changes instead of writing to them.
+type lncliState struct {
+	localNodeInfo      lnrpc.GetInfoResponse
+	walletBalance      lnrpc.WalletBalanceResponse
+	channels           []*lncliChannel
+	peers              []*lncliPeer
+	pendingchannels    lncliPendingChannelsContainer
+	walletTransactions []*lnrpc.Transaction
+	invoices           lncliInvoicesContainer
+	payments           lncliPaymentsContainer
+	sending            []*paymentInFlight
+	forwards           lncliForwardingHistoryContainer
+	routes             lncliRoutesContainer
+	batch              lncliBatchContainer
+	bulkClose          lncliBulkCloseContainer
+	utxos              []*lncliUtxo
+	price              lncliPrice
+	logs               []*logEntry
+}
+
+// stateStore holds the current lncliState. Its goroutine is the only writer,
+// applying the updates in order and publishing the resulting snapshots, which
+// can then be read from any goroutine.
+type stateStore struct {
+	mutex   sync.RWMutex
+	current *lncliState
+	updates chan stateUpdate
+	pending sync.WaitGroup
+}
+
+type stateUpdate struct {
+	apply func(st *lncliState)
+	done  chan struct{}
+}
+
+func newStateStore() *stateStore {
+	s := new(stateStore)
+	s.current = new(lncliState)
+	s.updates = make(chan stateUpdate)
+	go s.run()
+	return s
+}
+
+func (s *stateStore) run() {
+	for u := range s.updates {
+		next := *s.snapshot()
+		u.apply(&next)
+
+		s.mutex.Lock()
+		s.current = &next
+		s.mutex.Unlock()
+
+		close(u.done)
+	}
+}
+
+// snapshot returns the last published state.
+func (s *stateStore) snapshot() *lncliState {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.current
+}
+
+// update has fn applied by the writer goroutine and returns once the new
+// state is published. fn must not block nor update the store itself.
+func (s *stateStore) update(fn func(st *lncliState)) {
+	u := stateUpdate{fn, make(chan struct{})}
+	s.updates <- u
+	<-u.done
+}
+
+// background runs fn in its own goroutine, wait returning once all of them
+// are done.
+func (s *stateStore) background(fn func()) {
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		fn()
+	}()
+}
+
+func (s *stateStore) wait() {
+	s.pending.Wait()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestStateStoreSnapshots(t *testing.T) {
+	s := newStateStore()
+
+	s.update(func(st *lncliState) { st.peers = []*lncliPeer{{Alias: "first"}} })
+	first := s.snapshot()
+
+	s.update(func(st *lncliState) { st.peers = []*lncliPeer{{Alias: "second"}} })
+
+	if first.peers[0].Alias != "first" {
+		t.Error("published snapshot modified")
+	}
+	if s.snapshot().peers[0].Alias != "second" {
+		t.Error("update not published")
+	}
+}
+
+func TestStateStoreUpdatesInOrder(t *testing.T) {
+	s := newStateStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.update(func(st *lncliState) {
+				st.logs = append(st.logs, &logEntry{Message: fmt.Sprint(i)})
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(s.snapshot().logs); n != 50 {
+		t.Errorf("got %d log entries", n)
+	}
+}
+
+// TestConcurrentRefreshes runs the refreshes of all the views, node cache
+// refreshes, alias lookups and logging while the test goroutine, standing in
+// for the gocui one, renders the views and submits forms. It's meant to be
+// run with -race.
+func TestConcurrentRefreshes(t *testing.T) {
+	fb := setupTestContext(t)
+
+	views := []viewType{channelListViewt, peerListViewt, pendingChannelListViewt, invoiceListViewt,
+		paymentListViewt, walletTransactionsViewt, forwardingHistoryViewt, logViewt}
+
+	for i := 0; i < 5; i++ {
+		for _, view := range views {
+			view := view
+			status.store.background(func() { updateViewData(view) })
+		}
+		status.store.background(func() { logError("concurrent log") })
+		refreshNodeCache()
+	}
+
+	cv := context.views[channelListViewt].(*channelListView)
+	for i := 0; i < 20; i++ {
+		for _, view := range views {
+			activateView(view)
+			renderMainView(t)
+		}
+
+		activateView(channelListViewt)
+		renderMainView(t)
+		openTestPolicyForm(cv)
+		if cv.form != nil {
+			typeInForm(t, cv.form, "FeeRatePpm", fmt.Sprint(i))
+			cv.form.submit(true)
+		}
+	}
+
+	status.store.wait()
+
+	updateTestData(t)
+	waitFor(t, "channel aliases", func() bool { return status.state().channels[1].NodeAlias == "bitrefill" })
+	status.store.wait()
+
+	lines := renderMainView(t)
+	assertLine(t, lines, "acinq", "2,000,000")
+
+	if fb.callCount("updatechanpolicy") == 0 {
+		t.Error("policy form not submitted")
+	}
+
+	activateView(logViewt)
+	assertLine(t, renderMainView(t), "concurrent log")
+}
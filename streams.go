@@ -0,0 +1,312 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+var (
+	streamRetryDelay    = 5 * time.Second
+	maxStreamRetryDelay = time.Minute
+)
+
+// streamManager follows lnd's subscription streams, applying their updates
+// to the state as they come. The refresh sources of a stream are polled again
+// at their intervals while it is down, and resubscribed with a growing delay.
+type streamManager struct {
+	ctx     gocontext.Context
+	cancel  gocontext.CancelFunc
+	running sync.WaitGroup
+}
+
+// startStreams subscribes to the streams of the backend, returning nil when
+// it has none.
+func startStreams(ctxt *lnclicursesContext) *streamManager {
+	b, ok := ctxt.backend.(streamingBackend)
+	if !ok {
+		return nil
+	}
+
+	m := new(streamManager)
+	m.ctx, m.cancel = gocontext.WithCancel(gocontext.Background())
+
+	m.follow("invoices", []string{"invoices"}, func(ctx gocontext.Context) (func() error, error) {
+		recv, err := b.subscribeInvoices(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			invoice, err := recv()
+			if err == nil {
+				status.applyInvoice(invoice)
+			}
+			return err
+		}, nil
+	})
+
+	m.follow("channel events", []string{"channels", "pendingChannels"}, func(ctx gocontext.Context) (func() error, error) {
+		recv, err := b.subscribeChannelEvents(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			event, err := recv()
+			if err == nil {
+				status.applyChannelEvent(ctxt, event)
+			}
+			return err
+		}, nil
+	})
+
+	m.follow("transactions", []string{"walletTransactions"}, func(ctx gocontext.Context) (func() error, error) {
+		recv, err := b.subscribeTransactions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			tx, err := recv()
+			if err == nil {
+				status.applyTransaction(ctxt, tx)
+			}
+			return err
+		}, nil
+	})
+
+	m.follow("peer events", []string{"peers"}, func(ctx gocontext.Context) (func() error, error) {
+		recv, err := b.subscribePeerEvents(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			event, err := recv()
+			if err == nil {
+				status.applyPeerEvent(event)
+			}
+			return err
+		}, nil
+	})
+
+	return m
+}
+
+// follow keeps the stream returned by subscribe up, calling its next
+// function for every update. The sources named keys are updated right after
+// subscribing, catching up with what happened while the stream was down.
+func (m *streamManager) follow(name string, keys []string, subscribe func(ctx gocontext.Context) (func() error, error)) {
+	m.running.Add(1)
+	go func() {
+		defer m.running.Done()
+
+		delay := streamRetryDelay
+
+		for {
+			next, err := subscribe(m.ctx)
+			if err == nil {
+				context.scheduler.setStreaming(keys, true)
+				for _, key := range keys {
+					context.scheduler.refreshSource(key)
+				}
+				refreshView()
+
+				received := false
+				for err == nil {
+					if err = next(); err == nil {
+						received = true
+						refreshView()
+					}
+				}
+				if received {
+					delay = streamRetryDelay
+				}
+
+				context.scheduler.setStreaming(keys, false)
+			}
+
+			if m.ctx.Err() != nil {
+				return
+			}
+
+			logError(fmt.Sprintf("%s stream: %s", name, err.Error()))
+
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			if delay *= 2; delay > maxStreamRetryDelay {
+				delay = maxStreamRetryDelay
+			}
+		}
+	}()
+}
+
+// stop closes the streams and waits for their goroutines.
+func (m *streamManager) stop() {
+	m.cancel()
+	m.running.Wait()
+}
+
+// applyInvoice replaces the invoice with the same add index, adding it when
+// the most recent invoices are displayed. Settled and canceled invoices are
+// removed from the pending ones.
+func (s *lncliStatus) applyInvoice(invoice *lnrpc.Invoice) {
+	ni := &lncliInvoice{*invoice}
+	done := invoice.Settled || invoice.State == lnrpc.Invoice_CANCELED
+
+	s.store.update(func(st *lncliState) {
+		page := st.invoices.page
+		if invoice.AddIndex > page.total {
+			page.total = invoice.AddIndex
+		}
+
+		var invoices []*lncliInvoice
+		found := false
+		for _, c := range st.invoices.invoices {
+			if c.AddIndex != invoice.AddIndex {
+				invoices = append(invoices, c)
+				continue
+			}
+			found = true
+			if !(page.pendingOnly && done) {
+				invoices = append(invoices, ni)
+			}
+		}
+		if !found && page.start == 0 && !(page.pendingOnly && done) {
+			invoices = append(invoices, ni)
+			if page.first == 0 {
+				page.first = invoice.AddIndex
+			}
+			page.last = invoice.AddIndex
+		}
+
+		st.invoices = lncliInvoicesContainer{page, invoices}
+	})
+}
+
+// applyChannelEvent applies the channel events carrying the channel data,
+// updating the channel sources for the others.
+func (s *lncliStatus) applyChannelEvent(ctxt *lnclicursesContext, event *lnrpc.ChannelEventUpdate) {
+	switch e := event.Channel.(type) {
+	case *lnrpc.ChannelEventUpdate_ActiveChannel:
+		s.setChannelActive(channelPointString(e.ActiveChannel), true)
+	case *lnrpc.ChannelEventUpdate_InactiveChannel:
+		s.setChannelActive(channelPointString(e.InactiveChannel), false)
+	case *lnrpc.ChannelEventUpdate_OpenChannel:
+		s.addChannel(ctxt, e.OpenChannel)
+	case *lnrpc.ChannelEventUpdate_ClosedChannel:
+		s.removeChannel(e.ClosedChannel.ChannelPoint)
+		context.scheduler.refreshSource("pendingChannels")
+	default:
+		context.scheduler.refreshSource("pendingChannels")
+	}
+}
+
+func (s *lncliStatus) setChannelActive(channelPoint string, active bool) {
+	s.store.update(func(st *lncliState) {
+		for i, c := range st.channels {
+			if c.ChannelPoint == channelPoint {
+				nc := *c
+				nc.Active = active
+				next := append([]*lncliChannel(nil), st.channels...)
+				next[i] = &nc
+				st.channels = next
+				return
+			}
+		}
+	})
+}
+
+// addChannel adds the opened channel, removing it from the pending ones, and
+// resolves its alias.
+func (s *lncliStatus) addChannel(ctxt *lnclicursesContext, channel *lnrpc.Channel) {
+	nc := &lncliChannel{Channel: *channel}
+
+	s.store.update(func(st *lncliState) {
+		var channels []*lncliChannel
+		for _, c := range st.channels {
+			if c.ChannelPoint != channel.ChannelPoint {
+				channels = append(channels, c)
+			}
+		}
+		st.channels = append(channels, nc)
+
+		var pending []*lncliPendingChannel
+		for _, c := range st.pendingchannels.pendingChannels {
+			if c.ChannelPoint != channel.ChannelPoint {
+				pending = append(pending, c)
+			}
+		}
+		st.pendingchannels.pendingChannels = pending
+	})
+
+	s.store.background(func() {
+		resolved := *nc
+		manageError(resolved.updateNodeAlias(ctxt, s))
+		manageError(resolved.updateNodeUpdate(ctxt, s))
+		s.store.update(func(st *lncliState) {
+			for i, c := range st.channels {
+				if c == nc {
+					next := append([]*lncliChannel(nil), st.channels...)
+					next[i] = &resolved
+					st.channels = next
+					return
+				}
+			}
+		})
+		refreshView()
+	})
+}
+
+func (s *lncliStatus) removeChannel(channelPoint string) {
+	s.store.update(func(st *lncliState) {
+		var channels []*lncliChannel
+		for _, c := range st.channels {
+			if c.ChannelPoint != channelPoint {
+				channels = append(channels, c)
+			}
+		}
+		st.channels = channels
+	})
+}
+
+// applyTransaction replaces the transaction with the same hash, adding it
+// otherwise, and updates the wallet balance.
+func (s *lncliStatus) applyTransaction(ctxt *lnclicursesContext, tx *lnrpc.Transaction) {
+	s.store.update(func(st *lncliState) {
+		transactions := append([]*lnrpc.Transaction(nil), st.walletTransactions...)
+		for i, t := range transactions {
+			if t.TxHash == tx.TxHash {
+				transactions[i] = tx
+				st.walletTransactions = transactions
+				return
+			}
+		}
+		st.walletTransactions = append(transactions, tx)
+	})
+
+	manageError(s.updateWalletBalance(ctxt))
+}
+
+// applyPeerEvent removes the peers going offline, updating the peers source
+// for the ones coming online.
+func (s *lncliStatus) applyPeerEvent(event *lnrpc.PeerEvent) {
+	if event.Type != lnrpc.PeerEvent_PEER_OFFLINE {
+		context.scheduler.refreshSource("peers")
+		return
+	}
+
+	s.store.update(func(st *lncliState) {
+		var peers []*lncliPeer
+		for _, p := range st.peers {
+			if p.PubKey != event.PubKey {
+				peers = append(peers, p)
+			}
+		}
+		st.peers = peers
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+func startTestStreams(t *testing.T, fb *fakeBackend) *streamManager {
+	t.Helper()
+	m := startStreams(&context)
+	if m == nil {
+		t.Fatal("streams not started")
+	}
+	waitFor(t, "streams", func() bool {
+		return fb.callCount("subscribepeerevents") == 1 && strings.Contains(context.scheduler.getStatus(peerListViewt), "streaming")
+	})
+	return m
+}
+
+func TestStreamInvoices(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	m := startTestStreams(t, fb)
+	defer m.stop()
+
+	waitFor(t, "invoices", func() bool { return len(status.state().invoices.invoices) == 2 })
+
+	fb.invoiceEvents <- &lnrpc.Invoice{Memo: "donation", Value: 100000, Settled: true, State: lnrpc.Invoice_SETTLED, AddIndex: 1, SettleIndex: 2}
+	fb.invoiceEvents <- &lnrpc.Invoice{Memo: "beer", Value: 5000, AddIndex: 3}
+
+	waitFor(t, "invoice updates", func() bool {
+		invoices := status.state().invoices.invoices
+		return len(invoices) == 3 && invoices[2].Memo == "beer"
+	})
+	for _, i := range status.state().invoices.invoices {
+		if i.AddIndex == 1 && !i.Settled {
+			t.Error("settled invoice not updated")
+		}
+	}
+
+	activateView(invoiceListViewt)
+	assertLine(t, renderMainView(t), "[Invoices]", "streaming")
+}
+
+func TestStreamChannelEvents(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	m := startTestStreams(t, fb)
+	defer m.stop()
+
+	waitFor(t, "channels", func() bool {
+		return len(status.state().channels) == 2 && len(status.state().pendingchannels.pendingChannels) == 4
+	})
+
+	fb.channelEvents <- &lnrpc.ChannelEventUpdate{Channel: &lnrpc.ChannelEventUpdate_InactiveChannel{
+		InactiveChannel: &lnrpc.ChannelPoint{FundingTxid: &lnrpc.ChannelPoint_FundingTxidStr{FundingTxidStr: strings.Repeat("a1", 32)}},
+	}}
+	waitFor(t, "inactive channel", func() bool { return !status.state().channels[0].Active })
+
+	fb.channelEvents <- &lnrpc.ChannelEventUpdate{Channel: &lnrpc.ChannelEventUpdate_OpenChannel{
+		OpenChannel: &lnrpc.Channel{Active: true, RemotePubkey: testPubkey(3), ChannelPoint: strings.Repeat("c3", 32) + ":0", Capacity: 300000},
+	}}
+	waitFor(t, "opened channel", func() bool {
+		channels := status.state().channels
+		return len(channels) == 3 && channels[2].NodeAlias == "yalls"
+	})
+	if n := len(status.state().pendingchannels.pendingChannels); n != 3 {
+		t.Errorf("%d pending channels, opened one not removed", n)
+	}
+
+	pending := fb.callCount("pendingchannels")
+	fb.channelEvents <- &lnrpc.ChannelEventUpdate{Channel: &lnrpc.ChannelEventUpdate_ClosedChannel{
+		ClosedChannel: &lnrpc.ChannelCloseSummary{ChannelPoint: strings.Repeat("b2", 32) + ":1"},
+	}}
+	waitFor(t, "closed channel", func() bool { return len(status.state().channels) == 2 })
+	waitFor(t, "pending channels update", func() bool { return fb.callCount("pendingchannels") > pending })
+}
+
+func TestStreamTransactionsAndPeers(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	m := startTestStreams(t, fb)
+	defer m.stop()
+
+	waitFor(t, "data", func() bool {
+		return len(status.state().walletTransactions) == 2 && len(status.state().peers) == 2
+	})
+
+	balance := fb.callCount("walletbalance")
+	fb.transactionEvents <- &lnrpc.Transaction{TxHash: strings.Repeat("0c", 32), Amount: 50000}
+	fb.transactionEvents <- &lnrpc.Transaction{TxHash: strings.Repeat("0b", 32), Amount: -2009050, NumConfirmations: 1}
+
+	waitFor(t, "transactions", func() bool {
+		txs := status.state().walletTransactions
+		return len(txs) == 3 && txs[1].NumConfirmations == 1
+	})
+	waitFor(t, "wallet balance update", func() bool { return fb.callCount("walletbalance") == balance+2 })
+
+	fb.peerEvents <- &lnrpc.PeerEvent{PubKey: testPubkey(2), Type: lnrpc.PeerEvent_PEER_OFFLINE}
+	waitFor(t, "offline peer", func() bool { return len(status.state().peers) == 1 })
+
+	peers := fb.callCount("listpeers")
+	fb.peerEvents <- &lnrpc.PeerEvent{PubKey: testPubkey(2), Type: lnrpc.PeerEvent_PEER_ONLINE}
+	waitFor(t, "online peer", func() bool { return fb.callCount("listpeers") > peers && len(status.state().peers) == 2 })
+}
+
+func TestStreamDropFallsBackToPolling(t *testing.T) {
+	fb := setupTestContext(t)
+	s := context.scheduler
+
+	s.setStreaming([]string{"invoices"}, true)
+	src := s.getSourceByKey("invoices")
+	s.run(src)
+	if delay := time.Until(src.next); delay < 4*time.Minute-time.Second {
+		t.Errorf("streaming source polled in %v", delay)
+	}
+	s.setStreaming([]string{"invoices"}, false)
+	if delay := time.Until(src.next); delay > time.Minute {
+		t.Errorf("polling not resumed, next in %v", delay)
+	}
+
+	defer func(delay time.Duration) { streamRetryDelay = delay }(streamRetryDelay)
+	streamRetryDelay = 10 * time.Millisecond
+
+	m := startTestStreams(t, fb)
+	defer m.stop()
+
+	polls := fb.callCount("listinvoices")
+	fb.dropInvoiceStream()
+
+	waitFor(t, "resubscription", func() bool { return fb.callCount("subscribeinvoices") == 2 })
+	waitFor(t, "invoices update", func() bool { return fb.callCount("listinvoices") > polls })
+	waitFor(t, "stream error logged", func() bool {
+		for _, l := range status.state().logs {
+			if strings.Contains(l.Message, "invoices stream: EOF") {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func TestQuitStopsStreams(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	context.streams = startTestStreams(t, fb)
+
+	if err := quit(context.gocui, nil); err != gocui.ErrQuit {
+		t.Fatalf("quit returned %v", err)
+	}
+	if context.streams.ctx.Err() == nil {
+		t.Error("streams not stopped")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// amountUnit is a unit amounts are displayed and entered in, each column and
+// form field declaring the native unit of its values.
+type amountUnit int
+
+const (
+	msatUnit amountUnit = 0
+	satUnit  amountUnit = 1
+	bitsUnit amountUnit = 2
+	btcUnit  amountUnit = 3
+)
+
+var amountUnitNames = []string{"msat", "sat", "bits", "BTC"}
+
+// amountUnitScales are the units in msat.
+var amountUnitScales = []int64{1, 1000, 100000, 100000000000}
+
+// amountUnitDecimals are the decimals displayed for the units, down to the
+// sat. The msat are displayed when not a whole number of sat.
+var amountUnitDecimals = []int{0, 0, 2, 8}
+
+func (u amountUnit) String() string {
+	return amountUnitNames[u]
+}
+
+func (u amountUnit) next() amountUnit {
+	return (u + 1) % amountUnit(len(amountUnitNames))
+}
+
+func parseAmountUnit(s string) (amountUnit, error) {
+	for i, n := range amountUnitNames {
+		if strings.EqualFold(n, s) {
+			return amountUnit(i), nil
+		}
+	}
+	return satUnit, errors.New("unit must be msat, sat, bits or BTC")
+}
+
+// formatAmount formats the amount of native unit in the display unit, with
+// thousands separators.
+func formatAmount(amount int64, native amountUnit, unit amountUnit) string {
+	msat := amount * amountUnitScales[native]
+
+	sign := ""
+	if msat < 0 {
+		sign = "-"
+		msat = -msat
+	}
+
+	scale := amountUnitScales[unit]
+	s := sign + context.printer.Sprintf("%d", msat/scale)
+
+	decimals := amountUnitDecimals[unit]
+	if msat%1000 != 0 && unit != msatUnit {
+		decimals += 3
+	}
+	if decimals == 0 {
+		return s
+	}
+
+	frac := new(big.Rat).SetFrac64(msat%scale, scale).FloatString(decimals)
+	return s + frac[1:]
+}
+
+// formatAmountUnit formats the amount of native unit in the display unit,
+// followed by the unit.
+func formatAmountUnit(amount int64, native amountUnit) string {
+	return formatAmount(amount, native, context.unit) + " " + context.unit.String()
+}
+
+// formatAmountInput formats the amount of native unit in the display unit,
+// as parsed back by parseAmount.
+func formatAmountInput(amount int64, native amountUnit, unit amountUnit) string {
+	r := new(big.Rat).SetFrac64(amount*amountUnitScales[native], amountUnitScales[unit])
+	if r.IsInt() {
+		return r.RatString()
+	}
+	return strings.TrimRight(r.FloatString(11), "0")
+}
+
+var amountMultipliers = map[string]int64{"k": 1000, "m": 1000000}
+
+// parseAmount parses an amount entered in the display unit, or in the unit
+// it ends with, e.g. "1500", "1_500", "100k", "0.01btc" or "2.5k sat", and
+// returns it in the native unit.
+func parseAmount(s string, native amountUnit, unit amountUnit) (int64, error) {
+	s = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(s, "_", "")))
+
+	// the longest unit names first, "msat" ending in "sat"
+	for _, u := range []amountUnit{msatUnit, bitsUnit, btcUnit, satUnit} {
+		if name := strings.ToLower(u.String()); strings.HasSuffix(s, name) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, name)), u
+			break
+		}
+	}
+
+	multiplier := int64(1)
+	for suffix, m := range amountMultipliers {
+		if strings.HasSuffix(s, suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, suffix)), m
+			break
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || len(s) == 0 || strings.ContainsAny(s, "/eE") {
+		return 0, errors.New("invalid amount")
+	}
+
+	r.Mul(r, new(big.Rat).SetInt64(multiplier*amountUnitScales[unit]))
+	r.Quo(r, new(big.Rat).SetInt64(amountUnitScales[native]))
+
+	if !r.IsInt() {
+		return 0, errors.New("below 1 " + native.String())
+	}
+	if !r.Num().IsInt64() {
+		return 0, errors.New("invalid amount")
+	}
+	return r.Num().Int64(), nil
+}
+
+// cycleAmountUnit switches to the next display unit.
+func cycleAmountUnit() {
+	context.unit = context.unit.next()
+	context.unitShortcut.header = "Unit " + context.unit.String()
+	refreshView()
+}
+
+func initAmountUnitShortcut() {
+	context.unitShortcut = &keyHandle{"Unit " + context.unit.String(), "M", 'm', gocui.ModAlt, cycleAmountUnit, true, ""}
+	context.globalShortcuts = append(context.globalShortcuts, context.unitShortcut)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestFormatAmount(t *testing.T) {
+	context.printer = message.NewPrinter(language.English)
+
+	tests := []struct {
+		amount int64
+		native amountUnit
+		unit   amountUnit
+		want   string
+	}{
+		{1500000, satUnit, satUnit, "1,500,000"},
+		{1500000, satUnit, msatUnit, "1,500,000,000"},
+		{1500000, satUnit, bitsUnit, "15,000.00"},
+		{1500000, satUnit, btcUnit, "0.01500000"},
+		{250000000, satUnit, btcUnit, "2.50000000"},
+		{1002, msatUnit, satUnit, "1.002"},
+		{1002, msatUnit, btcUnit, "0.00000001002"},
+		{10000000, msatUnit, satUnit, "10,000"},
+		{-12, satUnit, bitsUnit, "-0.12"},
+		{0, msatUnit, btcUnit, "0.00000000"},
+	}
+
+	for _, tt := range tests {
+		if s := formatAmount(tt.amount, tt.native, tt.unit); s != tt.want {
+			t.Errorf("%d %s in %s = %q, want %q", tt.amount, tt.native, tt.unit, s, tt.want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		s      string
+		native amountUnit
+		unit   amountUnit
+		want   int64
+	}{
+		{"1500", satUnit, satUnit, 1500},
+		{"2_000_000", satUnit, satUnit, 2000000},
+		{"100k", satUnit, satUnit, 100000},
+		{"1.5M", satUnit, satUnit, 1500000},
+		{"0.01btc", satUnit, satUnit, 1000000},
+		{"0.01 BTC", msatUnit, satUnit, 1000000000},
+		{"2.5k sat", satUnit, btcUnit, 2500},
+		{"1msat", msatUnit, btcUnit, 1},
+		{"250 bits", satUnit, satUnit, 25000},
+		{"0.5", satUnit, bitsUnit, 50},
+		{"3", msatUnit, satUnit, 3000},
+	}
+
+	for _, tt := range tests {
+		n, err := parseAmount(tt.s, tt.native, tt.unit)
+		if err != nil || n != tt.want {
+			t.Errorf("%q = %d, %v, want %d", tt.s, n, err, tt.want)
+		}
+	}
+
+	for s, want := range map[string]string{
+		"":       "invalid amount",
+		"k":      "invalid amount",
+		"1e3":    "invalid amount",
+		"1/2":    "invalid amount",
+		"abc":    "invalid amount",
+		"1 sats": "invalid amount",
+		"1msat":  "below 1 sat",
+		"0.5":    "below 1 sat",
+	} {
+		if _, err := parseAmount(s, satUnit, satUnit); err == nil || err.Error() != want {
+			t.Errorf("%q: error %v, want %q", s, err, want)
+		}
+	}
+}
+
+func TestFormatAmountInput(t *testing.T) {
+	for _, unit := range []amountUnit{msatUnit, satUnit, bitsUnit, btcUnit} {
+		for _, amount := range []int64{0, 1, 1234567, 2100000000000000} {
+			s := formatAmountInput(amount, satUnit, unit)
+			if n, err := parseAmount(s, satUnit, unit); err != nil || n != amount {
+				t.Errorf("%d in %s: %q parsed as %d, %v", amount, unit, s, n, err)
+			}
+		}
+	}
+}
+
+func TestAmountColumnUnits(t *testing.T) {
+	setupTestContext(t)
+	updateData()
+
+	cycleAmountUnit()
+	if context.unit != bitsUnit || context.unitShortcut.header != "Unit bits" {
+		t.Fatalf("unit %s, shortcut %q", context.unit, context.unitShortcut.header)
+	}
+	cycleAmountUnit()
+
+	lines := renderMainView(t)
+	assertLine(t, lines, "0.02000000", "0.01500000", "0.00490950")
+
+	lines = renderView(t, "balance", refreshWalletBalanceView)
+	assertLine(t, lines, "Total", "0.01500000 BTC")
+
+	cycleAmountUnit()
+	lines = renderMainView(t)
+	assertLine(t, lines, "│500,000,000 │0 ", "│490,950,000 │")
+
+	cv := context.views[channelListViewt].(*channelListView)
+	if err := cv.grid.setFilter("Local>1500000000"); err != nil {
+		t.Fatal(err)
+	}
+	if rows := gridRowNames(cv.grid); len(rows) != 0 {
+		t.Errorf("plain filter amount not in msat, rows %q", rows)
+	}
+	if err := cv.grid.setFilter("Local>100000000"); err != nil {
+		t.Fatal(err)
+	}
+	if rows := gridRowNames(cv.grid); len(rows) != 1 {
+		t.Errorf("plain filter amount not in msat, rows %q", rows)
+	}
+	if err := cv.grid.setFilter("Local>0.01btc"); err != nil {
+		t.Fatal(err)
+	}
+	if rows := gridRowNames(cv.grid); len(rows) != 1 {
+		t.Errorf("filter amount in BTC not converted, rows %q", rows)
+	}
+	if err := cv.grid.setFilter("Local>1 sats"); err == nil {
+		t.Error("no error for an invalid amount")
+	}
+}
+
+func TestAmountEdit(t *testing.T) {
+	fb := setupTestContext(t)
+	seedNodeCache(fb)
+	context.unit = btcUnit
+
+	cv := context.views[paymentListViewt].(*paymentListView)
+	cc := &keysendContainer{Amount: 25000}
+	cv.openKeysend(cc)
+
+	e := getTestEditor(t, cv.form, "Amount")
+	if e.getLabel() != "Amount (BTC)" || e.(*amountEdit).value != "0.00025" {
+		t.Errorf("amount editor %q, %q", e.getLabel(), e.(*amountEdit).value)
+	}
+
+	typeInForm(t, cv.form, "PubKey", testPubkey(3))
+	typeInForm(t, cv.form, "Amount", "0.5 sat")
+	cv.form.submit(true)
+	if e.getError() != "below 1 sat" {
+		t.Errorf("amount error = %q", e.getError())
+	}
+
+	typeInForm(t, cv.form, "Amount", "0.0001")
+	typeInForm(t, cv.form, "FeeLimit", "2k sat")
+	cv.form.submit(true)
+	if cv.form != nil {
+		t.Fatalf("form not submitted, amount error %q", e.getError())
+	}
+	if cc.Amount != 10000 || cc.FeeLimit != 2000 {
+		t.Errorf("amount %d, fee limit %d", cc.Amount, cc.FeeLimit)
+	}
+}
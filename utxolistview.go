@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/jroimartin/gocui"
+)
+
+type utxoListView struct {
+	viewBase
+	form *formEdit
+}
+
+type leaseUtxosContainer struct {
+	Outputs string `displayname:"Outputs" length:"40" readonly:"1"`
+	Minutes int    `displayname:"Lease minutes" length:"8" validate:"required,min=1,max=525600"`
+}
+
+func newutxoListView(physicalView string, fmtnormal string, fmtheader string, fmtselected string) *utxoListView {
+	cv := new(utxoListView)
+
+	cv.grid = makeNewDataGrid()
+	cv.mappedToPhysicalView = physicalView
+
+	cv.init(fmtnormal, fmtheader, fmtselected)
+
+	return cv
+}
+
+func (cv *utxoListView) init(fmtnormal string, fmtheader string, fmtselected string) {
+
+	cv.grid.fmtForeground = fmtnormal
+	cv.grid.fmtHeader = fmtheader
+	cv.grid.fmtSelected = fmtselected
+
+	cv.grid.setMarkable("Outpoint")
+
+	cv.shortcuts = nil
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Send coins", "C", 'c', gocui.ModAlt, cv.grid.selectionAction(cv.sendCoins), true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Open channel", "O", 'o', gocui.ModAlt, cv.grid.selectionAction(cv.openChannel), true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Lease", "L", 'l', gocui.ModAlt, cv.grid.selectionAction(cv.lease), true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Release", "E", 'e', gocui.ModAlt, cv.grid.selectionAction(cv.release), true, ""})
+
+	cv.grid.key = "utxos"
+	cv.grid.addColumn("Outpoint", "Outpoint", stringRow)        //"Outpoint",0
+	cv.grid.addColumn("Address", "Address", stringRow)          //"Address",0
+	cv.grid.addColumn("Type", "AddressType", stringRow)         //"Type",8
+	cv.grid.addAmountColumn("Amount", "AmountSat", satUnit)     //"Amount",13
+	cv.grid.addColumn("Confirmations", "Confirmations", intRow) //"Conf.",8
+	cv.grid.addColumn("LeasedUntil", "LeasedUntil", stringRow)  //"Leased until",18
+	cv.grid.addFiatColumn("AmountFiat", "AmountSat", satUnit)
+	cv.grid.initConfig()
+}
+
+// getUtxos converts the grid items an action applies to.
+func getUtxos(items []reflect.Value) []*lncliUtxo {
+	var utxos []*lncliUtxo
+	for _, item := range items {
+		utxos = append(utxos, item.Interface().(*lncliUtxo))
+	}
+	return utxos
+}
+
+// coinControlTitle adds the outputs spent by a form to its title.
+func coinControlTitle(title string, utxos []*lncliUtxo) string {
+	if len(utxos) == 0 {
+		return title
+	}
+	return fmt.Sprintf("%s from %d UTXO(s), %s", title, len(utxos), formatAmountUnit(sumUtxos(utxos), satUnit))
+}
+
+func (cv *utxoListView) sendCoins(items []reflect.Value) {
+	switchActiveView(walletTransactionsViewt)
+	context.views[walletTransactionsViewt].(*walletTransactionListView).sendCoinsFrom(getUtxos(items))
+}
+
+func (cv *utxoListView) openChannel(items []reflect.Value) {
+	switchActiveView(channelListViewt)
+	context.views[channelListViewt].(*channelListView).openChannelFrom(getUtxos(items))
+}
+
+// lease opens the form leasing the outputs, lnd not spending them until
+// released or expired.
+func (cv *utxoListView) lease(items []reflect.Value) {
+	utxos := getUtxos(items)
+
+	cc := new(leaseUtxosContainer)
+	cc.Outputs = fmt.Sprintf("%d UTXO(s), %s", len(utxos), formatAmountUnit(sumUtxos(utxos), satUnit))
+	cc.Minutes = 60
+
+	cv.form = newFormEdit("leaseUtxosVal", "Lease outputs", cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.getValue()
+		cv.form.close(context.gocui)
+		cv.form = nil
+		if valid {
+			if err := status.leaseUtxos(&context, utxos, uint64(cc.Minutes)*60); err != nil {
+				logError(err.Error())
+				displayMessage("Error: "+err.Error(), nil)
+			}
+		}
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
+func (cv *utxoListView) release(items []reflect.Value) {
+	if err := status.releaseUtxos(&context, getUtxos(items)); err != nil {
+		logError(err.Error())
+		displayMessage("Error: "+err.Error(), nil)
+	}
+}
+
+func (cv *utxoListView) refreshView(g *gocui.Gui) {
+	v, err := g.View(cv.mappedToPhysicalView)
+	if err != nil {
+		log.Panicln(err.Error())
+		return
+	}
+	v.Clear()
+
+	x, y := v.Size()
+
+	utxos := status.state().utxos
+	cv.grid.items = utxos
+	cv.grid.info = getUtxosInfo(utxos, getUtxos(cv.grid.getMarkedItems()))
+	cv.grid.setRenderSize(x, y)
+
+	for _, row := range cv.grid.getGridRows() {
+		fmt.Fprintln(v, row)
+	}
+
+	if cv.form != nil {
+		cv.form.layout(g)
+	}
+}
+
+// getUtxosInfo sums the outputs and the marked ones.
+func getUtxosInfo(utxos []*lncliUtxo, marked []*lncliUtxo) string {
+	info := fmt.Sprintf("%d UTXO(s), %s", len(utxos), formatAmountUnit(sumUtxos(utxos), satUnit))
+	if len(marked) > 0 {
+		info += fmt.Sprintf(", %s marked", formatAmountUnit(sumUtxos(marked), satUnit))
+	}
+	return info
+}
+
+func (cv *utxoListView) getShortCuts() []*keyHandle {
+	return cv.shortcuts
+}
+
+func (cv *utxoListView) getGrid() *dataGrid {
+	return cv.grid
+}
+
+func (cv *utxoListView) getPhysicalView() string {
+	return cv.mappedToPhysicalView
+}
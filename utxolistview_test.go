@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+)
+
+var (
+	testUtxo0 = strings.Repeat("0a", 32) + ":0"
+	testUtxo1 = strings.Repeat("0a", 32) + ":1"
+	testUtxo2 = strings.Repeat("0b", 32) + ":2"
+)
+
+func TestUtxoList(t *testing.T) {
+	setupTestContext(t)
+
+	activateView(utxoListViewt)
+	lines := updateTestData(t)
+	assertLine(t, lines, "[UTXOs]", "3 UTXO(s), 1,500,000 sat")
+	assertLine(t, lines, "600,000", "p2wkh", testUtxo0, "tb1qfakeaddress0")
+	assertLine(t, lines, "400,000", "np2wkh", testUtxo1)
+	assertLine(t, lines, "500,000", "0 ", "p2tr", testUtxo2)
+
+	cv := context.views[utxoListViewt].(*utxoListView)
+	markTestUtxos(cv, testUtxo0, testUtxo2)
+	lines = updateTestData(t)
+	assertLine(t, lines, "[UTXOs]", "1,100,000 sat marked", "Marked: 2")
+	if l := assertLine(t, lines, testUtxo0); !strings.HasPrefix(l, "*") {
+		t.Errorf("mark not displayed %q", l)
+	}
+	if l := assertLine(t, lines, testUtxo1); strings.HasPrefix(l, "*") {
+		t.Errorf("unmarked output displayed as marked %q", l)
+	}
+
+	if utxos := getUtxos(cv.grid.getSelectedItems()); len(utxos) != 2 {
+		t.Errorf("%d outputs to act on, want the marked ones", len(utxos))
+	}
+
+	cv.grid.clearMarks()
+	if utxos := getUtxos(cv.grid.getSelectedItems()); len(utxos) != 1 {
+		t.Errorf("%d outputs to act on, want the current one", len(utxos))
+	}
+}
+
+// markTestUtxos marks the outputs in the UTXO grid.
+func markTestUtxos(cv *utxoListView, outpoints ...string) {
+	for _, op := range outpoints {
+		cv.grid.marked[op] = true
+	}
+}
+
+func TestLeaseUtxos(t *testing.T) {
+	fb := setupTestContext(t)
+
+	activateView(utxoListViewt)
+	updateTestData(t)
+
+	utxos := status.state().utxos
+	if err := status.leaseUtxos(&context, []*lncliUtxo{utxos[0]}, 3600); err != nil {
+		t.Fatal(err)
+	}
+	if r := fb.lastLease; string(r.Id) != string(utxoLeaseID[:]) || r.ExpirationSeconds != 3600 || outPointString(r.Outpoint) != testUtxo0 {
+		t.Errorf("lease request %+v", r)
+	}
+
+	var leased *lncliUtxo
+	for _, u := range status.state().utxos {
+		if u.Outpoint == testUtxo0 {
+			leased = u
+		}
+	}
+	if leased == nil || len(leased.LeasedUntil) == 0 || leased.Address != "tb1qfakeaddress0" {
+		t.Fatalf("leased output %+v", leased)
+	}
+	assertLine(t, renderMainView(t), testUtxo0, leased.LeasedUntil)
+
+	if err := status.releaseUtxos(&context, utxos[1:]); err == nil || err.Error() != "no leased output selected" {
+		t.Errorf("release error %v", err)
+	}
+
+	// an output leased by another application is kept
+	other := sha256.Sum256([]byte("other"))
+	if _, err := fb.leaseOutput(&walletrpc.LeaseOutputRequest{Id: other[:], Outpoint: utxos[1].outpoint, ExpirationSeconds: 600}); err != nil {
+		t.Fatal(err)
+	}
+	context.scheduler.refreshSource("utxos")
+	var foreign *lncliUtxo
+	for _, u := range status.state().utxos {
+		if u.Outpoint == utxos[1].Outpoint {
+			foreign = u
+		}
+	}
+
+	err := status.releaseUtxos(&context, []*lncliUtxo{leased, foreign})
+	if err == nil || err.Error() != "not leased by lncli-curses, kept: "+utxos[1].Outpoint {
+		t.Errorf("release error %v", err)
+	}
+	if fb.callCount("releaseoutput") != 1 {
+		t.Error("output not released")
+	}
+	for _, u := range status.state().utxos {
+		if len(u.LeasedUntil) > 0 && u.Outpoint != utxos[1].Outpoint {
+			t.Errorf("output still leased %+v", u)
+		}
+	}
+}
+
+func TestCoinControl(t *testing.T) {
+	fb := setupTestContext(t)
+
+	activateView(utxoListViewt)
+	updateTestData(t)
+
+	cv := context.views[utxoListViewt].(*utxoListView)
+	markTestUtxos(cv, testUtxo0, testUtxo1)
+	cv.grid.selectionAction(cv.openChannel)()
+
+	chv := context.views[channelListViewt].(*channelListView)
+	if chv.form == nil || chv.form.title != " Open channel from 2 UTXO(s), 1,000,000 sat " {
+		t.Fatalf("open channel form not opened from the selection")
+	}
+	typeInForm(t, chv.form, "NodeKey", testPubkey(3))
+	typeInForm(t, chv.form, "LocalAmt", "900000")
+	chv.form.callback(true)
+
+	req := fb.lastOpenChannel
+	if req == nil || len(req.Outpoints) != 2 || outPointString(req.Outpoints[0]) != testUtxo0 || outPointString(req.Outpoints[1]) != testUtxo1 {
+		t.Fatalf("openchannel request %+v", req)
+	}
+
+	activateView(utxoListViewt)
+	cv.grid.selectionAction(cv.sendCoins)()
+	wv := context.views[walletTransactionsViewt].(*walletTransactionListView)
+	if wv.form == nil || wv.form.title != " Send coins from 2 UTXO(s), 1,000,000 sat " {
+		t.Fatalf("send coins form not opened from the selection")
+	}
+
+	sweep := &lnrpc.SendCoinsRequest{Addr: testBatchAddress1, SendAll: true, Outpoints: utxoOutpoints(getUtxos(cv.grid.getMarkedItems()))}
+	e, err := status.estimateSendCoins(&context, sweep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.amount != 1000000-201*5 {
+		t.Errorf("swept amount %d, want the selected outputs less the fee", e.amount)
+	}
+	if cc := newSendCoinsConfirmContainer(sweep, e); cc.Amount != "998,995 sat, sweeping 2 selected output(s)" {
+		t.Errorf("confirmation amount %q", cc.Amount)
+	}
+
+	sweep.Outpoints = append(sweep.Outpoints, &lnrpc.OutPoint{TxidStr: strings.Repeat("0f", 32)})
+	if _, err := status.estimateSendCoins(&context, sweep); err == nil {
+		t.Error("no error for an unknown output")
+	}
+}
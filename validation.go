@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// formValidator is implemented by the form containers needing checks across
+// fields, it's called once the field tags are valid and returns the error
+// messages by field name.
+type formValidator interface {
+	validate() map[string]string
+}
+
+// validateField checks a form value against the rules of its `validate` tag,
+// e.g. `validate:"required,min=1,max=100"` or `validate:"hex=32"`, and its
+// `regex` tag. Optional empty fields pass all the rules but required.
+func validateField(sf reflect.StructField, value interface{}) error {
+	rules := strings.Split(sf.Tag.Get("validate"), ",")
+
+	empty := isEmptyFormValue(value)
+
+	for _, rule := range rules {
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+
+		if name == "required" {
+			if empty {
+				return errors.New("required")
+			}
+			continue
+		}
+
+		if empty || len(name) == 0 {
+			continue
+		}
+
+		if err := validateRule(name, arg, value); err != nil {
+			if unit, ok := sf.Tag.Lookup("unit"); ok && (name == "min" || name == "max") {
+				return errors.New(name + " " + formatAmountLimit(arg, unit))
+			}
+			return err
+		}
+	}
+
+	if expr, ok := sf.Tag.Lookup("regex"); ok && !empty {
+		if !regexp.MustCompile(expr).MatchString(fmt.Sprint(value)) {
+			return errors.New("invalid format")
+		}
+	}
+
+	return nil
+}
+
+// formatAmountLimit formats the min or max of an amount field, in the native
+// unit of the field, in the display unit.
+func formatAmountLimit(limit string, unit string) string {
+	native, _ := parseAmountUnit(unit)
+	n, _ := strconv.ParseInt(limit, 10, 64)
+	return formatAmountUnit(n, native)
+}
+
+func isEmptyFormValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return len(strings.TrimSpace(v)) == 0
+	case int:
+		return v == 0
+	}
+	return false
+}
+
+func validateRule(name string, arg string, value interface{}) error {
+	switch name {
+	case "min", "max":
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid %s validation '%s'", name, arg))
+		}
+		n := 0
+		switch v := value.(type) {
+		case int:
+			n = v
+		case string:
+			n = len(v)
+		}
+		if name == "min" && n < limit {
+			return fmt.Errorf("min %d", limit)
+		}
+		if name == "max" && n > limit {
+			return fmt.Errorf("max %d", limit)
+		}
+	case "hex":
+		return validateHex(value.(string), arg)
+	case "pubkey":
+		return validatePubkey(value.(string))
+	case "hostport":
+		return validateHostPort(value.(string))
+	case "address":
+		return validateAddress(value.(string), getChainParams())
+	case "bolt11":
+		return validateBolt11(value.(string))
+	case "chanid":
+		if _, err := parseShortChannelID(value.(string)); err != nil {
+			return errors.New("block x tx x output expected")
+		}
+	default:
+		panic(fmt.Sprintf("Unknown validation '%s'", name))
+	}
+
+	return nil
+}
+
+// validateHex checks for an hex string, of size bytes if not empty.
+func validateHex(s string, size string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return errors.New("hex expected")
+	}
+	if len(size) > 0 {
+		if n, _ := strconv.Atoi(size); len(b) != n {
+			return fmt.Errorf("%d bytes hex expected", n)
+		}
+	}
+	return nil
+}
+
+func validatePubkey(s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 33 || (b[0] != 2 && b[0] != 3) {
+		return errors.New("invalid public key")
+	}
+	return nil
+}
+
+func validateHostPort(s string) error {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || len(host) == 0 {
+		return errors.New("host:port expected")
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return errors.New("invalid port")
+	}
+	return nil
+}
+
+// validateAddress checks for an address of the node's network, decoded with
+// the checksum, witness version and program length rules of its type.
+func validateAddress(s string, params *chaincfg.Params) error {
+	a, err := btcutil.DecodeAddress(s, params)
+	if err != nil {
+		return errors.New("invalid address")
+	}
+	if !a.IsForNet(params) {
+		return fmt.Errorf("not a %s address", params.Name)
+	}
+	return nil
+}
+
+// base58Prefixes are the leading characters of the p2pkh and p2sh
+// addresses of the networks.
+var base58Prefixes = map[string]string{
+	chaincfg.MainNetParams.Name:  "13",
+	chaincfg.TestNet3Params.Name: "mn2",
+}
+
+// hasAddressPrefix tells if s starts like an address of the network, valid
+// or not.
+func hasAddressPrefix(s string, params *chaincfg.Params) bool {
+	if len(s) == 0 {
+		return false
+	}
+	if strings.HasPrefix(strings.ToLower(s), params.Bech32HRPSegwit+"1") {
+		return true
+	}
+	return strings.IndexByte(base58Prefixes[params.Name], s[0]) >= 0
+}
+
+// getChainParams returns the parameters of the node's network, from getinfo.
+func getChainParams() *chaincfg.Params {
+	if status.state().localNodeInfo.Testnet {
+		return &chaincfg.TestNet3Params
+	}
+	return &chaincfg.MainNetParams
+}
+
+func validateBolt11(s string) error {
+	hrp, _, err := bech32.DecodeNoLimit(s)
+	if err != nil || !strings.HasPrefix(hrp, "ln") {
+		return errors.New("invalid payment request")
+	}
+	return nil
+}
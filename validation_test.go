@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testBolt11 is the "donation" payment request of the BOLT 11 examples.
+const testBolt11 = "lnbc1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpl2pkx2ctnv5sxxmmwwd5kgetjypeh2ursdae8g6twvus8g6rfwvs8qun0dfjkxaq8rkx3yf5tcsyz3d73gafnh3cax9rn449d9p5uxz9ezhhypd0elx87sjle52x86fux2ypatgddc6k63n7erqz25le42c4u4ecky03ylcqca784w"
+
+type testValidationContainer struct {
+	Name    string `validate:"required,max=8"`
+	Amount  int    `validate:"min=10,max=100"`
+	Hash    string `validate:"hex=32"`
+	Key     string `validate:"pubkey"`
+	Host    string `validate:"hostport"`
+	Address string `validate:"address"`
+	PayReq  string `validate:"bolt11"`
+	ChanID  string `validate:"chanid"`
+	Label   string `regex:"^[a-z]+$"`
+}
+
+func validateTestField(name string, value interface{}) error {
+	sf, _ := reflect.TypeOf(testValidationContainer{}).FieldByName(name)
+	return validateField(sf, value)
+}
+
+func TestValidateField(t *testing.T) {
+	setupTestContext(t)
+	status.store.update(func(st *lncliState) { st.localNodeInfo.Testnet = false })
+
+	tests := []struct {
+		field string
+		value interface{}
+		err   string
+	}{
+		{"Name", "", "required"},
+		{"Name", "  ", "required"},
+		{"Name", "alice", ""},
+		{"Name", "alice and bob", "max 8"},
+		{"Amount", 0, ""},
+		{"Amount", 5, "min 10"},
+		{"Amount", 101, "max 100"},
+		{"Amount", 100, ""},
+		{"Hash", strings.Repeat("ab", 32), ""},
+		{"Hash", strings.Repeat("ab", 31), "32 bytes hex expected"},
+		{"Hash", strings.Repeat("zz", 32), "hex expected"},
+		{"Key", testPubkey(1), ""},
+		{"Key", "04" + testPubkey(1)[2:], "invalid public key"},
+		{"Key", testPubkey(1)[:64], "invalid public key"},
+		{"Host", "node.example.com:9735", ""},
+		{"Host", "[::1]:10009", ""},
+		{"Host", "node.example.com", "host:port expected"},
+		{"Host", "node.example.com:99999", "invalid port"},
+		{"Address", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", ""},
+		{"Address", "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", ""},
+		{"Address", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", ""},
+		{"Address", "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", "invalid address"},
+		{"Address", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", "invalid address"},
+		{"Address", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", "invalid address"},
+		{"Address", "bc1zw508d6qejxtdg4y5r3zarvaryvqyzf3du", "invalid address"},
+		{"Address", "BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P", "invalid address"},
+		{"Address", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", ""},
+		{"Address", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", ""},
+		{"Address", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLz", "invalid address"},
+		{"PayReq", testBolt11, ""},
+		{"ChanID", "600000x1x1", ""},
+		{"ChanID", "600000x1", "block x tx x output expected"},
+		{"ChanID", "16777216x1x1", "block x tx x output expected"},
+		{"PayReq", testBolt11[:len(testBolt11)-1] + "q", "invalid payment request"},
+		{"PayReq", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", "invalid payment request"},
+		{"Label", "abc", ""},
+		{"Label", "ABC", "invalid format"},
+	}
+
+	for _, tt := range tests {
+		err := validateTestField(tt.field, tt.value)
+		switch {
+		case err == nil && len(tt.err) > 0:
+			t.Errorf("%s %q: no error, want %q", tt.field, tt.value, tt.err)
+		case err != nil && err.Error() != tt.err:
+			t.Errorf("%s %q: error %q, want %q", tt.field, tt.value, err, tt.err)
+		}
+	}
+}
+
+func TestValidateTestnetAddress(t *testing.T) {
+	setupTestContext(t)
+
+	if err := validateTestField("Address", "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"); err != nil {
+		t.Errorf("testnet address: %v", err)
+	}
+	if err := validateTestField("Address", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"); err == nil || err.Error() != "not a testnet3 address" {
+		t.Errorf("mainnet address on testnet: %v", err)
+	}
+}
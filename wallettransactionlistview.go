@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/jroimartin/gocui"
+	"github.com/lightningnetwork/lnd/lnrpc"
 )
 
 type walletTransactionListView struct {
@@ -22,6 +23,82 @@ type walletNewAddressReponseContainer struct {
 	AdressQR   string `displayname:"QRCode" length:"50" readonly:"1" lines:"16"`
 }
 
+type sendCoinsContainer struct {
+	Address    string `displayname:"Address" length:"64" validate:"required,address"`
+	Amount     int    `displayname:"Amount" length:"16" unit:"sat" validate:"min=0"`
+	SweepAll   bool   `displayname:"Sweep all"`
+	ConfTarget int    `displayname:"Conf target (opt)" length:"6" validate:"min=0,max=1008"`
+	SatPerByte int    `displayname:"Sat per vbyte (opt)" length:"6" validate:"min=0"`
+	Label      string `displayname:"Label (opt)" length:"64" validate:"max=500"`
+}
+
+func (c *sendCoinsContainer) validate() map[string]string {
+	errs := make(map[string]string)
+	if c.SweepAll && c.Amount > 0 {
+		errs["Amount"] = "amount or sweep all"
+	}
+	if !c.SweepAll && c.Amount == 0 {
+		errs["Amount"] = "required"
+	}
+	if c.ConfTarget > 0 && c.SatPerByte > 0 {
+		errs["SatPerByte"] = "conf target or sat per vbyte"
+	}
+	return errs
+}
+
+type sendCoinsConfirmContainer struct {
+	Address string `displayname:"Address" length:"66" readonly:"1"`
+	Amount  string `displayname:"Amount" length:"66" readonly:"1"`
+	Fee     string `displayname:"Fee estimate" length:"66" readonly:"1"`
+	Label   string `displayname:"Label" length:"66" readonly:"1"`
+}
+
+func newSendCoinsConfirmContainer(req *lnrpc.SendCoinsRequest, e *onChainEstimate) *sendCoinsConfirmContainer {
+	cc := new(sendCoinsConfirmContainer)
+	cc.Address = req.Addr
+	cc.Amount = formatAmountUnit(e.amount, satUnit)
+	switch {
+	case req.SendAll && len(req.Outpoints) > 0:
+		cc.Amount += fmt.Sprintf(", sweeping %d selected output(s)", len(req.Outpoints))
+	case req.SendAll:
+		cc.Amount += ", sweeping all confirmed funds"
+	}
+	cc.Fee = formatOnChainFee(e, req.TargetConf, req.SatPerByte)
+	cc.Label = req.Label
+	return cc
+}
+
+// formatOnChainFee describes the fee estimate, with its rate and the
+// confirmation target it's estimated for when the rate isn't set.
+func formatOnChainFee(e *onChainEstimate, targetConf int32, satPerByte int64) string {
+	fee := formatAmountUnit(e.fee, satUnit) + context.printer.Sprintf(", %d sat/vbyte", e.feeRate)
+	if satPerByte == 0 {
+		if targetConf == 0 {
+			targetConf = defaultConfTarget
+		}
+		fee += fmt.Sprintf(", %d blocks target", targetConf)
+	}
+	return fee
+}
+
+// displayOnChainConfirmation shows the on-chain payment about to be sent,
+// calling confirmed when validated.
+func displayOnChainConfirmation(title string, cc interface{}, confirmed func()) {
+
+	form := newFormEdit("onChainConfirm", title, cc)
+
+	form.callback = func(valid bool) {
+		form.close(context.gocui)
+		form = nil
+		if valid {
+			confirmed()
+		}
+	}
+
+	form.switchActiveEditor(-1, context.gocui)
+	form.initialize(context.gocui)
+}
+
 func newwalletTransactionListView(physicalView string, fmtnormal string, fmtheader string, fmtselected string) *walletTransactionListView {
 	cv := new(walletTransactionListView)
 
@@ -42,17 +119,22 @@ func (cv *walletTransactionListView) init(fmtnormal string, fmtheader string, fm
 	cv.shortcuts = nil
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Up", "Up", gocui.KeyArrowUp, gocui.ModNone, func() { cv.grid.moveSelectionUp() }, false, ""})
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Scroll Down", "Down", gocui.KeyArrowDown, gocui.ModNone, func() { cv.grid.moveSelectionDown() }, false, ""})
+	cv.shortcuts = append(cv.shortcuts, cv.grid.getShortCuts(cv.mappedToPhysicalView)...)
 	cv.shortcuts = append(cv.shortcuts, &keyHandle{"New address", "N", 'n', gocui.ModAlt, cv.newaddress, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Send coins", "C", 'c', gocui.ModAlt, cv.sendCoins, true, ""})
+	cv.shortcuts = append(cv.shortcuts, &keyHandle{"Send many", "B", 'b', gocui.ModAlt, cv.sendMany, true, ""})
 
 	cv.grid.key = "walletTransactions"
-	cv.grid.addColumn("Amount", "Amount", intRow)                  //"Amount",12
+	cv.grid.addAmountColumn("Amount", "Amount", satUnit)           //"Amount",12
 	cv.grid.addColumn("Confirmations", "NumConfirmations", intRow) //"Conf.",8
 	cv.grid.addColumn("BlockHeight", "BlockHeight", intRow)        //"Bloc kHeight", 8
-	cv.grid.addColumn("Fees", "TotalFees", intRow)                 //"Fees",8
+	cv.grid.addAmountColumn("Fees", "TotalFees", satUnit)          //"Fees",8
 	cv.grid.addColumn("Timestamp", "TimeStamp", dateRow)           //"Timestamp",18
 	cv.grid.addColumn("TxHash", "TxHash", stringRow)               //"Tx Hash", 0
 	cv.grid.addColumn("BlockHash", "BlockHash", stringRow)         //"Block Hash",0
 	cv.grid.addColumn("Destination", "DestAddresses", sliceRow)    //"Dest.",0
+	cv.grid.addColumn("Label", "Label", stringRow)                 //"Label",16
+	cv.grid.addFiatColumn("AmountFiat", "Amount", satUnit)
 	cv.grid.initConfig()
 }
 
@@ -88,6 +170,45 @@ func (cv *walletTransactionListView) newaddress() {
 	cv.form.switchActiveEditor(-1, context.gocui)
 }
 
+// sendCoins opens the on-chain payment form, the payment being confirmed
+// with its fee estimate before sent.
+func (cv *walletTransactionListView) sendCoins() {
+	cv.sendCoinsFrom(nil)
+}
+
+// sendCoinsFrom opens the on-chain payment form, the payment spending the
+// utxos when set.
+func (cv *walletTransactionListView) sendCoinsFrom(utxos []*lncliUtxo) {
+	cc := new(sendCoinsContainer)
+
+	cv.form = newFormEdit("sendCoinsVal", coinControlTitle("Send coins", utxos), cc)
+
+	cv.form.callback = func(valid bool) {
+		cv.form.getValue()
+		cv.form.close(context.gocui)
+		cv.form = nil
+		if valid {
+			status.sendCoins(&context, &lnrpc.SendCoinsRequest{
+				Addr:       cc.Address,
+				Amount:     int64(cc.Amount),
+				SendAll:    cc.SweepAll,
+				TargetConf: int32(cc.ConfTarget),
+				SatPerByte: int64(cc.SatPerByte),
+				Label:      cc.Label,
+				Outpoints:  utxoOutpoints(utxos),
+			})
+		}
+	}
+
+	cv.form.initialize(context.gocui)
+}
+
+// sendMany switches to the batch payment view and opens its CSV file form.
+func (cv *walletTransactionListView) sendMany() {
+	switchActiveView(batchListViewt)
+	context.views[batchListViewt].(*batchListView).load()
+}
+
 func (cv *walletTransactionListView) displayAddress(at string, a string) {
 	cr := new(walletNewAddressReponseContainer)
 
@@ -116,6 +237,7 @@ func (cv *walletTransactionListView) refreshView(g *gocui.Gui) {
 
 	x, y := v.Size()
 
+	cv.grid.items = status.state().walletTransactions
 	cv.grid.setRenderSize(x, y)
 
 	for _, row := range cv.grid.getGridRows() {
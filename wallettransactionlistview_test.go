@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+func TestWalletTransactionListViewRender(t *testing.T) {
+	setupTestContext(t)
+
+	activateView(walletTransactionsViewt)
+	updateData()
+
+	lines := renderMainView(t)
+
+	assertLine(t, lines, "[Wallet transactions]")
+	assertLine(t, lines, "1,000,000", "599,994", "0a0a0a0a")
+	assertLine(t, lines, "-2,009,050", "tb1qfakeaddress1, tb1qfakeaddress2")
+}
+
+func TestNewAddressForm(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[walletTransactionsViewt].(*walletTransactionListView)
+	cv.newaddress()
+
+	typeInForm(t, cv.form, "AdressType", "p2wkh")
+	cv.form.callback(true)
+
+	if fb.lastNewAddressType != "p2wkh" {
+		t.Errorf("address type %q", fb.lastNewAddressType)
+	}
+
+	cr := cv.form.toMap.(*walletNewAddressReponseContainer)
+	if cr.Adress != "tb1qfakenewaddress" {
+		t.Errorf("displayed address %q", cr.Adress)
+	}
+}
+
+func TestSendCoinsFormValidation(t *testing.T) {
+	fb := setupTestContext(t)
+
+	cv := context.views[walletTransactionsViewt].(*walletTransactionListView)
+	cv.sendCoins()
+
+	typeInForm(t, cv.form, "Address", "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsy")
+	cv.form.submit(true)
+
+	for name, msg := range map[string]string{"Address": "invalid address", "Amount": ""} {
+		if e := getTestEditor(t, cv.form, name).getError(); e != msg {
+			t.Errorf("%s error = %q, want %q", name, e, msg)
+		}
+	}
+
+	typeInForm(t, cv.form, "Address", "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx")
+	cv.form.submit(true)
+	if e := getTestEditor(t, cv.form, "Amount").getError(); e != "required" {
+		t.Errorf("amount error = %q", e)
+	}
+
+	typeInForm(t, cv.form, "Amount", "100k")
+	toggleInForm(t, cv.form, "SweepAll")
+	typeInForm(t, cv.form, "ConfTarget", "2")
+	typeInForm(t, cv.form, "SatPerByte", "10")
+	cv.form.submit(true)
+	for name, msg := range map[string]string{"Amount": "amount or sweep all", "SatPerByte": "conf target or sat per vbyte"} {
+		if e := getTestEditor(t, cv.form, name).getError(); e != msg {
+			t.Errorf("%s error = %q, want %q", name, e, msg)
+		}
+	}
+
+	toggleInForm(t, cv.form, "SweepAll")
+	typeInForm(t, cv.form, "SatPerByte", "0")
+	typeInForm(t, cv.form, "Label", "withdrawal")
+	cv.form.submit(true)
+	if cv.form != nil {
+		t.Fatal("valid form not submitted")
+	}
+
+	status.store.wait()
+	if fb.callCount("estimatefee") != 1 || fb.callCount("sendcoins") != 0 {
+		t.Error("coins sent before confirmation")
+	}
+}
+
+func TestSendCoinsEstimate(t *testing.T) {
+	fb := setupTestContext(t)
+
+	addr := "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"
+
+	req := &lnrpc.SendCoinsRequest{Addr: addr, Amount: 100000, Label: "withdrawal"}
+	e, err := status.estimateSendCoins(&context, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := newSendCoinsConfirmContainer(req, e)
+	if cc.Address != addr || cc.Amount != "100,000 sat" || cc.Fee != "705 sat, 5 sat/vbyte, 6 blocks target" || cc.Label != "withdrawal" {
+		t.Errorf("unexpected confirmation %+v", cc)
+	}
+
+	req = &lnrpc.SendCoinsRequest{Addr: addr, Amount: 100000, SatPerByte: 12}
+	e, _ = status.estimateSendCoins(&context, req)
+	if cc := newSendCoinsConfirmContainer(req, e); cc.Fee != "1,692 sat, 12 sat/vbyte" {
+		t.Errorf("fee at set rate %q", cc.Fee)
+	}
+
+	// the p2wkh and np2wkh confirmed outputs swept to a p2wkh address, 201
+	// vbytes
+	req = &lnrpc.SendCoinsRequest{Addr: addr, SendAll: true, TargetConf: 1}
+	e, _ = status.estimateSendCoins(&context, req)
+	if cc := newSendCoinsConfirmContainer(req, e); cc.Amount != "995,980 sat, sweeping all confirmed funds" || cc.Fee != "4,020 sat, 20 sat/vbyte, 1 blocks target" {
+		t.Errorf("unexpected sweep confirmation %+v", cc)
+	}
+
+	calls := fb.callCount("estimatefee")
+	req = &lnrpc.SendCoinsRequest{Addr: addr, SendAll: true, SatPerByte: 2}
+	e, _ = status.estimateSendCoins(&context, req)
+	if cc := newSendCoinsConfirmContainer(req, e); cc.Fee != "402 sat, 2 sat/vbyte" || fb.callCount("estimatefee") != calls {
+		t.Errorf("sweep fee at set rate %q", cc.Fee)
+	}
+
+	if _, err := status.estimateSendCoins(&context, &lnrpc.SendCoinsRequest{Addr: addr, Amount: 2000000}); err == nil {
+		t.Error("no error above the confirmed balance")
+	}
+}
+
+func TestSendCoins(t *testing.T) {
+	fb := setupTestContext(t)
+
+	activateView(walletTransactionsViewt)
+	updateData()
+
+	req := &lnrpc.SendCoinsRequest{Addr: "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", Amount: 100000, TargetConf: 2, Label: "withdrawal"}
+	msg := status.sendCoinsNow(&context, req)
+	if msg != "Coins sent\nTxid: "+strings.Repeat("0c", 32) {
+		t.Errorf("unexpected message %q", msg)
+	}
+	if fb.lastSendCoins != req {
+		t.Errorf("sendcoins request %+v", fb.lastSendCoins)
+	}
+	if fb.callCount("walletbalance") != 2 {
+		t.Error("balance not updated after sending")
+	}
+
+	lines := renderMainView(t)
+	l := assertLine(t, lines, "-100,705", "withdrawal", "0c0c0c0c")
+	if !strings.HasPrefix(l, "-100,705   │0      │") {
+		t.Errorf("sent transaction not unconfirmed %q", l)
+	}
+
+	fb.err = errors.New("insufficient funds")
+	if msg := status.sendCoinsNow(&context, req); msg != "Send failed\ninsufficient funds" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}